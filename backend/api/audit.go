@@ -0,0 +1,20 @@
+package api
+
+import (
+	"axolotl-cloud/internal/app/handler"
+	"axolotl-cloud/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterAuditRoutes(r *gin.RouterGroup, db *gorm.DB) {
+	auditHandler := &handler.AuditHandler{
+		AuditRepository: &repository.AuditEventRepository{DB: db},
+	}
+	auditGroup := r.Group("/audit", handler.RequireAdmin)
+	{
+		auditGroup.GET("", auditHandler.GetAuditEvents)
+		auditGroup.GET("/export", auditHandler.ExportAuditEvents)
+	}
+}
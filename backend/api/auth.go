@@ -0,0 +1,86 @@
+package api
+
+import (
+	"axolotl-cloud/infra/oidc"
+	"axolotl-cloud/infra/ratelimit"
+	"axolotl-cloud/infra/secrets"
+	"axolotl-cloud/infra/shared"
+	"axolotl-cloud/internal/app/handler"
+	"axolotl-cloud/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterAuthRoutes(r *gin.RouterGroup, db *gorm.DB, settingRepository *repository.SettingRepository, cipher *secrets.Cipher, access *handler.Access) {
+	provider := oidc.NewProvider(shared.OIDCConfig())
+	authHandler := &handler.AuthHandler{
+		UserRepository:      &repository.UserRepository{DB: db},
+		SessionRepository:   &repository.SessionRepository{DB: db},
+		ChallengeRepository: &repository.LoginChallengeRepository{DB: db},
+		SettingRepository:   settingRepository,
+		Cipher:              cipher,
+		OIDCEnabled:         provider.Enabled(),
+		Lockout:             ratelimit.NewLockout(loginFreeFailures, loginLockout, loginMaxLockout),
+		UserLockout:         ratelimit.NewLockout(userFreeFailures, loginLockout, userMaxLockout),
+	}
+	oidcHandler := &handler.OIDCHandler{
+		Provider:          provider,
+		Auth:              authHandler,
+		MappingRepository: &repository.OIDCGroupMappingRepository{DB: db},
+		MemberRepository:  access.MemberRepository,
+		ProjectRepository: &repository.ProjectRepository{DB: db},
+	}
+	authLimit := rateLimit(authRateInterval, authRateBurst)
+	authGroup := r.Group("/auth")
+	{
+		authGroup.GET("/status", authHandler.GetAuthStatus)
+		authGroup.POST("/setup", authLimit, authHandler.Setup)
+		authGroup.POST("/login", authLimit, authHandler.Login)
+		authGroup.POST("/login/verify", authLimit, authHandler.VerifyLogin)
+		authGroup.POST("/logout", authHandler.Logout)
+		authGroup.GET("/me", authHandler.GetCurrentUser)
+		authGroup.PUT("/password", authHandler.ChangePassword)
+		authGroup.GET("/sessions", authHandler.GetSessions)
+		authGroup.DELETE("/sessions/:sessionId", authHandler.RevokeSession)
+		authGroup.GET("/2fa", authHandler.GetTwoFactorStatus)
+		authGroup.POST("/2fa/setup", authHandler.SetupTwoFactor)
+		authGroup.POST("/2fa/enable", authHandler.EnableTwoFactor)
+		authGroup.POST("/2fa/disable", authHandler.DisableTwoFactor)
+		authGroup.POST("/2fa/recovery_codes", authHandler.RegenerateRecoveryCodes)
+		authGroup.GET("/oidc/login", authLimit, oidcHandler.Login)
+		authGroup.GET("/oidc/callback", authLimit, oidcHandler.Callback)
+	}
+
+	oidcGroup := r.Group("/oidc", handler.RequireAdmin)
+	{
+		oidcGroup.GET("/mappings", oidcHandler.GetAllGroupMappings)
+		oidcGroup.POST("/mappings", oidcHandler.CreateGroupMapping)
+		oidcGroup.DELETE("/mappings/:mappingId", oidcHandler.DeleteGroupMapping)
+	}
+
+	tokenHandler := &handler.TokenHandler{
+		TokenRepository:   &repository.APITokenRepository{DB: db},
+		ProjectRepository: &repository.ProjectRepository{DB: db},
+		Access:            access,
+	}
+	tokenGroup := r.Group("/tokens")
+	{
+		tokenGroup.GET("", tokenHandler.GetAllTokens)
+		tokenGroup.POST("", tokenHandler.CreateToken)
+		tokenGroup.DELETE("/:tokenId", tokenHandler.DeleteToken)
+	}
+
+	userHandler := &handler.UserHandler{
+		UserRepository:    authHandler.UserRepository,
+		SessionRepository: authHandler.SessionRepository,
+	}
+	userGroup := r.Group("/users", handler.RequireAdmin)
+	{
+		userGroup.GET("", userHandler.GetAllUsers)
+		userGroup.POST("", userHandler.CreateUser)
+		userGroup.PUT("/:userId", userHandler.UpdateUser)
+		userGroup.DELETE("/:userId", userHandler.DeleteUser)
+		userGroup.DELETE("/:userId/2fa", userHandler.ResetTwoFactor)
+	}
+}
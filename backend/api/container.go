@@ -4,32 +4,59 @@ import (
 	"axolotl-cloud/infra/docker"
 	"axolotl-cloud/infra/worker"
 	"axolotl-cloud/internal/app/handler"
+	"axolotl-cloud/internal/app/model"
 	"axolotl-cloud/internal/app/repository"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func RegisterContainerRoutes(r *gin.RouterGroup, db *gorm.DB, dockerClient *docker.DockerClient, w *worker.Worker) {
+func RegisterContainerRoutes(r *gin.RouterGroup, db *gorm.DB, dockerClient *docker.DockerClient, w *worker.Worker, settingRepository *repository.SettingRepository, secretRepository *repository.SecretRepository, access *handler.Access) {
 	containerHandler := &handler.ContainerHandler{
 		ContainerRepository: &repository.ContainerRepository{DB: db},
 		ProjectRepository:   &repository.ProjectRepository{DB: db},
+		BuildRepository:     &repository.BuildRepository{DB: db},
+		TemplateRepository:  &repository.ComposeTemplateRepository{DB: db},
+		RevisionRepository:  &repository.ContainerRevisionRepository{DB: db},
+		SettingRepository:   settingRepository,
+		SecretRepository:    secretRepository,
 		DockerClient:        dockerClient,
 		JobWorker:           w,
 	}
-	containerGroup := r.Group("/projects/:id/containers")
+	viewer := access.RequireRole(model.RoleViewer)
+	deployer := access.RequireRole(model.RoleDeployer)
+	owner := access.RequireRole(model.RoleOwner)
+	buildLimit := rateLimit(buildRateInterval, buildRateBurst)
+
+	// Reading needs the viewer role, checked before the container belongs to
+	// the project so that other projects are not revealed
+	containerGroup := r.Group("/projects/:id/containers", viewer, containerHandler.RequireContainerInProject)
 	{
-		containerGroup.POST("", containerHandler.CreateContainer)
+		containerGroup.POST("", owner, containerHandler.CreateContainer)
 		containerGroup.GET("", containerHandler.GetAllContainers)
+		containerGroup.GET("/env", containerHandler.GetEffectiveEnv)
 		containerGroup.GET("/:containerId", containerHandler.GetContainerByID)
-		containerGroup.PUT("/:containerId", containerHandler.UpdateContainer)
-		containerGroup.DELETE("/:containerId", containerHandler.DeleteContainer)
+		containerGroup.PUT("/:containerId", owner, containerHandler.UpdateContainer)
+		containerGroup.DELETE("/:containerId", owner, containerHandler.DeleteContainer)
 
 		containerGroup.GET("/:containerId/status", containerHandler.GetContainerStatus)
-		containerGroup.POST("/:containerId/start", containerHandler.StartContainer)
-		containerGroup.POST("/:containerId/stop", containerHandler.StopContainer)
+		containerGroup.POST("/:containerId/start", deployer, containerHandler.StartContainer)
+		containerGroup.POST("/:containerId/stop", deployer, containerHandler.StopContainer)
 		containerGroup.GET("/:containerId/logs", containerHandler.GetContainerLogs)
-		containerGroup.POST("/import", containerHandler.ImportComposeFile)
-		containerGroup.POST("/build_from_source", containerHandler.BuildFromSource)
+		containerGroup.GET("/:containerId/builds", containerHandler.GetContainerBuilds)
+		containerGroup.POST("/:containerId/rollback/:buildId", deployer, containerHandler.RollbackContainer)
+		containerGroup.GET("/:containerId/revisions", containerHandler.GetContainerRevisions)
+		containerGroup.GET("/:containerId/revisions/diff", containerHandler.DiffContainerRevisions)
+		containerGroup.POST("/:containerId/revisions/:number/restore", owner, containerHandler.RestoreContainerRevision)
+		containerGroup.POST("/import", owner, containerHandler.ImportComposeFile)
+		containerGroup.POST("/reimport", owner, containerHandler.ReimportComposeFile)
+		containerGroup.POST("/build_from_source", deployer, buildLimit, containerHandler.BuildFromSource)
+		containerGroup.POST("/build_from_archive", deployer, buildLimit, containerHandler.BuildFromArchive)
+	}
+
+	reconcileGroup := r.Group("/reconcile", handler.RequireAdmin)
+	{
+		reconcileGroup.GET("", containerHandler.GetReconcileReport)
+		reconcileGroup.POST("", containerHandler.ReconcileAction)
 	}
 }
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"axolotl-cloud/internal/app/handler"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,6 +15,10 @@ func RegisterFrontRoutes(r *gin.Engine) {
 	})
 	r.Static("/assets", "./dist/assets")
 	r.NoRoute(func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, legacyPrefix+"/") {
+			handler.RespondNotFound(c)
+			return
+		}
 		c.HTML(http.StatusOK, "index.html", nil)
 	})
 }
@@ -9,15 +9,16 @@ import (
 	"gorm.io/gorm"
 )
 
-func RegisterJobsRoutes(r *gin.RouterGroup, db *gorm.DB, w *worker.Worker) {
+func RegisterJobsRoutes(r *gin.RouterGroup, db *gorm.DB, w *worker.Worker, access *handler.Access) {
 	jobHandler := &handler.JobHandler{
 		JobRepository: &repository.JobRepository{DB: db},
+		Access:        access,
 		Worker:        w,
 	}
 	jobGroup := r.Group("/jobs")
 	{
 		jobGroup.GET("", jobHandler.GetAllJobs)
 		jobGroup.GET("/:id", jobHandler.GetJobByID)
-		jobGroup.DELETE("/:id", jobHandler.DeleteJob)
+		jobGroup.DELETE("/:id", handler.RequireAdmin, jobHandler.DeleteJob)
 	}
 }
@@ -1,16 +1,85 @@
 package api
 
 import (
+	"axolotl-cloud/infra/network"
+	"axolotl-cloud/infra/settings"
+	"axolotl-cloud/infra/shared"
+	"axolotl-cloud/internal/app/handler"
+	"axolotl-cloud/internal/app/repository"
+	"slices"
+	"strings"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func RegisterMiddlewares(r *gin.Engine) {
+// publicRoutes are the API routes reachable without a session.
+var publicRoutes = []string{
+	handler.APIPrefix + "/auth/status",
+	handler.APIPrefix + "/auth/setup",
+	handler.APIPrefix + "/auth/login",
+	handler.APIPrefix + "/auth/login/verify",
+	handler.APIPrefix + "/auth/oidc/login",
+	handler.APIPrefix + "/auth/oidc/callback",
+	handler.APIPrefix + "/openapi.json",
+}
+
+// NewOriginPolicy allows the origins of CORS_ALLOWED_ORIGINS and of the
+// allowed_origins setting, shared by CORS and the WebSocket upgrade.
+func NewOriginPolicy(settingRepository *repository.SettingRepository) *network.OriginPolicy {
+	return network.NewOriginPolicy(shared.NetworkConfig().AllowedOrigins, func() []string {
+		setting, err := settingRepository.GetByKey(settings.AllowedOrigins)
+		if err != nil {
+			return nil
+		}
+		return strings.FieldsFunc(setting.Value, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	})
+}
+
+func RegisterMiddlewares(r *gin.Engine, db *gorm.DB, settingRepository *repository.SettingRepository, origins *network.OriginPolicy) {
+	// The client IP (sessions, audit log) is only read from the headers of
+	// trusted proxies
+	networkConfig := shared.NetworkConfig()
+	if err := r.SetTrustedProxies(networkConfig.TrustedProxies); err != nil {
+		panic("Invalid trusted proxies: " + err.Error())
+	}
+	r.RemoteIPHeaders = []string{networkConfig.RealIPHeader}
+
+	r.Use(handler.RequestID())
+
 	config := cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173"},
+		AllowOriginFunc:  origins.Allowed,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", handler.RequestIDHeader},
+		ExposeHeaders:    []string{handler.RequestIDHeader, "Retry-After"},
 		AllowCredentials: true,
 	}
 	r.Use(cors.New(config))
+	// Registered first to also record the requests refused by authentication
+	r.Use(handler.AuditTrail(&repository.AuditEventRepository{DB: db}))
+	r.Use(authMiddleware(&repository.SessionRepository{DB: db}, &repository.APITokenRepository{DB: db}, settingRepository))
+}
+
+// authMiddleware requires a session or an API token on the API and the
+// WebSocket upgrade. The web UI itself is served to everyone, it shows the
+// login page.
+func authMiddleware(sessions *repository.SessionRepository, tokens *repository.APITokenRepository, settingRepository *repository.SettingRepository) gin.HandlerFunc {
+	requireSession := handler.RequireSession(sessions, settingRepository)
+	requireToken := handler.RequireToken(tokens)
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		protected := path == "/ws" || path == "/api" || strings.HasPrefix(path, "/api/")
+		if !protected || slices.Contains(publicRoutes, path) {
+			c.Next()
+			return
+		}
+		if strings.HasPrefix(c.GetHeader("Authorization"), "Bearer ") {
+			requireToken(c)
+			return
+		}
+		requireSession(c)
+	}
 }
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"axolotl-cloud/infra/openapi"
+	"axolotl-cloud/internal/app/handler"
+	"axolotl-cloud/internal/app/model"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// openAPIVersion is the version of the document, bumped with the API.
+const openAPIVersion = "1.0.0"
+
+// jobResponse is returned by the routes starting a job.
+var jobResponse = openapi.Object(map[string]*openapi.Schema{"job_id": openapi.Integer()})
+
+// openAPIRoutes describes every route of the API, relative to APIPrefix.
+// openapi_test.go checks it against the registered routes.
+var openAPIRoutes = []openapi.Route{
+	// auth.go
+	{Method: "GET", Path: "/auth/status", Tag: "auth", Summary: "Tell whether setup is required and single sign-on is enabled", Public: true,
+		Response: struct {
+			SetupRequired bool `json:"setup_required"`
+			OIDCEnabled   bool `json:"oidc_enabled"`
+		}{}},
+	{Method: "POST", Path: "/auth/setup", Tag: "auth", Summary: "Create the first admin account", Public: true,
+		Body: handler.RequestCredentials{}, Status: 201, Response: model.User{}},
+	{Method: "POST", Path: "/auth/login", Tag: "auth", Summary: "Log in with a password, or start a two-factor login", Public: true,
+		Body: handler.RequestCredentials{},
+		Response: struct {
+			model.User
+			TwoFactorRequired bool `json:"two_factor_required,omitempty"`
+		}{}},
+	{Method: "POST", Path: "/auth/login/verify", Tag: "auth", Summary: "End a two-factor login with a TOTP or recovery code", Public: true,
+		Body: handler.RequestSecondFactor{}, Response: model.User{}},
+	{Method: "POST", Path: "/auth/logout", Tag: "auth", Summary: "Log out", Status: 204},
+	{Method: "GET", Path: "/auth/me", Tag: "auth", Summary: "Get the current user", Response: model.User{}},
+	{Method: "PUT", Path: "/auth/password", Tag: "auth", Summary: "Change the password of the current user",
+		Body: handler.RequestChangePassword{}, Status: 204},
+	{Method: "GET", Path: "/auth/sessions", Tag: "auth", Summary: "List the sessions of the current user",
+		Response: []handler.SessionResponse{}},
+	{Method: "DELETE", Path: "/auth/sessions/:sessionId", Tag: "auth", Summary: "Revoke a session", Status: 204},
+	{Method: "GET", Path: "/auth/2fa", Tag: "auth", Summary: "Get the two-factor status of the current user",
+		Response: handler.TwoFactorStatusResponse{}},
+	{Method: "POST", Path: "/auth/2fa/setup", Tag: "auth", Summary: "Generate a TOTP secret",
+		Response: handler.TwoFactorSetupResponse{}},
+	{Method: "POST", Path: "/auth/2fa/enable", Tag: "auth", Summary: "Enable two-factor authentication with a first code",
+		Body: handler.RequestSecondFactor{}, Response: handler.RecoveryCodesResponse{}},
+	{Method: "POST", Path: "/auth/2fa/disable", Tag: "auth", Summary: "Disable two-factor authentication",
+		Body: handler.RequestSecondFactor{}, Status: 204},
+	{Method: "POST", Path: "/auth/2fa/recovery_codes", Tag: "auth", Summary: "Replace the recovery codes",
+		Body: handler.RequestSecondFactor{}, Response: handler.RecoveryCodesResponse{}},
+	{Method: "GET", Path: "/auth/oidc/login", Tag: "auth", Summary: "Redirect to the identity provider", Public: true, Status: 302},
+	{Method: "GET", Path: "/auth/oidc/callback", Tag: "auth", Summary: "End a single sign-on login", Public: true, Status: 302,
+		Query: struct {
+			Code             string `form:"code"`
+			State            string `form:"state"`
+			Error            string `form:"error"`
+			ErrorDescription string `form:"error_description"`
+		}{}},
+	{Method: "GET", Path: "/oidc/mappings", Tag: "oidc", Summary: "List the group mappings",
+		Response: []model.OIDCGroupMapping{}},
+	{Method: "POST", Path: "/oidc/mappings", Tag: "oidc", Summary: "Map a group to a project role, or to admin",
+		Body: handler.RequestCreateGroupMapping{}, Status: 201, Response: model.OIDCGroupMapping{}},
+	{Method: "DELETE", Path: "/oidc/mappings/:mappingId", Tag: "oidc", Summary: "Delete a group mapping", Status: 204},
+	{Method: "GET", Path: "/tokens", Tag: "tokens", Summary: "List the API tokens", Response: []model.APIToken{}},
+	{Method: "POST", Path: "/tokens", Tag: "tokens", Summary: "Create an API token, returned only once",
+		Body: handler.RequestCreateToken{}, Status: 201, Response: handler.CreatedTokenResponse{}},
+	{Method: "DELETE", Path: "/tokens/:tokenId", Tag: "tokens", Summary: "Revoke an API token", Status: 204},
+	{Method: "GET", Path: "/users", Tag: "users", Summary: "List the users", Response: []model.User{}},
+	{Method: "POST", Path: "/users", Tag: "users", Summary: "Create a user",
+		Body: handler.RequestCreateUser{}, Status: 201, Response: model.User{}},
+	{Method: "PUT", Path: "/users/:userId", Tag: "users", Summary: "Change the admin flag or the password of a user",
+		Body: handler.RequestUpdateUser{}, Response: model.User{}},
+	{Method: "DELETE", Path: "/users/:userId", Tag: "users", Summary: "Delete a user", Status: 204},
+	{Method: "DELETE", Path: "/users/:userId/2fa", Tag: "users", Summary: "Turn off the second factor of a user", Status: 204},
+
+	// project.go
+	{Method: "GET", Path: "/projects", Tag: "projects", Summary: "List the readable projects", Response: []model.Project{}},
+	{Method: "GET", Path: "/projects/:id", Tag: "projects", Summary: "Get a project", Response: model.Project{}},
+	{Method: "POST", Path: "/projects", Tag: "projects", Summary: "Create a project",
+		Body: model.Project{}, Status: 201, Response: model.Project{}},
+	{Method: "PUT", Path: "/projects/:id", Tag: "projects", Summary: "Update a project",
+		Body: model.Project{}, Response: model.Project{}},
+	{Method: "DELETE", Path: "/projects/:id", Tag: "projects", Summary: "Move a project to the trash and stop its containers",
+		Status: 201, Response: jobResponse},
+	{Method: "GET", Path: "/projects/:id/members", Tag: "projects", Summary: "List the members of a project",
+		Response: []model.ProjectMember{}},
+	{Method: "PUT", Path: "/projects/:id/members/:userId", Tag: "projects", Summary: "Set the role of a member",
+		Body: handler.RequestSetMember{}, Response: model.ProjectMember{}},
+	{Method: "DELETE", Path: "/projects/:id/members/:userId", Tag: "projects", Summary: "Remove a member", Status: 204},
+
+	// container.go
+	{Method: "POST", Path: "/projects/:id/containers", Tag: "containers", Summary: "Create a container",
+		Body: model.Container{}, Status: 201, Response: model.Container{}},
+	{Method: "GET", Path: "/projects/:id/containers", Tag: "containers", Summary: "List the containers of a project",
+		Response: []model.Container{}},
+	{Method: "GET", Path: "/projects/:id/containers/env", Tag: "containers", Summary: "Get the effective environment of the containers",
+		Response: []handler.ContainerEnvResponse{}},
+	{Method: "GET", Path: "/projects/:id/containers/:containerId", Tag: "containers", Summary: "Get a container",
+		Response: model.Container{}},
+	{Method: "PUT", Path: "/projects/:id/containers/:containerId", Tag: "containers", Summary: "Update a container",
+		Body: model.Container{}},
+	{Method: "DELETE", Path: "/projects/:id/containers/:containerId", Tag: "containers", Summary: "Move a container to the trash and stop it",
+		Status: 201, Response: jobResponse},
+	{Method: "GET", Path: "/projects/:id/containers/:containerId/status", Tag: "containers", Summary: "Get the Docker status of a container",
+		Response: struct {
+			Status string `json:"status"`
+		}{}},
+	{Method: "POST", Path: "/projects/:id/containers/:containerId/start", Tag: "containers", Summary: "Deploy and start a container",
+		Status: 201, Response: jobResponse},
+	{Method: "POST", Path: "/projects/:id/containers/:containerId/stop", Tag: "containers", Summary: "Stop a container",
+		Status: 201, Response: jobResponse},
+	{Method: "GET", Path: "/projects/:id/containers/:containerId/logs", Tag: "containers", Summary: "Get the logs of a container",
+		Query: struct {
+			Tail string `form:"tail"`
+		}{},
+		Response: openapi.String(), ResponseType: "text/plain"},
+	{Method: "GET", Path: "/projects/:id/containers/:containerId/builds", Tag: "builds", Summary: "List the builds of a container",
+		Response: []model.Build{}},
+	{Method: "POST", Path: "/projects/:id/containers/:containerId/rollback/:buildId", Tag: "builds", Summary: "Redeploy the image of a previous build",
+		Status: 201, Response: jobResponse},
+	{Method: "GET", Path: "/projects/:id/containers/:containerId/revisions", Tag: "revisions", Summary: "List the configuration revisions of a container",
+		Response: []model.ContainerRevision{}},
+	{Method: "GET", Path: "/projects/:id/containers/:containerId/revisions/diff", Tag: "revisions", Summary: "Compare two revisions, the latest by default",
+		Query: struct {
+			From uint `form:"from" binding:"required"`
+			To   uint `form:"to"`
+		}{},
+		Response: struct {
+			From    uint                 `json:"from"`
+			To      uint                 `json:"to"`
+			Changes []model.ConfigChange `json:"changes"`
+		}{}},
+	{Method: "POST", Path: "/projects/:id/containers/:containerId/revisions/:number/restore", Tag: "revisions", Summary: "Restore the configuration of a revision",
+		Response: model.Container{}},
+	{Method: "POST", Path: "/projects/:id/containers/import", Tag: "containers", Summary: "Import the services of a compose file",
+		Body: handler.RequestImportComposeFile{}, Status: 201, Response: []model.Container{}},
+	{Method: "POST", Path: "/projects/:id/containers/reimport", Tag: "containers", Summary: "Import the last compose file again",
+		Response: []model.Container{}},
+	{Method: "POST", Path: "/projects/:id/containers/build_from_source", Tag: "builds", Summary: "Build images from a Git repository",
+		Body: handler.RequestBuildFromSource{}, Status: 201, Response: jobResponse},
+	{Method: "POST", Path: "/projects/:id/containers/build_from_archive", Tag: "builds", Summary: "Build images from an uploaded archive",
+		Body: openapi.Object(map[string]*openapi.Schema{
+			"archive": {Type: "string", Format: "binary"},
+		}), BodyType: "multipart/form-data", Status: 201, Response: jobResponse},
+	{Method: "GET", Path: "/reconcile", Tag: "reconcile", Summary: "Compare the containers with the Docker daemon",
+		Response: handler.ReconcileReport{}},
+	{Method: "POST", Path: "/reconcile", Tag: "reconcile", Summary: "Adopt, remove or recreate a container",
+		Body: handler.RequestReconcileAction{}, Status: 201,
+		Response: struct {
+			JobID     uint             `json:"job_id,omitempty"`
+			Container *model.Container `json:"container,omitempty"`
+			Skipped   []string         `json:"skipped,omitempty"`
+		}{}},
+
+	// job.go
+	{Method: "GET", Path: "/jobs", Tag: "jobs", Summary: "List the readable jobs", Response: []model.Job{}},
+	{Method: "GET", Path: "/jobs/:id", Tag: "jobs", Summary: "Get a job and its logs", Response: model.Job{}},
+	{Method: "DELETE", Path: "/jobs/:id", Tag: "jobs", Summary: "Delete a job", Status: 204},
+
+	// volumes.go
+	{Method: "GET", Path: "/volumes", Tag: "volumes", Summary: "List the volumes of the readable containers, with their size",
+		Response: []model.Volume{}},
+
+	// setting.go
+	{Method: "GET", Path: "/settings", Tag: "settings", Summary: "List the settings", Response: []model.Setting{}},
+	{Method: "GET", Path: "/settings/:key", Tag: "settings", Summary: "Get a setting", Response: model.Setting{}},
+	{Method: "POST", Path: "/settings", Tag: "settings", Summary: "Save a setting",
+		Body: model.Setting{}, Response: model.Setting{}},
+	{Method: "DELETE", Path: "/settings/:key", Tag: "settings", Summary: "Delete a setting", Status: 204},
+
+	// secret.go
+	{Method: "GET", Path: "/secrets", Tag: "secrets", Summary: "List the secrets, masked", Response: []handler.SecretResponse{}},
+	{Method: "POST", Path: "/secrets", Tag: "secrets", Summary: "Create a secret",
+		Body: handler.RequestPutSecret{}, Status: 201, Response: handler.SecretResponse{}},
+	{Method: "PUT", Path: "/secrets/:name", Tag: "secrets", Summary: "Rotate a secret",
+		Body: handler.RequestPutSecret{}, Response: handler.SecretResponse{}},
+	{Method: "PUT", Path: "/secrets/:name/projects", Tag: "secrets", Summary: "Set the projects allowed to use a secret",
+		Body: handler.RequestSetSecretProjects{}, Response: handler.SecretResponse{}},
+	{Method: "DELETE", Path: "/secrets/:name", Tag: "secrets", Summary: "Delete an unused secret", Status: 204},
+
+	// trash.go
+	{Method: "GET", Path: "/trash", Tag: "trash", Summary: "List the deleted projects and containers", Response: handler.TrashResponse{}},
+	{Method: "POST", Path: "/trash/projects/:id/restore", Tag: "trash", Summary: "Restore a project", Response: model.Project{}},
+	{Method: "DELETE", Path: "/trash/projects/:id", Tag: "trash", Summary: "Purge a project", Status: 201, Response: jobResponse},
+	{Method: "POST", Path: "/trash/containers/:containerId/restore", Tag: "trash", Summary: "Restore a container", Response: model.Container{}},
+	{Method: "DELETE", Path: "/trash/containers/:containerId", Tag: "trash", Summary: "Purge a container", Status: 201, Response: jobResponse},
+
+	// audit.go
+	{Method: "GET", Path: "/audit", Tag: "audit", Summary: "Search the audit log",
+		Query: handler.RequestAuditQuery{}, Response: handler.AuditResponse{}},
+	{Method: "GET", Path: "/audit/export", Tag: "audit", Summary: "Download the audit events",
+		Query: handler.RequestAuditQuery{}, Response: []model.AuditEvent{}},
+
+	// openapi.go
+	{Method: "GET", Path: "/openapi.json", Tag: "meta", Summary: "Get this document", Public: true, Response: openapi.Object(nil)},
+}
+
+// NewOpenAPIDocument describes the API.
+func NewOpenAPIDocument() *openapi.Document {
+	document := openapi.NewDocument(openapi.Info{
+		Title:       "Axolotl Cloud API",
+		Version:     openAPIVersion,
+		Description: "Routes are also served under the deprecated /api prefix.",
+	}, map[reflect.Type]*openapi.Schema{
+		reflect.TypeOf(gorm.DeletedAt{}): {Type: "string", Format: "date-time", Nullable: true},
+	})
+	document.Servers = []openapi.Server{{URL: handler.APIPrefix}}
+	document.Errors = document.SchemaOf(reflect.TypeOf(handler.ErrorResponse{}))
+	document.Components.SecuritySchemes = map[string]openapi.SecurityScheme{
+		"session": {Type: "apiKey", In: "cookie", Name: handler.SessionCookie},
+		"token":   {Type: "http", Scheme: "bearer"},
+	}
+	document.Security = []openapi.SecurityRequirement{{"session": {}}, {"token": {}}}
+	document.Add(openAPIRoutes...)
+	return document
+}
+
+// RegisterOpenAPIRoutes serves the document of the API.
+func RegisterOpenAPIRoutes(apiGroup *gin.RouterGroup) {
+	document := NewOpenAPIDocument()
+	apiGroup.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(200, document)
+	})
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"axolotl-cloud/infra/db"
+	"axolotl-cloud/infra/secrets"
+	"axolotl-cloud/infra/worker"
+	"axolotl-cloud/internal/app/handler"
+	"axolotl-cloud/internal/app/repository"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestOpenAPIRoutes checks that the OpenAPI document lists every route of the
+// API, and only them.
+func TestOpenAPIRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("DATABASE_PATH", filepath.Join(t.TempDir(), "data.db"))
+	database, err := db.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := secrets.NewCipher(make([]byte, secrets.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	jobWorker := worker.NewWorker(1, &repository.JobRepository{DB: database})
+	registerAPIRoutes(r, database, repository.NewSettingRepository(database), cipher, nil, jobWorker)
+
+	document := NewOpenAPIDocument()
+	registered := make(map[string]bool)
+	for _, route := range r.Routes() {
+		path, ok := strings.CutPrefix(route.Path, handler.APIPrefix)
+		if !ok {
+			continue
+		}
+		registered[route.Method+" "+path] = true
+		if !document.Has(route.Method, path) {
+			t.Errorf("%s %s is missing from the OpenAPI document", route.Method, route.Path)
+		}
+	}
+	for _, route := range openAPIRoutes {
+		if !registered[route.Method+" "+route.Path] {
+			t.Errorf("%s %s%s is in the OpenAPI document but not registered", route.Method, handler.APIPrefix, route.Path)
+		}
+	}
+}
@@ -1,23 +1,36 @@
 package api
 
 import (
+	"axolotl-cloud/infra/docker"
+	"axolotl-cloud/infra/worker"
 	"axolotl-cloud/internal/app/handler"
+	"axolotl-cloud/internal/app/model"
 	"axolotl-cloud/internal/app/repository"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func RegisterProjectRoutes(r *gin.RouterGroup, db *gorm.DB) {
+func RegisterProjectRoutes(r *gin.RouterGroup, db *gorm.DB, dockerClient *docker.DockerClient, w *worker.Worker, access *handler.Access) {
 	projectHandler := &handler.ProjectHandler{
-		ProjectRepository: &repository.ProjectRepository{DB: db},
+		ProjectRepository:   &repository.ProjectRepository{DB: db},
+		ContainerRepository: &repository.ContainerRepository{DB: db},
+		MemberRepository:    &repository.ProjectMemberRepository{DB: db},
+		UserRepository:      &repository.UserRepository{DB: db},
+		Access:              access,
+		DockerClient:        dockerClient,
+		JobWorker:           w,
 	}
 	projectGroup := r.Group("/projects")
 	{
 		projectGroup.GET("", projectHandler.GetAllProjects)
-		projectGroup.GET("/:id", projectHandler.GetProjectByID)
-		projectGroup.POST("", projectHandler.CreateProject)
-		projectGroup.PUT("/:id", projectHandler.UpdateProject)
-		projectGroup.DELETE("/:id", projectHandler.DeleteProject)
+		projectGroup.GET("/:id", access.RequireRole(model.RoleViewer), projectHandler.GetProjectByID)
+		projectGroup.POST("", handler.RequireProjectCreator, projectHandler.CreateProject)
+		projectGroup.PUT("/:id", access.RequireRole(model.RoleOwner), projectHandler.UpdateProject)
+		projectGroup.DELETE("/:id", access.RequireRole(model.RoleOwner), projectHandler.DeleteProject)
+
+		projectGroup.GET("/:id/members", access.RequireRole(model.RoleViewer), projectHandler.GetProjectMembers)
+		projectGroup.PUT("/:id/members/:userId", access.RequireRole(model.RoleOwner), projectHandler.SetProjectMember)
+		projectGroup.DELETE("/:id/members/:userId", access.RequireRole(model.RoleOwner), projectHandler.RemoveProjectMember)
 	}
 }
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"axolotl-cloud/infra/ratelimit"
+	"axolotl-cloud/internal/app/handler"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request budgets per client: a request every interval on average, in bursts
+// of up to burst requests.
+const (
+	apiRateInterval = 50 * time.Millisecond
+	apiRateBurst    = 200
+	// Login, setup and single sign-on, per IP
+	authRateInterval = 6 * time.Second
+	authRateBurst    = 10
+	// Builds clone and build images on the host
+	buildRateInterval = 30 * time.Second
+	buildRateBurst    = 5
+	// Listing volumes walks every file of every volume
+	volumeRateInterval = 10 * time.Second
+	volumeRateBurst    = 6
+)
+
+// Logins are locked out for a minute after 5 failures, then twice as long
+// after every other failure, up to an hour. A username is also locked out
+// from every IP after 20 failures, up to 15 minutes.
+const (
+	loginFreeFailures = 5
+	loginLockout      = time.Minute
+	loginMaxLockout   = time.Hour
+	userFreeFailures  = 20
+	userMaxLockout    = 15 * time.Minute
+)
+
+func rateLimit(interval time.Duration, burst int) gin.HandlerFunc {
+	return handler.RateLimit(ratelimit.NewLimiter(interval, burst))
+}
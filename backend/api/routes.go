@@ -2,21 +2,49 @@ package api
 
 import (
 	"axolotl-cloud/infra/docker"
+	"axolotl-cloud/infra/secrets"
+	"axolotl-cloud/infra/shared"
+	"axolotl-cloud/infra/websocket"
 	"axolotl-cloud/infra/worker"
+	"axolotl-cloud/internal/app/handler"
+	"axolotl-cloud/internal/app/repository"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func RegisterRoutes(r *gin.Engine, db *gorm.DB, dockerClient *docker.DockerClient, jobWorker *worker.Worker) {
-	apiGroup := r.Group("/api")
-	{
-		RegisterProjectRoutes(apiGroup, db)
-		RegisterContainerRoutes(apiGroup, db, dockerClient, jobWorker)
-		RegisterJobsRoutes(apiGroup, db, jobWorker)
-		RegisterVolumeRoutes(apiGroup, db, dockerClient)
-		RegisterSettingRoutes(apiGroup, db)
+func RegisterRoutes(r *gin.Engine, db *gorm.DB, settingRepository *repository.SettingRepository, dockerClient *docker.DockerClient, jobWorker *worker.Worker, wss *websocket.WebSocketServer) {
+	cipher, err := secrets.NewCipher(shared.MasterKey())
+	if err != nil {
+		panic("Failed to initialize secrets store: " + err.Error())
 	}
-
+	access := registerAPIRoutes(r, db, settingRepository, cipher, dockerClient, jobWorker)
+	RegisterWebSocketRoutes(r, wss, db, access)
 	RegisterFrontRoutes(r)
 }
+
+// registerAPIRoutes registers the routes under APIPrefix, the ones listed in
+// the OpenAPI document.
+func registerAPIRoutes(r *gin.Engine, db *gorm.DB, settingRepository *repository.SettingRepository, cipher *secrets.Cipher, dockerClient *docker.DockerClient, jobWorker *worker.Worker) *handler.Access {
+	secretRepository := &repository.SecretRepository{DB: db, Cipher: cipher}
+
+	access := &handler.Access{
+		MemberRepository:    &repository.ProjectMemberRepository{DB: db},
+		ContainerRepository: &repository.ContainerRepository{DB: db},
+	}
+
+	apiGroup := r.Group(handler.APIPrefix, rateLimit(apiRateInterval, apiRateBurst))
+	{
+		RegisterAuthRoutes(apiGroup, db, settingRepository, cipher, access)
+		RegisterProjectRoutes(apiGroup, db, dockerClient, jobWorker, access)
+		RegisterContainerRoutes(apiGroup, db, dockerClient, jobWorker, settingRepository, secretRepository, access)
+		RegisterJobsRoutes(apiGroup, db, jobWorker, access)
+		RegisterVolumeRoutes(apiGroup, db, dockerClient, access)
+		RegisterSettingRoutes(apiGroup, settingRepository)
+		RegisterSecretRoutes(apiGroup, db, secretRepository)
+		RegisterTrashRoutes(apiGroup, db, dockerClient, jobWorker, settingRepository)
+		RegisterAuditRoutes(apiGroup, db)
+		RegisterOpenAPIRoutes(apiGroup)
+	}
+	return access
+}
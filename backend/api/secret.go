@@ -0,0 +1,25 @@
+package api
+
+import (
+	"axolotl-cloud/internal/app/handler"
+	"axolotl-cloud/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterSecretRoutes(r *gin.RouterGroup, db *gorm.DB, secretRepository *repository.SecretRepository) {
+	secretHandler := &handler.SecretHandler{
+		SecretRepository:    secretRepository,
+		ContainerRepository: &repository.ContainerRepository{DB: db},
+		ProjectRepository:   &repository.ProjectRepository{DB: db},
+	}
+	secretGroup := r.Group("/secrets")
+	{
+		secretGroup.GET("", handler.RequireAdmin, secretHandler.GetAllSecrets)
+		secretGroup.POST("", handler.RequireAdmin, secretHandler.CreateSecret)
+		secretGroup.PUT("/:name", handler.RequireAdmin, secretHandler.RotateSecret)
+		secretGroup.PUT("/:name/projects", handler.RequireAdmin, secretHandler.SetSecretProjects)
+		secretGroup.DELETE("/:name", handler.RequireAdmin, secretHandler.DeleteSecret)
+	}
+}
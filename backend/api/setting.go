@@ -5,18 +5,17 @@ import (
 	"axolotl-cloud/internal/app/repository"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
-func RegisterSettingRoutes(router *gin.RouterGroup, db *gorm.DB) {
+func RegisterSettingRoutes(router *gin.RouterGroup, settingRepository *repository.SettingRepository) {
 	settingHandler := &handler.SettingHandler{
-		SettingRepository: repository.NewSettingRepository(db),
+		SettingRepository: settingRepository,
 	}
 	settingGroup := router.Group("/settings")
 	{
 		settingGroup.GET("", settingHandler.GetAllSettings)
 		settingGroup.GET("/:key", settingHandler.GetSettingByKey)
-		settingGroup.POST("", settingHandler.SaveSetting)
-		settingGroup.DELETE("/:key", settingHandler.DeleteSetting)
+		settingGroup.POST("", handler.RequireAdmin, settingHandler.SaveSetting)
+		settingGroup.DELETE("/:key", handler.RequireAdmin, settingHandler.DeleteSetting)
 	}
 }
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"axolotl-cloud/infra/docker"
+	"axolotl-cloud/infra/worker"
+	"axolotl-cloud/internal/app/handler"
+	"axolotl-cloud/internal/app/repository"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterTrashRoutes(r *gin.RouterGroup, db *gorm.DB, dockerClient *docker.DockerClient, w *worker.Worker, settingRepository *repository.SettingRepository) {
+	trashHandler := &handler.TrashHandler{
+		ProjectRepository:   &repository.ProjectRepository{DB: db},
+		ContainerRepository: &repository.ContainerRepository{DB: db},
+		BuildRepository:     &repository.BuildRepository{DB: db},
+		SettingRepository:   settingRepository,
+		DockerClient:        dockerClient,
+		JobWorker:           w,
+	}
+	trashHandler.StartPurgeScheduler(time.Hour)
+
+	trashGroup := r.Group("/trash", handler.RequireAdmin)
+	{
+		trashGroup.GET("", trashHandler.GetTrash)
+		trashGroup.POST("/projects/:id/restore", trashHandler.RestoreProject)
+		trashGroup.DELETE("/projects/:id", trashHandler.PurgeProject)
+		trashGroup.POST("/containers/:containerId/restore", trashHandler.RestoreContainer)
+		trashGroup.DELETE("/containers/:containerId", trashHandler.PurgeContainer)
+	}
+}
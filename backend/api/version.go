@@ -0,0 +1,36 @@
+package api
+
+import (
+	"axolotl-cloud/internal/app/handler"
+	"net/http"
+	"strings"
+)
+
+// legacyPrefix is the unversioned prefix of the API, kept as an alias of
+// APIPrefix while clients move to it.
+const legacyPrefix = "/api"
+
+// WithLegacyPrefix serves the requests made under /api with the routes of
+// /api/v1. Their responses are flagged as deprecated, with a link to the
+// versioned route.
+func WithLegacyPrefix(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if legacyPath(path) {
+			r.URL.Path = handler.APIPrefix + strings.TrimPrefix(path, legacyPrefix)
+			if r.URL.RawPath != "" {
+				r.URL.RawPath = handler.APIPrefix + strings.TrimPrefix(r.URL.RawPath, legacyPrefix)
+			}
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+r.URL.Path+`>; rel="successor-version"`)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func legacyPath(path string) bool {
+	if path != legacyPrefix && !strings.HasPrefix(path, legacyPrefix+"/") {
+		return false
+	}
+	return path != handler.APIPrefix && !strings.HasPrefix(path, handler.APIPrefix+"/")
+}
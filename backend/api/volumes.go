@@ -9,10 +9,11 @@ import (
 	"gorm.io/gorm"
 )
 
-func RegisterVolumeRoutes(router *gin.RouterGroup, db *gorm.DB, dockerClient *docker.DockerClient) {
+func RegisterVolumeRoutes(router *gin.RouterGroup, db *gorm.DB, dockerClient *docker.DockerClient, access *handler.Access) {
 	volumeHandler := &handler.VolumeHandler{
 		ContainerRepository: &repository.ContainerRepository{DB: db},
+		Access:              access,
 		DockerClient:        dockerClient,
 	}
-	router.GET("/volumes", volumeHandler.GetVolumes)
+	router.GET("/volumes", rateLimit(volumeRateInterval, volumeRateBurst), volumeHandler.GetVolumes)
 }
@@ -2,12 +2,16 @@ package api
 
 import (
 	"axolotl-cloud/infra/websocket"
+	"axolotl-cloud/internal/app/handler"
+	"axolotl-cloud/internal/app/repository"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func RegisterWebSocketRoutes(r *gin.Engine, wss *websocket.WebSocketServer) {
+func RegisterWebSocketRoutes(r *gin.Engine, wss *websocket.WebSocketServer, db *gorm.DB, access *handler.Access) {
+	jobRepository := &repository.JobRepository{DB: db}
 	r.GET("/ws", func(c *gin.Context) {
-		wss.HandleHTTP(c.Writer, c.Request)
+		wss.HandleHTTP(c.Writer, c.Request, access.TopicAuthorizer(c, jobRepository))
 	})
 }
@@ -5,13 +5,17 @@ import (
 	"axolotl-cloud/infra/db"
 	"axolotl-cloud/infra/docker"
 	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/infra/network"
 	"axolotl-cloud/infra/settings"
 	"axolotl-cloud/infra/shared"
 	"axolotl-cloud/infra/websocket"
 	"axolotl-cloud/infra/worker"
+	"axolotl-cloud/internal/app/handler"
 	"axolotl-cloud/internal/app/repository"
 	"context"
 	"fmt"
+	"net/http"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -60,8 +64,9 @@ func initDockerClient() *docker.DockerClient {
 	return dockerClient
 }
 
-func initWSServer() *websocket.WebSocketServer {
+func initWSServer(origins *network.OriginPolicy) *websocket.WebSocketServer {
 	return &websocket.WebSocketServer{
+		Upgrader:  websocket.NewUpgrader(origins.CheckRequest),
 		OnConnect: func(conn websocket.WebSocketConnection) {},
 		OnMessage: func(conn websocket.WebSocketConnection, data websocket.WSMessage[any]) {
 			websocket.NewWSMessageHandler(conn).HandleMessage(data)
@@ -79,14 +84,43 @@ func main() {
 	db := initDB()
 	dockerClient := initDockerClient()
 	defer dockerClient.Close()
-	wss := initWSServer()
+
+	// Shared so that every handler sees setting updates through the same cache
+	settingRepository := repository.NewSettingRepository(db)
+	origins := api.NewOriginPolicy(settingRepository)
+	wss := initWSServer(origins)
 
 	jobWorker, cancel := initWorker(db)
 	defer cancel()
 
-	r := gin.Default()
-	api.RegisterMiddlewares(r)
-	api.RegisterWebSocketRoutes(r, wss)
-	api.RegisterRoutes(r, db, dockerClient, jobWorker)
-	r.Run(":" + shared.GetEnv("HTTP_PORT"))
+	r := gin.New()
+	r.Use(gin.Logger(), handler.Recover())
+	api.RegisterMiddlewares(r, db, settingRepository, origins)
+	api.RegisterRoutes(r, db, settingRepository, dockerClient, jobWorker, wss)
+	if err := run(r); err != nil {
+		panic("Failed to start HTTP server: " + err.Error())
+	}
+}
+
+// run serves HTTP, or HTTPS with the configured or a self-signed certificate.
+func run(r *gin.Engine) error {
+	addr := ":" + shared.GetEnv("HTTP_PORT")
+	server := api.WithLegacyPrefix(r)
+	config := shared.NetworkConfig()
+	switch {
+	case config.TLSCertFile != "":
+		logger.Info("Serving HTTPS with certificate %s", config.TLSCertFile)
+		return http.ListenAndServeTLS(addr, config.TLSCertFile, config.TLSKeyFile, server)
+	case config.TLSSelfSigned:
+		// Kept next to the database, to be trusted once by the browsers
+		dir := filepath.Join(filepath.Dir(shared.GetEnv("DATABASE_PATH")), "tls")
+		certFile, keyFile, err := network.SelfSignedCertificate(dir, config.TLSHosts)
+		if err != nil {
+			return fmt.Errorf("failed to generate self-signed certificate: %w", err)
+		}
+		logger.Info("Serving HTTPS with self-signed certificate %s", certFile)
+		return http.ListenAndServeTLS(addr, certFile, keyFile, server)
+	default:
+		return http.ListenAndServe(addr, server)
+	}
 }
@@ -0,0 +1,242 @@
+// Command mock-oidc is an OpenID Connect provider for development and manual
+// testing of the single sign-on. It signs in anyone: the authorization page
+// asks for a username and groups, which end up in the ID token.
+//
+//	go run ./cmd/mock-oidc -addr :9999
+//	OIDC_ISSUER=http://localhost:9999 OIDC_CLIENT_ID=axolotl \
+//	OIDC_REDIRECT_URL=http://localhost:8080/api/v1/auth/oidc/callback go run ./cmd
+//
+// The username and groups can also be passed as query parameters of the
+// authorization request (login_hint and groups) to skip the form.
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const keyID = "mock"
+
+// grant is an authorization code waiting to be exchanged.
+type grant struct {
+	clientID    string
+	redirectURI string
+	nonce       string
+	challenge   string
+	username    string
+	groups      []string
+	expiresAt   time.Time
+}
+
+type server struct {
+	issuer string
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]*grant
+}
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!doctype html>
+<title>Mock OIDC</title>
+<form method="post">
+  {{range $name, $values := .}}<input type="hidden" name="{{$name}}" value="{{index $values 0}}">
+  {{end}}<p><label>Username <input name="login_hint" required autofocus></label></p>
+  <p><label>Groups (comma separated) <input name="groups"></label></p>
+  <button>Sign in</button>
+</form>
+`))
+
+func main() {
+	addr := flag.String("addr", ":9999", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9999", "issuer URL, as reached by the browser and the backend")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := &server{issuer: strings.TrimSuffix(*issuer, "/"), key: key, grants: map[string]*grant{}}
+
+	http.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	http.HandleFunc("/jwks", s.jwks)
+	http.HandleFunc("/authorize", s.authorize)
+	http.HandleFunc("/token", s.token)
+
+	log.Printf("Mock OIDC provider %s listening on %s", s.issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func (s *server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, 200, map[string]any{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, 200, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   encode(s.key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize shows the sign in form, then redirects back to the client with a
+// code.
+func (s *server) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	query := r.Form
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "only the authorization code flow with S256 PKCE is supported", 400)
+		return
+	}
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" {
+		http.Error(w, "invalid redirect_uri", 400)
+		return
+	}
+	if query.Get("login_hint") == "" {
+		authorizePage.Execute(w, query)
+		return
+	}
+
+	var groups []string
+	for _, group := range strings.Split(query.Get("groups"), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	code := randomString()
+	s.mu.Lock()
+	s.grants[code] = &grant{
+		clientID:    query.Get("client_id"),
+		redirectURI: query.Get("redirect_uri"),
+		nonce:       query.Get("nonce"),
+		challenge:   query.Get("code_challenge"),
+		username:    query.Get("login_hint"),
+		groups:      groups,
+		expiresAt:   time.Now().Add(time.Minute),
+	}
+	s.mu.Unlock()
+
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token exchanges a code for an ID token, once, after checking the PKCE
+// verifier.
+func (s *server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, 400, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	s.mu.Lock()
+	g := s.grants[r.PostForm.Get("code")]
+	delete(s.grants, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	clientID := r.PostForm.Get("client_id")
+	if user, _, ok := r.BasicAuth(); ok {
+		clientID = user
+	}
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if g == nil || time.Now().After(g.expiresAt) || g.clientID != clientID ||
+		g.redirectURI != r.PostForm.Get("redirect_uri") || encode(challenge[:]) != g.challenge {
+		writeJSON(w, 400, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idToken, err := s.sign(map[string]any{
+		"iss":                s.issuer,
+		"sub":                g.username,
+		"aud":                g.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+		"nonce":              g.nonce,
+		"preferred_username": g.username,
+		"email":              g.username + "@example.com",
+		"email_verified":     true,
+		"groups":             g.groups,
+	})
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, 200, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// sign returns the claims as a JWT signed with RS256.
+func (s *server) sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := encode(header) + "." + encode(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + encode(signature), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate random bytes: %v", err))
+	}
+	return encode(b)
+}
@@ -0,0 +1,213 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// MaxUploadSize is the maximum size of an uploaded archive.
+	MaxUploadSize = 256 << 20
+	// MaxExtractedSize is the maximum size of the extracted files, to protect
+	// against decompression bombs.
+	MaxExtractedSize = 1 << 30
+	// MaxFiles is the maximum number of entries of an archive.
+	MaxFiles = 50000
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported archive format, expected .tar.gz, .tgz or .zip")
+	ErrTooLarge          = errors.New("archive content exceeds the size limit")
+	ErrTooManyFiles      = errors.New("archive contains too many files")
+)
+
+// IsSupported reports whether the file name has an extension Extract knows.
+func IsSupported(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz") || strings.HasSuffix(name, ".zip")
+}
+
+// Extract unpacks the archive at path into dest. The format is chosen from
+// name (the original file name of the upload). Entries escaping dest, links
+// and special files are rejected.
+func Extract(path string, name string, dest string) error {
+	e := &extractor{dest: dest, maxSize: MaxExtractedSize, maxFiles: MaxFiles}
+	var err error
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		err = e.extractZip(path)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		err = e.extractTarGz(path)
+	default:
+		return ErrUnsupportedFormat
+	}
+	if err != nil {
+		return err
+	}
+	return flattenSingleRoot(dest)
+}
+
+// extractor enforces the limits shared by both formats.
+type extractor struct {
+	dest     string
+	maxSize  int64
+	maxFiles int
+	size     int64
+	files    int
+}
+
+// target resolves the destination path of an entry, refusing anything that
+// would land outside of dest.
+func (e *extractor) target(name string) (string, error) {
+	e.files++
+	if e.files > e.maxFiles {
+		return "", ErrTooManyFiles
+	}
+
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+	return filepath.Join(e.dest, clean), nil
+}
+
+func (e *extractor) writeFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	remaining := e.maxSize - e.size
+	n, err := io.Copy(f, io.LimitReader(r, remaining+1))
+	e.size += n
+	if err != nil {
+		return err
+	}
+	if e.size > e.maxSize {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+func (e *extractor) extractTarGz(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("invalid gzip archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar archive: %w", err)
+		}
+
+		target, err := e.target(hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := e.writeFile(target, tr, hdr.FileInfo().Mode()); err != nil {
+				return err
+			}
+		case tar.TypeXGlobalHeader:
+			// pax metadata written by git archive, nothing to extract
+		default:
+			return fmt.Errorf("unsupported entry %s in archive (links and special files are not allowed)", hdr.Name)
+		}
+	}
+}
+
+func (e *extractor) extractZip(path string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		target, err := e.target(zf.Name)
+		if err != nil {
+			return err
+		}
+
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := zf.Open()
+			if err != nil {
+				return err
+			}
+			err = e.writeFile(target, rc, mode)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported entry %s in archive (links and special files are not allowed)", zf.Name)
+		}
+	}
+	return nil
+}
+
+// flattenSingleRoot moves the content of dest/<root> up to dest when the
+// archive holds a single top-level directory, as archives downloaded from git
+// forges do.
+func flattenSingleRoot(dest string) error {
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return nil
+	}
+
+	// Rename the root first so that a child with the same name can move up.
+	root := filepath.Join(dest, ".axolotl-root")
+	if err := os.Rename(filepath.Join(dest, entries[0].Name()), root); err != nil {
+		return err
+	}
+	children, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := os.Rename(filepath.Join(root, child.Name()), filepath.Join(dest, child.Name())); err != nil {
+			return err
+		}
+	}
+	return os.Remove(root)
+}
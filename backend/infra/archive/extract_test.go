@@ -0,0 +1,213 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// entry is a file of a test archive.
+type entry struct {
+	name string
+	body string
+	dir  bool
+	// link is the target of a symbolic link
+	link string
+	// hardlink is the target of a hard link, tar only
+	hardlink string
+}
+
+func writeTarGz(t *testing.T, path string, entries []entry) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		switch {
+		case e.dir:
+			hdr.Typeflag, hdr.Mode, hdr.Size = tar.TypeDir, 0755, 0
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		case e.hardlink != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeLink, e.hardlink, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, path string, entries []entry) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		body := e.body
+		switch {
+		case e.dir:
+			hdr.Name = strings.TrimSuffix(e.name, "/") + "/"
+			hdr.SetMode(os.ModeDir | 0755)
+		case e.link != "":
+			hdr.SetMode(os.ModeSymlink | 0777)
+			body = e.link
+		case e.hardlink != "":
+			t.Fatal("zip archives have no hard links")
+		default:
+			hdr.SetMode(0644)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+		tarOnly bool
+		// want are the extracted files and their content
+		want map[string]string
+		// wantErr is part of the expected error
+		wantErr string
+	}{
+		{
+			name:    "files",
+			entries: []entry{{name: "Dockerfile", body: "FROM scratch"}, {name: "src", dir: true}, {name: "src/main.go", body: "package main"}},
+			want:    map[string]string{"Dockerfile": "FROM scratch", "src/main.go": "package main"},
+		},
+		{
+			name:    "single root flattened",
+			entries: []entry{{name: "repo-main/", dir: true}, {name: "repo-main/Dockerfile", body: "FROM scratch"}, {name: "repo-main/repo-main/x", body: "x"}},
+			want:    map[string]string{"Dockerfile": "FROM scratch", "repo-main/x": "x"},
+		},
+		{name: "parent traversal", entries: []entry{{name: "../evil", body: "x"}}, wantErr: "illegal path"},
+		{name: "nested traversal", entries: []entry{{name: "a/../../evil", body: "x"}}, wantErr: "illegal path"},
+		{name: "absolute path", entries: []entry{{name: "/tmp/evil", body: "x"}}, wantErr: "illegal path"},
+		{name: "symbolic link", entries: []entry{{name: "passwd", link: "/etc/passwd"}}, wantErr: "links and special files are not allowed"},
+		{name: "relative symbolic link", entries: []entry{{name: "up", link: ".."}, {name: "up/evil", body: "x"}}, wantErr: "links and special files are not allowed"},
+		{name: "hard link", entries: []entry{{name: "passwd", hardlink: "/etc/passwd"}}, tarOnly: true, wantErr: "links and special files are not allowed"},
+	}
+	formats := []struct {
+		name  string
+		write func(t *testing.T, path string, entries []entry)
+	}{
+		{name: "source.tar.gz", write: writeTarGz},
+		{name: "source.zip", write: writeZip},
+	}
+	for _, format := range formats {
+		for _, test := range tests {
+			if test.tarOnly && format.name == "source.zip" {
+				continue
+			}
+			t.Run(format.name+"/"+test.name, func(t *testing.T) {
+				dir := t.TempDir()
+				path := filepath.Join(dir, format.name)
+				format.write(t, path, test.entries)
+				dest := filepath.Join(dir, "a", "dest")
+				if err := os.MkdirAll(dest, 0755); err != nil {
+					t.Fatal(err)
+				}
+
+				err := Extract(path, format.name, dest)
+				for _, escaped := range []string{filepath.Join(dir, "a", "evil"), filepath.Join(dir, "evil")} {
+					if _, statErr := os.Lstat(escaped); statErr == nil {
+						t.Errorf("%s was written outside of the destination", escaped)
+					}
+				}
+				if test.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+						t.Fatalf("Extract() = %v, want an error containing %q", err, test.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Extract() = %v", err)
+				}
+				for name, body := range test.want {
+					got, err := os.ReadFile(filepath.Join(dest, name))
+					if err != nil {
+						t.Errorf("%s not extracted: %v", name, err)
+					} else if string(got) != body {
+						t.Errorf("%s = %q, want %q", name, got, body)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+		wantErr error
+	}{
+		{name: "under the limits", entries: []entry{{name: "a", body: "12345"}, {name: "b", body: "12345"}}},
+		{name: "too large", entries: []entry{{name: "a", body: "12345"}, {name: "b", body: "123456"}}, wantErr: ErrTooLarge},
+		{name: "single file too large", entries: []entry{{name: "a", body: strings.Repeat("0", 1000)}}, wantErr: ErrTooLarge},
+		{name: "too many files", entries: []entry{{name: "a"}, {name: "b"}, {name: "c"}, {name: "d"}}, wantErr: ErrTooManyFiles},
+		{name: "directories count", entries: []entry{{name: "a", dir: true}, {name: "b", dir: true}, {name: "c", dir: true}, {name: "c/d"}}, wantErr: ErrTooManyFiles},
+	}
+	formats := []struct {
+		name    string
+		write   func(t *testing.T, path string, entries []entry)
+		extract func(e *extractor, path string) error
+	}{
+		{name: "tar.gz", write: writeTarGz, extract: (*extractor).extractTarGz},
+		{name: "zip", write: writeZip, extract: (*extractor).extractZip},
+	}
+	for _, format := range formats {
+		for _, test := range tests {
+			t.Run(format.name+"/"+test.name, func(t *testing.T) {
+				dir := t.TempDir()
+				path := filepath.Join(dir, "source."+format.name)
+				format.write(t, path, test.entries)
+
+				e := &extractor{dest: filepath.Join(dir, "dest"), maxSize: 10, maxFiles: 3}
+				err := format.extract(e, path)
+				if !errors.Is(err, test.wantErr) {
+					t.Errorf("extract() = %v, want %v", err, test.wantErr)
+				}
+				if e.size > e.maxSize+1 {
+					t.Errorf("%d bytes written, the limit is %d", e.size, e.maxSize)
+				}
+			})
+		}
+	}
+}
+
+func TestExtractUnsupportedFormat(t *testing.T) {
+	if err := Extract("source.rar", "source.rar", t.TempDir()); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Extract() = %v, want %v", err, ErrUnsupportedFormat)
+	}
+}
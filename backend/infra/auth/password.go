@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the minimum length of a user password.
+const MinPasswordLength = 10
+
+var ErrPasswordTooShort = fmt.Errorf("password must be at least %d characters long", MinPasswordLength)
+
+// dummyHash is compared against when a login names an unknown user, so that
+// the response time does not reveal which users exist.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("axolotl-dummy-password"), bcrypt.DefaultCost)
+
+// HashPassword returns the bcrypt hash of password.
+func HashPassword(password string) ([]byte, error) {
+	if len(password) < MinPasswordLength {
+		return nil, ErrPasswordTooShort
+	}
+	// bcrypt ignores everything past 72 bytes, refuse instead of truncating
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return nil, fmt.Errorf("password must be at most 72 bytes long")
+	}
+	return hash, err
+}
+
+// CheckPassword reports whether password matches hash. A nil hash (unknown
+// user) never matches but takes as long as a real comparison.
+func CheckPassword(hash []byte, password string) bool {
+	if hash == nil {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
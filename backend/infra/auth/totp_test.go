@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 secret of the test vectors of RFC 6238.
+var rfcSecret = base32NoPadding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCheckTOTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		at       int64
+		wantStep int64
+		want     bool
+	}{
+		// RFC 6238 appendix B, last 6 digits
+		{name: "rfc 59", code: "287082", at: 59, wantStep: 1, want: true},
+		{name: "rfc 1111111109", code: "081804", at: 1111111109, wantStep: 37037036, want: true},
+		{name: "rfc 1111111111", code: "050471", at: 1111111111, wantStep: 37037037, want: true},
+		{name: "rfc 1234567890", code: "005924", at: 1234567890, wantStep: 41152263, want: true},
+		{name: "rfc 2000000000", code: "279037", at: 2000000000, wantStep: 66666666, want: true},
+		{name: "with spaces", code: "287 082", at: 59, wantStep: 1, want: true},
+		{name: "previous period", code: "287082", at: 59 + 30, wantStep: 1, want: true},
+		{name: "next period", code: "081804", at: 1111111109 - 30, wantStep: 37037036, want: true},
+		{name: "two periods late", code: "287082", at: 59 + 60},
+		{name: "two periods early", code: "081804", at: 1111111109 - 60},
+		{name: "wrong code", code: "287083", at: 59},
+		{name: "too short", code: "28708", at: 59},
+		{name: "too long", code: "2870820", at: 59},
+		{name: "empty", code: "", at: 59},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			step, ok := CheckTOTP(rfcSecret, test.code, time.Unix(test.at, 0))
+			if ok != test.want || (ok && step != test.wantStep) {
+				t.Errorf("CheckTOTP(%q, %d) = %d, %v, want %d, %v", test.code, test.at, step, ok, test.wantStep, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckTOTPInvalidSecret(t *testing.T) {
+	if _, ok := CheckTOTP("not base32!", "287082", time.Unix(59, 0)); ok {
+		t.Error("CheckTOTP() accepted a code for an invalid secret")
+	}
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("NewRecoveryCodes() returned %d codes, want %d", len(codes), RecoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' || seen[code] {
+			t.Errorf("invalid or duplicate recovery code %q", code)
+		}
+		seen[code] = true
+		if typed := strings.ToUpper(strings.ReplaceAll(code, "-", " ")); NormalizeRecoveryCode(typed) != code {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", typed, NormalizeRecoveryCode(typed), code)
+		}
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "abcde-fghij", want: "abcde-fghij"},
+		{in: "ABCDE-FGHIJ", want: "abcde-fghij"},
+		{in: " abcdefghij ", want: "abcde-fghij"},
+		{in: "abcde fghij", want: "abcde-fghij"},
+		{in: "abcde", want: "abcde"},
+	}
+	for _, test := range tests {
+		if got := NormalizeRecoveryCode(test.in); got != test.want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package buildpack
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+type Stack string
+
+const (
+	StackGo     Stack = "go"
+	StackNode   Stack = "node"
+	StackPython Stack = "python"
+	StackStatic Stack = "static"
+)
+
+// GeneratedDockerfile is the name of a Dockerfile generated or provided by the
+// project in the build context. It replaces a file of the repository with the
+// same name, and is never copied into the image.
+const GeneratedDockerfile = "Dockerfile.axolotl"
+
+var ErrUnknownStack = errors.New("could not detect the stack of the repository")
+
+type Result struct {
+	Stack      Stack
+	Dockerfile string
+	Port       string
+}
+
+type detector func(dir string) (*Result, error)
+
+// Detectors are tried in order, the first match wins. Static sites come last
+// since most stacks also ship an index.html somewhere.
+var detectors = []detector{
+	detectGo,
+	detectNode,
+	detectPython,
+	detectStatic,
+}
+
+// Detect recognizes the stack of the source tree in dir and generates a
+// multi-stage Dockerfile for it.
+func Detect(dir string) (*Result, error) {
+	for _, detect := range detectors {
+		result, err := detect(dir)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			return result, nil
+		}
+	}
+	return nil, ErrUnknownStack
+}
+
+func fileExists(dir string, name string) bool {
+	info, err := os.Stat(filepath.Join(dir, name))
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,48 @@
+package buildpack
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    Stack
+		wantErr error
+	}{
+		{name: "node server", files: map[string]string{"package.json": `{"scripts": {"start": "node index.js"}}`}, want: StackNode},
+		{name: "node front-end", files: map[string]string{"package.json": `{"scripts": {"build": "vite build"}}`}, want: StackNode},
+		{name: "npm tooling of a static site", files: map[string]string{"package.json": `{"scripts": {"lint": "eslint ."}}`, "index.html": "<html>"}, want: StackStatic},
+		{name: "npm tooling of a python app", files: map[string]string{"package.json": `{}`, "requirements.txt": "flask"}, want: StackPython},
+		{name: "npm tooling only", files: map[string]string{"package.json": `{}`}, wantErr: ErrUnknownStack},
+		{name: "empty", files: map[string]string{}, wantErr: ErrUnknownStack},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range test.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := Detect(dir)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("Detect() = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Detect() = %v", err)
+			}
+			if result.Stack != test.want {
+				t.Errorf("Detect() stack = %s, want %s", result.Stack, test.want)
+			}
+		})
+	}
+}
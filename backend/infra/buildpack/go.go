@@ -0,0 +1,86 @@
+package buildpack
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const goDockerfile = `FROM golang:%s-alpine AS builder
+WORKDIR /src
+COPY go.mod go.sum* ./
+RUN go mod download
+COPY . .
+RUN CGO_ENABLED=0 go build -o /out/app %s
+
+FROM alpine:3.21
+RUN apk add --no-cache ca-certificates
+WORKDIR /app
+COPY --from=builder /out/app ./app
+EXPOSE %s
+CMD ["./app"]
+`
+
+func detectGo(dir string) (*Result, error) {
+	if !fileExists(dir, "go.mod") {
+		return nil, nil
+	}
+
+	version, err := goVersion(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+
+	port := "8080"
+	return &Result{
+		Stack:      StackGo,
+		Dockerfile: fmt.Sprintf(goDockerfile, version, goMainPackage(dir), port),
+		Port:       port,
+	}, nil
+}
+
+// goVersion reads the minor Go version (e.g. 1.24) from the go directive of
+// go.mod, so that the builder image matches the module.
+func goVersion(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "go" {
+			parts := strings.SplitN(fields[1], ".", 3)
+			if len(parts) >= 2 {
+				return parts[0] + "." + parts[1], nil
+			}
+			return fields[1], nil
+		}
+	}
+	return "1", scanner.Err()
+}
+
+// goMainPackage returns the package to build: the module root when it holds
+// main.go, otherwise the first command found under cmd/.
+func goMainPackage(dir string) string {
+	if fileExists(dir, "main.go") {
+		return "."
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "cmd"))
+	if err != nil {
+		return "."
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && fileExists(filepath.Join(dir, "cmd", entry.Name()), "main.go") {
+			return "./cmd/" + entry.Name()
+		}
+	}
+	if fileExists(filepath.Join(dir, "cmd"), "main.go") {
+		return "./cmd"
+	}
+	return "."
+}
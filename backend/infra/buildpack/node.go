@@ -0,0 +1,111 @@
+package buildpack
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const nodeServerDockerfile = `FROM node:22-alpine AS builder
+WORKDIR /app
+%s
+COPY . .
+%s
+
+FROM node:22-alpine
+ENV NODE_ENV=production
+WORKDIR /app
+COPY --from=builder /app ./
+EXPOSE %s
+CMD ["npm", "run", "start"]
+`
+
+const nodeStaticDockerfile = `FROM node:22-alpine AS builder
+WORKDIR /app
+%s
+COPY . .
+RUN npm run build
+
+FROM nginx:alpine
+COPY --from=builder /app/%s /usr/share/nginx/html
+EXPOSE 80
+`
+
+type packageJSON struct {
+	Scripts map[string]string `json:"scripts"`
+}
+
+func detectNode(dir string) (*Result, error) {
+	if !fileExists(dir, "package.json") {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil, err
+	}
+	var pkg packageJSON
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return nil, fmt.Errorf("invalid package.json: %w", err)
+	}
+
+	install := nodeInstall(dir)
+	_, hasBuild := pkg.Scripts["build"]
+	_, hasStart := pkg.Scripts["start"]
+
+	switch {
+	case hasStart:
+		build := ""
+		if hasBuild {
+			build = "RUN npm run build"
+		}
+		port := "3000"
+		return &Result{
+			Stack:      StackNode,
+			Dockerfile: fmt.Sprintf(nodeServerDockerfile, install, build, port),
+			Port:       port,
+		}, nil
+	case hasBuild:
+		// No start script: assume a bundled front-end (Vite, CRA, ...) and
+		// serve its output with nginx.
+		output := "dist"
+		if pkgUses(pkg, "react-scripts") {
+			output = "build"
+		}
+		return &Result{
+			Stack:      StackNode,
+			Dockerfile: fmt.Sprintf(nodeStaticDockerfile, install, output),
+			Port:       "80",
+		}, nil
+	default:
+		// Nothing to run: let the next detectors try, e.g. a static site
+		// using npm for tooling only.
+		return nil, nil
+	}
+}
+
+// nodeInstall returns the dependency installation steps for the package
+// manager whose lock file is present.
+func nodeInstall(dir string) string {
+	switch {
+	case fileExists(dir, "pnpm-lock.yaml"):
+		return "COPY package.json pnpm-lock.yaml ./\nRUN corepack enable && pnpm install --frozen-lockfile"
+	case fileExists(dir, "yarn.lock"):
+		return "COPY package.json yarn.lock ./\nRUN corepack enable && yarn install --frozen-lockfile"
+	case fileExists(dir, "package-lock.json"):
+		return "COPY package.json package-lock.json ./\nRUN npm ci"
+	default:
+		return "COPY package.json ./\nRUN npm install"
+	}
+}
+
+func pkgUses(pkg packageJSON, command string) bool {
+	for _, script := range pkg.Scripts {
+		if strings.Contains(script, command) {
+			return true
+		}
+	}
+	return false
+}
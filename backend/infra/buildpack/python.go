@@ -0,0 +1,78 @@
+package buildpack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const pythonDockerfile = `FROM python:3.12-slim AS builder
+WORKDIR /app
+RUN python -m venv /venv
+ENV PATH="/venv/bin:$PATH"
+%s
+
+FROM python:3.12-slim
+WORKDIR /app
+COPY --from=builder /venv /venv
+ENV PATH="/venv/bin:$PATH" PYTHONUNBUFFERED=1
+COPY . .
+EXPOSE %s
+CMD %s
+`
+
+func detectPython(dir string) (*Result, error) {
+	var install string
+	var deps []byte
+	switch {
+	case fileExists(dir, "requirements.txt"):
+		install = "COPY requirements.txt ./\nRUN pip install --no-cache-dir -r requirements.txt"
+		content, err := os.ReadFile(filepath.Join(dir, "requirements.txt"))
+		if err != nil {
+			return nil, err
+		}
+		deps = content
+	case fileExists(dir, "pyproject.toml"):
+		install = "COPY . .\nRUN pip install --no-cache-dir ."
+		content, err := os.ReadFile(filepath.Join(dir, "pyproject.toml"))
+		if err != nil {
+			return nil, err
+		}
+		deps = content
+	default:
+		return nil, nil
+	}
+
+	port := "8000"
+	return &Result{
+		Stack:      StackPython,
+		Dockerfile: fmt.Sprintf(pythonDockerfile, install, port, pythonCommand(dir, strings.ToLower(string(deps)), port)),
+		Port:       port,
+	}, nil
+}
+
+// pythonCommand guesses how the application is started from its entrypoint
+// file and its dependencies.
+func pythonCommand(dir string, deps string, port string) string {
+	if fileExists(dir, "manage.py") {
+		return fmt.Sprintf(`["python", "manage.py", "runserver", "0.0.0.0:%s"]`, port)
+	}
+
+	entrypoint := "main"
+	for _, name := range []string{"main", "app", "server"} {
+		if fileExists(dir, name+".py") {
+			entrypoint = name
+			break
+		}
+	}
+
+	switch {
+	case strings.Contains(deps, "uvicorn") || strings.Contains(deps, "fastapi"):
+		return fmt.Sprintf(`["uvicorn", "%s:app", "--host", "0.0.0.0", "--port", "%s"]`, entrypoint, port)
+	case strings.Contains(deps, "gunicorn"):
+		return fmt.Sprintf(`["gunicorn", "--bind", "0.0.0.0:%s", "%s:app"]`, port, entrypoint)
+	default:
+		return fmt.Sprintf(`["python", "%s.py"]`, entrypoint)
+	}
+}
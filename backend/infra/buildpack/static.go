@@ -0,0 +1,17 @@
+package buildpack
+
+const staticDockerfile = `FROM nginx:alpine
+COPY . /usr/share/nginx/html
+EXPOSE 80
+`
+
+func detectStatic(dir string) (*Result, error) {
+	if !fileExists(dir, "index.html") {
+		return nil, nil
+	}
+	return &Result{
+		Stack:      StackStatic,
+		Dockerfile: staticDockerfile,
+		Port:       "80",
+	}, nil
+}
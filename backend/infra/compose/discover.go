@@ -0,0 +1,131 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultFileNames are the compose file names looked up in a source tree, in
+// the order of precedence of the Compose specification.
+var DefaultFileNames = []string{
+	"compose.yaml",
+	"compose.yml",
+	"docker-compose.yaml",
+	"docker-compose.yml",
+}
+
+// Discover returns the compose files of the source tree in dir, relative to
+// dir: the custom file when path is set, otherwise the first default file
+// found, followed by its override file and by the extra files. It returns no
+// files when the tree has no compose file.
+func Discover(dir string, path string, extra []string) ([]string, error) {
+	var files []string
+
+	if path != "" {
+		if _, err := resolve(dir, path); err != nil {
+			return nil, err
+		}
+		files = append(files, path)
+	} else {
+		for _, name := range DefaultFileNames {
+			if isFile(filepath.Join(dir, name)) {
+				files = append(files, name)
+				break
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		if len(extra) > 0 {
+			return nil, fmt.Errorf("extra compose files given but no compose file found")
+		}
+		return nil, nil
+	}
+
+	if override := overrideFile(dir, files[0]); override != "" {
+		files = append(files, override)
+	}
+
+	for _, file := range extra {
+		if _, err := resolve(dir, file); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// overrideFile returns the override file matching a compose file, e.g.
+// compose.override.yml for compose.yml, if it exists.
+func overrideFile(dir string, file string) string {
+	base := strings.TrimSuffix(file, filepath.Ext(file))
+	for _, candidate := range []string{base + ".override.yaml", base + ".override.yml"} {
+		if isFile(filepath.Join(dir, candidate)) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// resolve joins a path of the compose files to dir, refusing paths escaping
+// dir, and checks that the file exists.
+func resolve(dir string, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("compose file %s must be relative to the sources", path)
+	}
+	full := filepath.Join(dir, path)
+	if !within(dir, full) {
+		return "", fmt.Errorf("compose file %s is outside of the sources", path)
+	}
+	if !isFile(full) {
+		return "", fmt.Errorf("compose file %s not found", path)
+	}
+	if !withinResolved(dir, full) {
+		return "", fmt.Errorf("compose file %s links outside of the sources", path)
+	}
+	return full, nil
+}
+
+// ResolveContext joins the build context of a service, relative to the
+// sources as normalized by the loader, to dir. Like the compose files, the
+// context must stay inside dir, remote contexts are refused.
+func ResolveContext(dir string, context string) (string, error) {
+	if filepath.IsAbs(context) || strings.Contains(context, "://") {
+		return "", fmt.Errorf("build context %s must be a directory of the sources", context)
+	}
+	full := filepath.Join(dir, context)
+	if !within(dir, full) {
+		return "", fmt.Errorf("build context %s is outside of the sources", context)
+	}
+	if info, err := os.Stat(full); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("build context %s not found", context)
+	}
+	if !withinResolved(dir, full) {
+		return "", fmt.Errorf("build context %s links outside of the sources", context)
+	}
+	return full, nil
+}
+
+// within reports whether path, cleaned, is dir or lies under it.
+func within(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// withinResolved reports whether the existing path still lies under dir once
+// their symbolic links are followed.
+func withinResolved(dir string, path string) bool {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	return err == nil && within(realDir, realPath)
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
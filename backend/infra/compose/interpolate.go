@@ -0,0 +1,186 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+)
+
+// interpolator substitutes ${VAR} style variables in the values of a compose
+// document, with the syntax of the Compose specification:
+//
+//	$VAR ${VAR}          value of VAR, empty when unset
+//	${VAR:-default}      default when VAR is unset or empty
+//	${VAR-default}       default when VAR is unset
+//	${VAR:?message}      error when VAR is unset or empty
+//	${VAR?message}       error when VAR is unset
+//	${VAR:+replacement}  replacement when VAR is set and not empty
+//	${VAR+replacement}   replacement when VAR is set
+//	$$                   a literal $
+//
+// Dotted references such as ${services.db.host} are left untouched, they are
+// resolved when the containers start.
+type interpolator struct {
+	lookup func(name string) (string, bool)
+	// unset collects the variables used without value nor default.
+	unset map[string]bool
+}
+
+// walk interpolates every string value of v. Mapping keys are left as is.
+func (in *interpolator) walk(v any) (any, error) {
+	switch value := v.(type) {
+	case string:
+		return in.interpolate(value)
+	case map[string]any:
+		for k, item := range value {
+			out, err := in.walk(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			value[k] = out
+		}
+		return value, nil
+	case []any:
+		for i, item := range value {
+			out, err := in.walk(item)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = out
+		}
+		return value, nil
+	default:
+		return v, nil
+	}
+}
+
+func (in *interpolator) interpolate(s string) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i == len(s)-1 {
+			out.WriteByte(s[i])
+			continue
+		}
+
+		next := s[i+1]
+		switch {
+		case next == '$':
+			out.WriteByte('$')
+			i++
+		case next == '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("invalid interpolation format for %q: missing }", s)
+			}
+			value, err := in.expression(s[i+2 : end])
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(value)
+			i = end
+		case isNameStart(next):
+			end := i + 1
+			for end < len(s) && isNameChar(s[end]) {
+				end++
+			}
+			out.WriteString(in.value(s[i+1 : end]))
+			i = end - 1
+		default:
+			out.WriteByte('$')
+		}
+	}
+	return out.String(), nil
+}
+
+// expression evaluates the content of ${...}.
+func (in *interpolator) expression(expr string) (string, error) {
+	end := 0
+	for end < len(expr) && isNameChar(expr[end]) {
+		end++
+	}
+	name, rest := expr[:end], expr[end:]
+	if name == "" || !isNameStart(name[0]) {
+		return "", fmt.Errorf("invalid interpolation format for ${%s}", expr)
+	}
+	if rest == "" {
+		return in.value(name), nil
+	}
+	if rest[0] == '.' {
+		return "${" + expr + "}", nil
+	}
+
+	value, set := in.lookup(name)
+	checkEmpty := strings.HasPrefix(rest, ":")
+	if checkEmpty {
+		rest = rest[1:]
+	}
+	if rest == "" {
+		return "", fmt.Errorf("invalid interpolation format for ${%s}", expr)
+	}
+	present := set && (!checkEmpty || value != "")
+	op, arg := rest[0], rest[1:]
+
+	switch op {
+	case '-':
+		if present {
+			return value, nil
+		}
+		return in.interpolate(arg)
+	case '?':
+		if present {
+			return value, nil
+		}
+		message, err := in.interpolate(arg)
+		if err != nil {
+			return "", err
+		}
+		if message == "" {
+			message = "not set"
+		}
+		return "", fmt.Errorf("required variable %s is missing a value: %s", name, message)
+	case '+':
+		if present {
+			return in.interpolate(arg)
+		}
+		return "", nil
+	default:
+		return "", fmt.Errorf("invalid interpolation format for ${%s}", expr)
+	}
+}
+
+func (in *interpolator) value(name string) string {
+	value, ok := in.lookup(name)
+	if !ok && in.unset != nil {
+		in.unset[name] = true
+	}
+	return value
+}
+
+// closingBrace returns the index of the } closing the expression starting at
+// start, taking nested ${...} defaults into account.
+func closingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,164 @@
+package compose
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	variables := map[string]string{"NAME": "app", "EMPTY": "", "PORT": "8080"}
+	tests := []struct {
+		in   string
+		want string
+		// wantErr is part of the expected error
+		wantErr string
+		// wantUnset are the variables reported as unset
+		wantUnset []string
+	}{
+		{in: "no variable", want: "no variable"},
+		{in: "$NAME-$PORT", want: "app-8080"},
+		{in: "${NAME}_db", want: "app_db"},
+		{in: "$$NAME costs $$5", want: "$NAME costs $5"},
+		{in: "price: 5$", want: "price: 5$"},
+		{in: "$1", want: "$1"},
+		{in: "${MISSING}", want: "", wantUnset: []string{"MISSING"}},
+		{in: "$MISSING/x", want: "/x", wantUnset: []string{"MISSING"}},
+		{in: "${MISSING:-default}", want: "default"},
+		{in: "${EMPTY:-default}", want: "default"},
+		{in: "${EMPTY-default}", want: ""},
+		{in: "${MISSING-default}", want: "default"},
+		{in: "${MISSING:-${NAME}-fallback}", want: "app-fallback"},
+		{in: "${NAME:?set NAME}", want: "app"},
+		{in: "${MISSING:?set MISSING}", wantErr: "required variable MISSING is missing a value: set MISSING"},
+		{in: "${EMPTY:?}", wantErr: "required variable EMPTY is missing a value: not set"},
+		{in: "${EMPTY?}", want: ""},
+		{in: "${MISSING?}", wantErr: "required variable MISSING"},
+		{in: "${NAME:+set}", want: "set"},
+		{in: "${EMPTY:+set}", want: ""},
+		{in: "${EMPTY+set}", want: "set"},
+		{in: "${MISSING+set}", want: ""},
+		{in: "${services.db.host}:5432", want: "${services.db.host}:5432"},
+		{in: "${NAME", wantErr: "missing }"},
+		{in: "${}", wantErr: "invalid interpolation format"},
+		{in: "${1A}", wantErr: "invalid interpolation format"},
+		{in: "${NAME:}", wantErr: "invalid interpolation format"},
+		{in: "${NAME!x}", wantErr: "invalid interpolation format"},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			in := &interpolator{
+				lookup: func(name string) (string, bool) {
+					value, ok := variables[name]
+					return value, ok
+				},
+				unset: map[string]bool{},
+			}
+
+			got, err := in.interpolate(test.in)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("interpolate(%q) = %q, %v, want an error containing %q", test.in, got, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("interpolate(%q) = %v", test.in, err)
+			}
+			if got != test.want {
+				t.Errorf("interpolate(%q) = %q, want %q", test.in, got, test.want)
+			}
+			var unset []string
+			for name := range in.unset {
+				unset = append(unset, name)
+			}
+			if !slices.Equal(unset, test.wantUnset) {
+				t.Errorf("interpolate(%q) unset = %v, want %v", test.in, unset, test.wantUnset)
+			}
+		})
+	}
+}
+
+func TestLoaderVariables(t *testing.T) {
+	tests := []struct {
+		name      string
+		compose   string
+		uploaded  map[string]string
+		variables map[string]string
+		want      map[string]model.ComposeService
+		// wantErr is part of the expected error
+		wantErr      string
+		wantWarnings []string
+	}{
+		{
+			name:    "project variables",
+			compose: `services: {web: {image: "web:${TAG}", ports: ["${PORT:-80}:80"]}}`,
+			variables: map[string]string{
+				"TAG": "1.2",
+			},
+			want: map[string]model.ComposeService{"web": {Image: "web:1.2", Ports: []string{"80:80"}}},
+		},
+		{
+			name:      ".env file, overridden by project variables",
+			compose:   `services: {web: {image: "web:${TAG}", environment: {MODE: "${MODE}"}}}`,
+			uploaded:  map[string]string{".env": "TAG=1.0\nMODE=dev\n"},
+			variables: map[string]string{"MODE": "prod"},
+			want:      map[string]model.ComposeService{"web": {Image: "web:1.0", Env: map[string]string{"MODE": "prod"}}},
+		},
+		{
+			name:         "unset variable",
+			compose:      `services: {web: {image: "web:${TAG}"}}`,
+			want:         map[string]model.ComposeService{"web": {Image: "web:"}},
+			wantWarnings: []string{"variable TAG is not set, defaulting to a blank string"},
+		},
+		{
+			name:    "required variable",
+			compose: `services: {web: {image: "web:${TAG:?pick a release}"}}`,
+			wantErr: "required variable TAG is missing a value: pick a release",
+		},
+		{
+			name:     "env_file",
+			compose:  `services: {web: {image: web, env_file: [web.env, {path: local.env, required: false}], environment: {B: own}}}`,
+			uploaded: map[string]string{"web.env": "A=file\nB=file\n"},
+			want:     map[string]model.ComposeService{"web": {Image: "web", Env: map[string]string{"A": "file", "B": "own"}}},
+		},
+		{
+			name:     "env_file relative to the declaring file",
+			compose:  `include: [api/compose.yaml]`,
+			uploaded: map[string]string{"api/compose.yaml": `services: {api: {image: api, env_file: api.env}}`, "api/api.env": "A=1"},
+			want:     map[string]model.ComposeService{"api": {Image: "api", Env: map[string]string{"A": "1"}}},
+		},
+		{
+			name:    "missing env_file",
+			compose: `services: {web: {image: web, env_file: web.env}}`,
+			wantErr: "env_file web.env not found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			loader := &Loader{Files: map[string][]byte{}, Variables: test.variables}
+			for name, content := range test.uploaded {
+				loader.Files[name] = []byte(content)
+			}
+
+			compose, err := loader.Load(File{Path: "compose.yaml", Content: []byte(test.compose)})
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("Load() = %v, want an error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() = %v", err)
+			}
+			if !reflect.DeepEqual(compose.Services, test.want) {
+				t.Errorf("Load() services = %+v, want %+v", compose.Services, test.want)
+			}
+			if !slices.Equal(loader.Warnings, test.wantWarnings) {
+				t.Errorf("Load() warnings = %v, want %v", loader.Warnings, test.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,415 @@
+package compose
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Loader turns compose files into a model.ComposeFile. It merges multiple
+// files like `docker compose -f a.yaml -f b.yaml` does, follows `include` and
+// `extends` and only keeps the services of the active profiles. YAML anchors
+// and merge keys are resolved by the YAML decoder. Variables are interpolated
+// and `env_file` entries are read into the environment of the services.
+type Loader struct {
+	// Dir is the directory the compose files are read from. It is empty for
+	// content that does not come from a source tree (e.g. an imported file).
+	Dir string
+	// Files are in-memory files (e.g. uploaded along an import) referenced by
+	// `include`, `extends`, `env_file` or the .env file. They are looked up
+	// before Dir.
+	Files map[string][]byte
+	// Profiles are the active profiles.
+	Profiles []string
+	// Variables are used for interpolation and take precedence over the .env
+	// file.
+	Variables map[string]string
+	// Warnings collects non fatal problems such as unset variables.
+	Warnings []string
+
+	interpolator *interpolator
+}
+
+// File is the content of a compose file. Path is relative to Loader.Dir.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// LoadFiles reads the files (relative to Dir) and loads them.
+func (l *Loader) LoadFiles(paths []string) (model.ComposeFile, error) {
+	files := make([]File, 0, len(paths))
+	for _, path := range paths {
+		content, err := l.read(path)
+		if err != nil {
+			return model.ComposeFile{}, err
+		}
+		files = append(files, File{Path: path, Content: content})
+	}
+	return l.Load(files...)
+}
+
+// Load merges the files in order, later files overriding earlier ones.
+func (l *Loader) Load(files ...File) (model.ComposeFile, error) {
+	if err := l.initInterpolation(); err != nil {
+		return model.ComposeFile{}, err
+	}
+
+	merged := map[string]any{}
+	for _, file := range files {
+		doc, err := l.parse(file, nil)
+		if err != nil {
+			return model.ComposeFile{}, err
+		}
+		merged = mergeMaps(merged, doc)
+	}
+
+	services := asMap(merged["services"])
+	resolved := make(map[string]any, len(services))
+	for name := range services {
+		service, err := l.resolveExtends(services, name, nil)
+		if err != nil {
+			return model.ComposeFile{}, err
+		}
+		if l.profileEnabled(service) {
+			resolved[name] = service
+		}
+	}
+	merged["services"] = resolved
+
+	unset := slices.Sorted(maps.Keys(l.interpolator.unset))
+	for _, name := range unset {
+		l.Warnings = append(l.Warnings, fmt.Sprintf("variable %s is not set, defaulting to a blank string", name))
+	}
+
+	return decode(merged)
+}
+
+// initInterpolation sets up the variable lookup: the .env file next to the
+// compose files, overridden by Variables.
+func (l *Loader) initInterpolation() error {
+	env := map[string]string{}
+	content, found, err := l.readOptional(".env")
+	if err != nil {
+		return err
+	}
+	if found {
+		if env, err = godotenv.UnmarshalBytes(content); err != nil {
+			return fmt.Errorf("invalid .env file: %w", err)
+		}
+	}
+	maps.Copy(env, l.Variables)
+
+	l.interpolator = &interpolator{
+		lookup: func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		},
+		unset: map[string]bool{},
+	}
+	return nil
+}
+
+// parse decodes a compose file, loads its includes and normalizes its
+// services. stack holds the files being loaded to detect include cycles.
+func (l *Loader) parse(file File, stack []string) (map[string]any, error) {
+	if slices.Contains(stack, file.Path) {
+		return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), file.Path)
+	}
+	stack = append(stack, file.Path)
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(file.Content, &doc); err != nil {
+		return nil, fmt.Errorf("invalid compose file %s: %w", displayName(file.Path), err)
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+	dropExtensions(doc)
+	if _, err := l.interpolator.walk(doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", displayName(file.Path), err)
+	}
+
+	fileDir := filepath.Dir(file.Path)
+	services := asMap(doc["services"])
+	for name, raw := range services {
+		service := asMap(raw)
+		if service == nil {
+			service = map[string]any{}
+		}
+		if err := normalizeService(service, fileDir); err != nil {
+			return nil, fmt.Errorf("service %s of %s: %w", name, displayName(file.Path), err)
+		}
+		if err := l.applyEnvFiles(service, fileDir); err != nil {
+			return nil, fmt.Errorf("service %s of %s: %w", name, displayName(file.Path), err)
+		}
+		if extends := asMap(service["extends"]); extends != nil {
+			if f, ok := extends["file"].(string); ok && f != "" {
+				extends["file"] = filepath.Join(fileDir, f)
+			}
+		}
+		services[name] = service
+	}
+
+	includes, err := includePaths(doc["include"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid include in %s: %w", displayName(file.Path), err)
+	}
+	delete(doc, "include")
+
+	base := map[string]any{}
+	for _, include := range includes {
+		path := filepath.Join(fileDir, include)
+		content, err := l.read(path)
+		if err != nil {
+			return nil, err
+		}
+		included, err := l.parse(File{Path: path, Content: content}, stack)
+		if err != nil {
+			return nil, err
+		}
+		base = mergeMaps(base, included)
+	}
+	return mergeMaps(base, doc), nil
+}
+
+// resolveExtends returns the service name of services with its `extends`
+// chain applied. stack holds the services being resolved to detect cycles.
+func (l *Loader) resolveExtends(services map[string]any, name string, stack []string) (map[string]any, error) {
+	service := asMap(services[name])
+	if service == nil {
+		return nil, fmt.Errorf("service %s not found", name)
+	}
+
+	extends := service["extends"]
+	if extends == nil {
+		return service, nil
+	}
+
+	key := name
+	var baseName, baseFile string
+	switch e := extends.(type) {
+	case string:
+		baseName = e
+	case map[string]any:
+		baseName, _ = e["service"].(string)
+		baseFile, _ = e["file"].(string)
+	}
+	if baseName == "" {
+		return nil, fmt.Errorf("service %s: extends needs a service", name)
+	}
+	if baseFile != "" {
+		key = baseFile + ":" + baseName
+	}
+	if slices.Contains(stack, key) {
+		return nil, fmt.Errorf("extends cycle: %s -> %s", strings.Join(stack, " -> "), key)
+	}
+	stack = append(stack, key)
+
+	baseServices := services
+	if baseFile != "" {
+		content, err := l.read(baseFile)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := l.parse(File{Path: baseFile, Content: content}, nil)
+		if err != nil {
+			return nil, err
+		}
+		baseServices = asMap(doc["services"])
+	}
+
+	base, err := l.resolveExtends(baseServices, baseName, stack)
+	if err != nil {
+		return nil, fmt.Errorf("service %s extends %s: %w", name, baseName, err)
+	}
+
+	own := make(map[string]any, len(service))
+	for k, v := range service {
+		if k != "extends" {
+			own[k] = v
+		}
+	}
+	return mergeMaps(base, own), nil
+}
+
+func (l *Loader) profileEnabled(service map[string]any) bool {
+	profiles := asList(service["profiles"])
+	if len(profiles) == 0 {
+		return true
+	}
+	for _, profile := range profiles {
+		if p, ok := profile.(string); ok && slices.Contains(l.Profiles, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyEnvFiles reads the `env_file` entries of a service into its
+// environment. Values of `environment` take precedence.
+func (l *Loader) applyEnvFiles(service map[string]any, fileDir string) error {
+	raw, ok := service["env_file"]
+	if !ok {
+		return nil
+	}
+	delete(service, "env_file")
+
+	entries := asList(raw)
+	if path, ok := raw.(string); ok {
+		entries = []any{path}
+	}
+
+	env := map[string]any{}
+	for _, entry := range entries {
+		path, required := "", true
+		switch e := entry.(type) {
+		case string:
+			path = e
+		case map[string]any:
+			path, _ = e["path"].(string)
+			if r, ok := e["required"].(bool); ok {
+				required = r
+			}
+		}
+		if path == "" {
+			return fmt.Errorf("env_file entry without path")
+		}
+
+		content, found, err := l.readOptional(filepath.Join(fileDir, path))
+		if err != nil {
+			return err
+		}
+		if !found {
+			if required {
+				return fmt.Errorf("env_file %s not found", path)
+			}
+			continue
+		}
+		values, err := godotenv.UnmarshalBytes(content)
+		if err != nil {
+			return fmt.Errorf("invalid env_file %s: %w", path, err)
+		}
+		for k, v := range values {
+			env[k] = v
+		}
+	}
+
+	service["environment"] = mergeMaps(env, asMap(service["environment"]))
+	return nil
+}
+
+func (l *Loader) read(path string) ([]byte, error) {
+	content, found, err := l.readOptional(path)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if l.Dir == "" {
+			return nil, fmt.Errorf("file %s not found, it must be uploaded along the compose file", path)
+		}
+		return nil, fmt.Errorf("compose file %s not found", path)
+	}
+	return content, nil
+}
+
+// readOptional reads a file from Files, then from Dir. found is false when
+// the file exists in neither.
+func (l *Loader) readOptional(path string) ([]byte, bool, error) {
+	if content, ok := l.Files[filepath.Clean(path)]; ok {
+		return content, true, nil
+	}
+	if l.Dir == "" {
+		return nil, false, nil
+	}
+	full, err := resolve(l.Dir, path)
+	if err != nil {
+		if isFile(filepath.Join(l.Dir, path)) {
+			return nil, false, err
+		}
+		return nil, false, nil
+	}
+	content, err := os.ReadFile(full)
+	return content, err == nil, err
+}
+
+// includePaths reads the short (list of paths) and long ({path: ...}) syntax
+// of the top-level `include`.
+func includePaths(raw any) ([]string, error) {
+	var paths []string
+	for _, entry := range asList(raw) {
+		switch e := entry.(type) {
+		case string:
+			paths = append(paths, e)
+		case map[string]any:
+			switch p := e["path"].(type) {
+			case string:
+				paths = append(paths, p)
+			case []any:
+				for _, item := range p {
+					if s, ok := item.(string); ok {
+						paths = append(paths, s)
+					}
+				}
+			default:
+				return nil, fmt.Errorf("include entry without path")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported include entry %v", entry)
+		}
+	}
+	return paths, nil
+}
+
+// decode converts the merged document to the model through YAML, now that
+// every field has the shape the model expects.
+func decode(doc map[string]any) (model.ComposeFile, error) {
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return model.ComposeFile{}, err
+	}
+	var compose model.ComposeFile
+	if err := yaml.Unmarshal(out, &compose); err != nil {
+		return model.ComposeFile{}, err
+	}
+	return compose, nil
+}
+
+func displayName(path string) string {
+	if path == "" {
+		return "compose file"
+	}
+	return path
+}
+
+// dropExtensions removes the x-* extension fields, whose content has already
+// been used through anchors.
+func dropExtensions(doc map[string]any) {
+	for k := range doc {
+		if strings.HasPrefix(k, "x-") {
+			delete(doc, k)
+		}
+	}
+	for _, service := range asMap(doc["services"]) {
+		if m := asMap(service); m != nil {
+			dropExtensions(m)
+		}
+	}
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func asList(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
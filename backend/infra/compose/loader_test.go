@@ -0,0 +1,298 @@
+package compose
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoaderLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []File
+		uploaded map[string]string
+		profiles []string
+		want     map[string]model.ComposeService
+		// wantErr is part of the expected error
+		wantErr string
+	}{
+		{
+			name: "override merge",
+			files: []File{
+				{Path: "compose.yaml", Content: []byte(`
+services:
+  web:
+    image: web:1
+    ports: ["80:80"]
+    environment: {A: "1", B: "1"}
+    volumes: ["data:/data", "logs:/logs"]
+`)},
+				{Path: "compose.override.yaml", Content: []byte(`
+services:
+  web:
+    image: web:2
+    ports: ["80:80", "443:443"]
+    environment: [B=2, C=2]
+    volumes: ["other:/data"]
+  db:
+    image: postgres
+`)},
+			},
+			want: map[string]model.ComposeService{
+				"web": {
+					Image:   "web:2",
+					Ports:   []string{"80:80", "443:443"},
+					Env:     map[string]string{"A": "1", "B": "2", "C": "2"},
+					Volumes: []string{"other:/data", "logs:/logs"},
+				},
+				"db": {Image: "postgres"},
+			},
+		},
+		{
+			name: "anchors",
+			files: []File{{Path: "compose.yaml", Content: []byte(`
+x-common: &common
+  image: app
+  environment: {LOG: debug}
+services:
+  api:
+    <<: *common
+    ports: ["8080"]
+  worker: *common
+`)}},
+			want: map[string]model.ComposeService{
+				"api":    {Image: "app", Env: map[string]string{"LOG": "debug"}, Ports: []string{"8080"}},
+				"worker": {Image: "app", Env: map[string]string{"LOG": "debug"}},
+			},
+		},
+		{
+			name: "include",
+			files: []File{{Path: "compose.yaml", Content: []byte(`
+include:
+  - db/compose.yaml
+services:
+  web:
+    build: .
+  db:
+    environment: {POSTGRES_DB: app}
+`)}},
+			uploaded: map[string]string{"db/compose.yaml": `
+services:
+  db:
+    build: ./image
+    environment: {POSTGRES_USER: app}
+`},
+			want: map[string]model.ComposeService{
+				"web": {Build: &model.ComposeBuild{Context: "."}},
+				"db":  {Build: &model.ComposeBuild{Context: "db/image"}, Env: map[string]string{"POSTGRES_USER": "app", "POSTGRES_DB": "app"}},
+			},
+		},
+		{
+			name:     "include cycle",
+			files:    []File{{Path: "compose.yaml", Content: []byte("include: [a.yaml]")}},
+			uploaded: map[string]string{"a.yaml": "include: [b.yaml]", "b.yaml": "include: [a.yaml]"},
+			wantErr:  "include cycle",
+		},
+		{
+			name:    "missing include",
+			files:   []File{{Path: "compose.yaml", Content: []byte("include: [missing.yaml]")}},
+			wantErr: "missing.yaml not found",
+		},
+		{
+			name: "extends",
+			files: []File{{Path: "compose.yaml", Content: []byte(`
+services:
+  base:
+    image: app
+    environment: {A: "1"}
+  api:
+    extends: base
+    environment: {B: "2"}
+  admin:
+    extends:
+      service: api
+    ports: ["9000"]
+`)}},
+			want: map[string]model.ComposeService{
+				"base":  {Image: "app", Env: map[string]string{"A": "1"}},
+				"api":   {Image: "app", Env: map[string]string{"A": "1", "B": "2"}},
+				"admin": {Image: "app", Env: map[string]string{"A": "1", "B": "2"}, Ports: []string{"9000"}},
+			},
+		},
+		{
+			name: "extends from another file",
+			files: []File{{Path: "compose.yaml", Content: []byte(`
+services:
+  api:
+    extends:
+      file: common/services.yaml
+      service: app
+    image: api
+`)}},
+			uploaded: map[string]string{"common/services.yaml": `
+services:
+  app:
+    image: app
+    build: ./app
+`},
+			want: map[string]model.ComposeService{
+				"api": {Image: "api", Build: &model.ComposeBuild{Context: "common/app"}},
+			},
+		},
+		{
+			name: "extends cycle",
+			files: []File{{Path: "compose.yaml", Content: []byte(`
+services:
+  a: {extends: b}
+  b: {extends: a}
+`)}},
+			wantErr: "extends cycle",
+		},
+		{
+			name:    "extends unknown service",
+			files:   []File{{Path: "compose.yaml", Content: []byte("services: {a: {extends: missing}}")}},
+			wantErr: "service missing not found",
+		},
+		{
+			name: "inactive profile",
+			files: []File{{Path: "compose.yaml", Content: []byte(`
+services:
+  web: {image: web}
+  debug: {image: debug, profiles: [debug]}
+`)}},
+			want: map[string]model.ComposeService{"web": {Image: "web"}},
+		},
+		{
+			name: "active profile",
+			files: []File{{Path: "compose.yaml", Content: []byte(`
+services:
+  web: {image: web}
+  debug: {image: debug, profiles: [debug]}
+`)}},
+			profiles: []string{"debug"},
+			want:     map[string]model.ComposeService{"web": {Image: "web"}, "debug": {Image: "debug"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			loader := &Loader{Files: map[string][]byte{}, Profiles: test.profiles}
+			for name, content := range test.uploaded {
+				loader.Files[name] = []byte(content)
+			}
+
+			compose, err := loader.Load(test.files...)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("Load() = %v, want an error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() = %v", err)
+			}
+			if !reflect.DeepEqual(compose.Services, test.want) {
+				t.Errorf("Load() services = %+v, want %+v", compose.Services, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeMaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     map[string]any
+		override map[string]any
+		want     map[string]any
+	}{
+		{
+			name:     "scalars replaced",
+			base:     map[string]any{"image": "a", "restart": "always"},
+			override: map[string]any{"image": "b"},
+			want:     map[string]any{"image": "b", "restart": "always"},
+		},
+		{
+			name:     "mappings merged",
+			base:     map[string]any{"labels": map[string]any{"a": "1", "b": "1"}},
+			override: map[string]any{"labels": map[string]any{"b": "2"}},
+			want:     map[string]any{"labels": map[string]any{"a": "1", "b": "2"}},
+		},
+		{
+			name:     "sequences extended",
+			base:     map[string]any{"ports": []any{"80", "443"}},
+			override: map[string]any{"ports": []any{"443", "8080"}},
+			want:     map[string]any{"ports": []any{"80", "443", "8080"}},
+		},
+		{
+			name:     "command replaced",
+			base:     map[string]any{"command": []any{"npm", "start"}},
+			override: map[string]any{"command": []any{"node", "index.js"}},
+			want:     map[string]any{"command": []any{"node", "index.js"}},
+		},
+		{
+			name:     "volumes remounted",
+			base:     map[string]any{"volumes": []any{"a:/data", "b:/logs"}},
+			override: map[string]any{"volumes": []any{"c:/data:ro"}},
+			want:     map[string]any{"volumes": []any{"c:/data:ro", "b:/logs"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := mergeMaps(test.base, test.override); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("mergeMaps() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		path    string
+		extra   []string
+		want    []string
+		wantErr string
+	}{
+		{name: "none", files: []string{"Dockerfile"}, want: nil},
+		{name: "precedence", files: []string{"docker-compose.yml", "compose.yml"}, want: []string{"compose.yml"}},
+		{name: "override", files: []string{"docker-compose.yaml", "docker-compose.override.yml"}, want: []string{"docker-compose.yaml", "docker-compose.override.yml"}},
+		{name: "custom path", files: []string{"compose.yaml", "deploy/prod.yaml", "deploy/prod.override.yaml"}, path: "deploy/prod.yaml", want: []string{"deploy/prod.yaml", "deploy/prod.override.yaml"}},
+		{name: "extra files", files: []string{"compose.yaml", "compose.override.yaml", "ci.yaml"}, extra: []string{"ci.yaml"}, want: []string{"compose.yaml", "compose.override.yaml", "ci.yaml"}},
+		{name: "extra files without compose file", files: []string{"ci.yaml"}, extra: []string{"ci.yaml"}, wantErr: "no compose file found"},
+		{name: "missing custom path", files: []string{"compose.yaml"}, path: "prod.yaml", wantErr: "not found"},
+		{name: "path outside of the sources", files: []string{"compose.yaml"}, path: "../compose.yaml", wantErr: "outside of the sources"},
+		{name: "absolute path", files: []string{"compose.yaml"}, extra: []string{"/etc/passwd"}, wantErr: "must be relative"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "sources")
+			for _, name := range test.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("services: {}"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			files, err := Discover(dir, test.path, test.extra)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("Discover() = %v, want an error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Discover() = %v", err)
+			}
+			if !slices.Equal(files, test.want) {
+				t.Errorf("Discover() = %v, want %v", files, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+)
+
+// replacedSequences are the sequences an override replaces instead of
+// extending, as they only make sense as a whole.
+var replacedSequences = map[string]bool{
+	"command":    true,
+	"entrypoint": true,
+	"test":       true, // healthcheck
+	"profiles":   true,
+}
+
+// mergeMaps merges override into base following the Compose merge rules:
+// mappings are merged recursively, sequences are extended and scalars are
+// replaced. Neither argument is modified.
+func mergeMaps(base map[string]any, override map[string]any) map[string]any {
+	out := make(map[string]any, len(base)+len(override))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range override {
+		out[k] = mergeValues(k, out[k], v)
+	}
+	return out
+}
+
+func mergeValues(key string, base any, override any) any {
+	switch o := override.(type) {
+	case map[string]any:
+		if b, ok := base.(map[string]any); ok {
+			return mergeMaps(b, o)
+		}
+	case []any:
+		if b, ok := base.([]any); ok && !replacedSequences[key] {
+			return mergeSequences(key, b, o)
+		}
+	}
+	return override
+}
+
+// mergeSequences appends the items of override to base, skipping duplicates.
+// Volumes are unique by mount target, so an override can remount a path.
+func mergeSequences(key string, base []any, override []any) []any {
+	identity := func(v any) string { return fmt.Sprint(v) }
+	if key == "volumes" {
+		identity = volumeTarget
+	}
+
+	out := make([]any, 0, len(base)+len(override))
+	index := make(map[string]int, len(base)+len(override))
+	for _, items := range [][]any{base, override} {
+		for _, item := range items {
+			id := identity(item)
+			if i, ok := index[id]; ok {
+				out[i] = item
+				continue
+			}
+			index[id] = len(out)
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// volumeTarget returns the mount target of a normalized (short syntax) volume.
+func volumeTarget(v any) string {
+	s := fmt.Sprint(v)
+	parts := strings.Split(s, ":")
+	if len(parts) >= 2 {
+		return parts[1]
+	}
+	return s
+}
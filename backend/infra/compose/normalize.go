@@ -0,0 +1,184 @@
+package compose
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// normalizeService rewrites the fields of a service that the Compose
+// specification allows in several shapes into the single shape of
+// model.ComposeService. Relative build contexts are rebased on fileDir, the
+// directory of the file declaring the service.
+func normalizeService(service map[string]any, fileDir string) error {
+	if env, ok := service["environment"]; ok {
+		m, err := toStringMap(env)
+		if err != nil {
+			return fmt.Errorf("environment: %w", err)
+		}
+		service["environment"] = m
+	}
+
+	if labels, ok := service["labels"]; ok {
+		m, err := toStringMap(labels)
+		if err != nil {
+			return fmt.Errorf("labels: %w", err)
+		}
+		service["labels"] = m
+	}
+
+	if ports, ok := service["ports"]; ok {
+		service["ports"] = normalizePorts(asList(ports))
+	}
+
+	if volumes, ok := service["volumes"]; ok {
+		service["volumes"] = normalizeVolumes(asList(volumes))
+	}
+
+	if networks, ok := service["networks"].(map[string]any); ok {
+		names := make([]any, 0, len(networks))
+		for name := range networks {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool { return names[i].(string) < names[j].(string) })
+		service["networks"] = names
+	}
+
+	if build, ok := service["build"]; ok {
+		normalized, err := normalizeBuild(build, fileDir)
+		if err != nil {
+			return fmt.Errorf("build: %w", err)
+		}
+		service["build"] = normalized
+	}
+	return nil
+}
+
+func normalizeBuild(raw any, fileDir string) (map[string]any, error) {
+	var build map[string]any
+	switch b := raw.(type) {
+	case string:
+		build = map[string]any{"context": b}
+	case map[string]any:
+		build = b
+	default:
+		return nil, fmt.Errorf("unsupported build definition")
+	}
+
+	context, _ := build["context"].(string)
+	if context == "" {
+		context = "."
+	}
+	if !filepath.IsAbs(context) && !strings.Contains(context, "://") {
+		context = filepath.Join(fileDir, context)
+	}
+	build["context"] = context
+
+	for _, key := range []string{"args", "labels"} {
+		if v, ok := build[key]; ok {
+			m, err := toStringMap(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			build[key] = m
+		}
+	}
+
+	if secrets, ok := build["secrets"]; ok {
+		ids := make([]any, 0)
+		for _, secret := range asList(secrets) {
+			switch s := secret.(type) {
+			case string:
+				ids = append(ids, s)
+			case map[string]any:
+				if source, ok := s["source"].(string); ok {
+					ids = append(ids, source)
+				}
+			}
+		}
+		build["secrets"] = ids
+	}
+	return build, nil
+}
+
+// toStringMap reads a mapping or a list of KEY=VALUE entries. Scalars are
+// kept as their string form and null values become empty strings.
+func toStringMap(raw any) (map[string]any, error) {
+	out := map[string]any{}
+	switch v := raw.(type) {
+	case nil:
+	case map[string]any:
+		for k, value := range v {
+			out[k] = scalarString(value)
+		}
+	case []any:
+		for _, item := range v {
+			entry, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported entry %v", item)
+			}
+			key, value, _ := strings.Cut(entry, "=")
+			out[key] = value
+		}
+	default:
+		return nil, fmt.Errorf("expected a mapping or a list")
+	}
+	return out, nil
+}
+
+func scalarString(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// normalizePorts converts the long syntax ({target, published, host_ip}) and
+// numeric entries to the short "published:target" syntax.
+func normalizePorts(ports []any) []any {
+	out := make([]any, 0, len(ports))
+	for _, port := range ports {
+		m, ok := port.(map[string]any)
+		if !ok {
+			out = append(out, scalarString(port))
+			continue
+		}
+		target := scalarString(m["target"])
+		published := scalarString(m["published"])
+		switch {
+		case published == "":
+			out = append(out, target)
+		case m["host_ip"] != nil:
+			out = append(out, fmt.Sprintf("%s:%s:%s", scalarString(m["host_ip"]), published, target))
+		default:
+			out = append(out, published+":"+target)
+		}
+	}
+	return out
+}
+
+// normalizeVolumes converts the long syntax ({source, target, read_only}) to
+// the short "source:target" syntax.
+func normalizeVolumes(volumes []any) []any {
+	out := make([]any, 0, len(volumes))
+	for _, volume := range volumes {
+		m, ok := volume.(map[string]any)
+		if !ok {
+			out = append(out, scalarString(volume))
+			continue
+		}
+		source := scalarString(m["source"])
+		target := scalarString(m["target"])
+		if source == "" {
+			out = append(out, target)
+			continue
+		}
+		entry := source + ":" + target
+		if readOnly, _ := m["read_only"].(bool); readOnly {
+			entry += ":ro"
+		}
+		out = append(out, entry)
+	}
+	return out
+}
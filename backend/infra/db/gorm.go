@@ -4,6 +4,7 @@ import (
 	"axolotl-cloud/infra/shared"
 	"axolotl-cloud/internal/app/model"
 	"fmt"
+	"strings"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -25,9 +26,32 @@ func InitDB() (*gorm.DB, error) {
 		&model.Job{},
 		&model.JobLog{},
 		&model.Setting{},
+		&model.Build{},
+		&model.ComposeTemplate{},
+		&model.Secret{},
+		&model.ContainerRevision{},
+		&model.User{},
+		&model.Session{},
+		&model.LoginChallenge{},
+		&model.APIToken{},
+		&model.ProjectMember{},
+		&model.OIDCGroupMapping{},
+		&model.AuditEvent{},
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The audit log is append-only, even for queries run by hand
+	for _, statement := range []string{"UPDATE", "DELETE"} {
+		err = db.Exec(fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS audit_events_no_%s BEFORE %s ON audit_events
+			BEGIN SELECT RAISE(ABORT, 'audit events are append-only'); END`, strings.ToLower(statement), statement)).Error
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return db, err
+	return db, nil
 }
 
 func ScanFK(db *gorm.DB) {
@@ -0,0 +1,132 @@
+package docker
+
+import (
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/internal/app/model"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	controlapi "github.com/moby/buildkit/api/services/control"
+)
+
+// buildKitTraceID is the id of the aux messages carrying BuildKit progress
+// (a base64 encoded controlapi.StatusResponse).
+const buildKitTraceID = "moby.buildkit.trace"
+
+// buildProgress keeps track of the BuildKit vertices of a build so that each
+// step can be logged once when it starts and once when it ends, and published
+// as a structured progress update.
+type buildProgress struct {
+	log   *logger.Logger
+	steps map[string]*model.BuildStep
+	order []string
+}
+
+func newBuildProgress(log *logger.Logger) *buildProgress {
+	return &buildProgress{
+		log:   log,
+		steps: make(map[string]*model.BuildStep),
+	}
+}
+
+func (p *buildProgress) handleTrace(aux json.RawMessage) error {
+	var dt []byte
+	if err := json.Unmarshal(aux, &dt); err != nil {
+		return fmt.Errorf("failed to decode BuildKit trace: %w", err)
+	}
+
+	var resp controlapi.StatusResponse
+	if err := resp.UnmarshalVT(dt); err != nil {
+		return fmt.Errorf("failed to decode BuildKit status: %w", err)
+	}
+
+	changed := false
+	for _, v := range resp.Vertexes {
+		if p.updateVertex(v) {
+			changed = true
+		}
+	}
+
+	for _, l := range resp.Logs {
+		prefix := ""
+		if i := p.index(l.Vertex); i > 0 {
+			prefix = fmt.Sprintf("#%d ", i)
+		}
+		for _, line := range strings.Split(strings.TrimRight(string(l.Msg), "\n"), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				p.log.Info("%s%s", prefix, line)
+			}
+		}
+	}
+
+	if changed {
+		p.log.Progress(p.snapshot())
+	}
+	return nil
+}
+
+// updateVertex merges a vertex into the known steps and logs its state
+// transitions. It reports whether anything changed.
+func (p *buildProgress) updateVertex(v *controlapi.Vertex) bool {
+	step, ok := p.steps[v.Digest]
+	if !ok {
+		step = &model.BuildStep{Digest: v.Digest}
+		p.steps[v.Digest] = step
+		p.order = append(p.order, v.Digest)
+	}
+	before := *step
+
+	step.Name = v.Name
+	step.Cached = v.Cached
+	step.Error = v.Error
+	if v.Started != nil {
+		started := v.Started.AsTime()
+		step.Started = &started
+	}
+	if v.Completed != nil {
+		completed := v.Completed.AsTime()
+		step.Completed = &completed
+	}
+
+	id := p.index(v.Digest)
+	if before.Started == nil && step.Started != nil && step.Completed == nil {
+		p.log.Info("#%d %s", id, step.Name)
+	}
+	if before.Completed == nil && step.Completed != nil {
+		switch {
+		case step.Error != "":
+			p.log.Error("#%d %s: %s", id, step.Name, step.Error)
+		case step.Cached:
+			p.log.Info("#%d CACHED %s", id, step.Name)
+		case step.Started != nil:
+			p.log.Info("#%d DONE %s (%.1fs)", id, step.Name, step.Completed.Sub(*step.Started).Seconds())
+		default:
+			p.log.Info("#%d DONE %s", id, step.Name)
+		}
+	}
+
+	return before.Name != step.Name ||
+		before.Cached != step.Cached ||
+		before.Error != step.Error ||
+		(before.Started == nil) != (step.Started == nil) ||
+		(before.Completed == nil) != (step.Completed == nil)
+}
+
+// index returns the 1-based position of a vertex in the build, 0 if unknown.
+func (p *buildProgress) index(digest string) int {
+	for i, d := range p.order {
+		if d == digest {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (p *buildProgress) snapshot() []model.BuildStep {
+	steps := make([]model.BuildStep, 0, len(p.order))
+	for _, d := range p.order {
+		steps = append(steps, *p.steps[d])
+	}
+	return steps
+}
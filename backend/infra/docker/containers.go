@@ -6,13 +6,20 @@ import (
 	"axolotl-cloud/internal/app/model"
 	"axolotl-cloud/utils"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/docker/docker/api/types/container"
 	dImage "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/go-connections/nat"
 )
 
@@ -44,6 +51,14 @@ func (dc *DockerClient) StartContainer(ctx context.Context, name string, log *lo
 	return name, nil
 }
 
+func (dc *DockerClient) RenameContainer(ctx context.Context, name string, newName string, log *logger.Logger) error {
+	if err := dc.cli.ContainerRename(ctx, name, newName); err != nil {
+		return fmt.Errorf("failed to rename container %s to %s: %w", name, newName, err)
+	}
+	log.Info("Container %s renamed to %s", name, newName)
+	return nil
+}
+
 func (dc *DockerClient) StopContainer(ctx context.Context, name string, log *logger.Logger) error {
 	cli := dc.cli
 
@@ -56,6 +71,20 @@ func (dc *DockerClient) StopContainer(ctx context.Context, name string, log *log
 	return nil
 }
 
+// StopContainerIfExists stops a container, doing nothing when there is no
+// container with this name.
+func (dc *DockerClient) StopContainerIfExists(ctx context.Context, name string, log *logger.Logger) error {
+	err := dc.cli.ContainerStop(ctx, name, container.StopOptions{})
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stop container %s: %w", name, err)
+	}
+	log.Info("Container %s stopped successfully", name)
+	return nil
+}
+
 func (dc *DockerClient) RemoveContainer(ctx context.Context, name string, log *logger.Logger) error {
 	cli := dc.cli
 
@@ -68,6 +97,34 @@ func (dc *DockerClient) RemoveContainer(ctx context.Context, name string, log *l
 	return nil
 }
 
+// RemoveContainerIfExists removes a container, doing nothing when there is no
+// container with this name.
+func (dc *DockerClient) RemoveContainerIfExists(ctx context.Context, name string, log *logger.Logger) error {
+	err := dc.cli.ContainerRemove(ctx, name, container.RemoveOptions{Force: true})
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove container %s: %w", name, err)
+	}
+	log.Info("Container %s removed successfully", name)
+	return nil
+}
+
+// RemoveVolumes deletes the volume directories created for a container (the
+// ones with a relative host path).
+func (dc *DockerClient) RemoveVolumes(name string, log *logger.Logger) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("invalid container name %q", name)
+	}
+	dir := filepath.Join(shared.GetEnv("VOLUMES_PATH_CONTAINER"), name)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove volumes of %s: %w", name, err)
+	}
+	log.Info("Volumes of %s removed", name)
+	return nil
+}
+
 func (dc *DockerClient) PullImage(ctx context.Context, image string, log *logger.Logger) error {
 	cli := dc.cli
 	reader, err := cli.ImagePull(ctx, image, dImage.PullOptions{})
@@ -86,17 +143,61 @@ func (dc *DockerClient) PullImage(ctx context.Context, image string, log *logger
 	return nil
 }
 
-func (dc *DockerClient) CreateContainer(ctx context.Context, name string, image string, ports map[string]string, env map[string]string, volumes map[string]string, networkMode string, log *logger.Logger) (string, error) {
-	cli := dc.cli
+// ContainerSpec describes a container to create.
+type ContainerSpec struct {
+	Name        string
+	Image       string
+	Ports       map[string]string // host port -> container port
+	Env         map[string]string
+	Volumes     map[string]string // host path (relative to the volumes directory or absolute) -> container path
+	NetworkMode string            // bridge, host or none
+	// Network is the user-defined network the container joins in bridge mode,
+	// so that the other containers of the network can reach it by name.
+	Network string
+	Aliases []string // extra names of the container on Network
+	// Entrypoint and Cmd override the ones of the image when set.
+	Entrypoint []string `json:",omitempty"`
+	Cmd        []string `json:",omitempty"`
+	// VolumeOwner is the name the volume directories are created under,
+	// Name when empty. It lets a temporary container use the volumes of the
+	// container it replaces.
+	VolumeOwner string            `json:"-"`
+	Labels      map[string]string `json:"-"`
+}
 
-	if err := dc.PullImage(ctx, image, log); err != nil {
-		return "", fmt.Errorf("failed to pull image %s: %w", image, err)
-	}
+// Labels set on the containers created by Axolotl.
+const (
+	// ConfigHashLabel holds the hash of the configuration a container has been
+	// created from, see ContainerSpec.Hash.
+	ConfigHashLabel = "axolotl.config-hash"
+	ManagedLabel    = "axolotl.managed"
+	ProjectLabel    = "axolotl.project"   // ID of the project
+	ContainerLabel  = "axolotl.container" // ID of the model.Container
+	// ProxyLabel marks the proxy publishing the ports of a container, see
+	// ProxySpec. It holds the name of the container.
+	ProxyLabel = "axolotl.proxy"
+)
+
+// Hash identifies the configuration of the spec along with the image it
+// runs (imageID, so that a moved tag counts as a change). Labels are left out.
+func (spec ContainerSpec) Hash(imageID string) string {
+	data, _ := json.Marshal(struct {
+		Spec    ContainerSpec
+		ImageID string
+	}{spec, imageID})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateContainer creates the container from spec. The image must have been
+// pulled beforehand.
+func (dc *DockerClient) CreateContainer(ctx context.Context, spec ContainerSpec, log *logger.Logger) (string, error) {
+	cli := dc.cli
 
 	// ports
 	exposed := nat.PortSet{}
 	bindings := nat.PortMap{}
-	for host, cont := range ports {
+	for host, cont := range spec.Ports {
 		port := nat.Port(cont + "/tcp")
 		exposed[port] = struct{}{}
 		bindings[port] = []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: host}}
@@ -104,7 +205,7 @@ func (dc *DockerClient) CreateContainer(ctx context.Context, name string, image
 
 	// env
 	var envVars []string
-	for k, v := range env {
+	for k, v := range spec.Env {
 		envVars = append(envVars, k+"="+v)
 	}
 
@@ -112,22 +213,26 @@ func (dc *DockerClient) CreateContainer(ctx context.Context, name string, image
 	var mounts []mount.Mount
 	volumesPathHost := shared.GetEnv("VOLUMES_PATH_HOST")
 	volumesPathContainer := shared.GetEnv("VOLUMES_PATH_CONTAINER")
+	owner := spec.VolumeOwner
+	if owner == "" {
+		owner = spec.Name
+	}
 
-	for hostPath, containerPath := range volumes {
+	for hostPath, containerPath := range spec.Volumes {
 		sourceHost := hostPath
 		if !utils.IsAbsolutePath(hostPath) {
-			sourceContainer := fmt.Sprintf("%s/%s/%s", volumesPathContainer, name, hostPath)
+			sourceContainer := fmt.Sprintf("%s/%s/%s", volumesPathContainer, owner, hostPath)
 			log.Info("Creating volume at: %s", sourceContainer)
 			if err := os.MkdirAll(sourceContainer, 0755); err != nil {
 				return "", fmt.Errorf("failed to create volume directory %s: %w", sourceContainer, err)
 			}
-			sourceHost = fmt.Sprintf("%s/%s/%s", volumesPathHost, name, hostPath)
+			sourceHost = fmt.Sprintf("%s/%s/%s", volumesPathHost, owner, hostPath)
 		}
 
 		mounts = append(mounts, mount.Mount{Type: mount.TypeBind, Source: sourceHost, Target: containerPath})
 	}
 
-	config := &container.Config{Image: image, Env: envVars, ExposedPorts: exposed}
+	config := &container.Config{Image: spec.Image, Env: envVars, ExposedPorts: exposed, Labels: spec.Labels, Entrypoint: spec.Entrypoint, Cmd: spec.Cmd}
 	hostConfig := &container.HostConfig{
 		PortBindings: bindings,
 		Mounts:       mounts,
@@ -138,19 +243,59 @@ func (dc *DockerClient) CreateContainer(ctx context.Context, name string, image
 			CgroupParent: "/docker.slice",
 		},
 	}
-	if networkMode != "" {
-		hostConfig.NetworkMode = container.NetworkMode(networkMode)
+	if spec.NetworkMode != "" {
+		hostConfig.NetworkMode = container.NetworkMode(spec.NetworkMode)
+	}
+
+	var networkingConfig *network.NetworkingConfig
+	if spec.Network != "" && (spec.NetworkMode == "" || spec.NetworkMode == "bridge") {
+		hostConfig.NetworkMode = container.NetworkMode(spec.Network)
+		networkingConfig = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				spec.Network: {Aliases: spec.Aliases},
+			},
+		}
 	}
 
-	resp, err := cli.ContainerCreate(ctx, config, hostConfig, nil, nil, name)
+	resp, err := cli.ContainerCreate(ctx, config, hostConfig, networkingConfig, nil, spec.Name)
 	if err != nil {
 		return "", fmt.Errorf("failed to create container: %w", err)
 	}
-	log.Info("Container %s created successfully", name)
+	log.Info("Container %s created successfully", spec.Name)
 
 	return resp.ID, nil
 }
 
+// ListContainers returns all the Docker containers, running or not.
+func (dc *DockerClient) ListContainers(ctx context.Context) ([]container.Summary, error) {
+	containers, err := dc.cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	return containers, nil
+}
+
+func (dc *DockerClient) InspectContainer(ctx context.Context, name string) (container.InspectResponse, error) {
+	info, err := dc.cli.ContainerInspect(ctx, name)
+	if err != nil {
+		return container.InspectResponse{}, fmt.Errorf("failed to inspect container %s: %w", name, err)
+	}
+	return info, nil
+}
+
+// ContainerLabels returns the labels of a container. exists is false when
+// there is no container with this name.
+func (dc *DockerClient) ContainerLabels(ctx context.Context, name string) (labels map[string]string, exists bool, err error) {
+	info, err := dc.cli.ContainerInspect(ctx, name)
+	if errdefs.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to inspect container %s: %w", name, err)
+	}
+	return info.Config.Labels, true, nil
+}
+
 func (dc *DockerClient) ContainerStatus(ctx context.Context, name string) (container.ContainerState, error) {
 	cli := dc.cli
 
@@ -2,12 +2,17 @@ package docker
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/docker/docker/client"
 )
 
 type DockerClient struct {
 	cli *client.Client
+
+	selfMu       sync.Mutex
+	selfID       string // container Axolotl runs in, see Self
+	selfResolved bool
 }
 
 func NewDockerClient() (*DockerClient, error) {
@@ -0,0 +1,166 @@
+package docker
+
+import (
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/internal/app/model"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+const (
+	defaultHealthTimeout = 60 * time.Second
+	healthPollInterval   = 2 * time.Second
+	// stableRunningPeriod is how long a container without healthcheck nor
+	// probe must keep running to be considered healthy.
+	stableRunningPeriod = 10 * time.Second
+)
+
+// WaitHealthy waits until the container is ready: its Docker healthcheck
+// reports healthy, or else the probe succeeds, or else it keeps running
+// without restarting for a few seconds. When Axolotl runs in a container, it
+// joins the network of the container for the time of the probe.
+func (dc *DockerClient) WaitHealthy(ctx context.Context, name string, probe *model.HealthProbe, log *logger.Logger) error {
+	timeout := defaultHealthTimeout
+	if probe != nil && probe.Timeout > 0 {
+		timeout = time.Duration(probe.Timeout) * time.Second
+	}
+	var joined []string
+	defer func() {
+		for _, network := range joined {
+			if err := dc.DisconnectSelf(context.WithoutCancel(ctx), network, log); err != nil {
+				log.Error("%v", err)
+			}
+		}
+	}()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var runningSince time.Time
+	restarts := -1
+	for {
+		info, err := dc.cli.ContainerInspect(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to inspect container %s: %w", name, err)
+		}
+		state := info.State
+
+		switch {
+		case state.Status == container.StateExited || state.Status == container.StateDead:
+			return fmt.Errorf("container %s exited with code %d", name, state.ExitCode)
+		case restarts >= 0 && info.RestartCount > restarts:
+			return fmt.Errorf("container %s restarted while waiting for it to be healthy", name)
+		case state.Health != nil:
+			switch state.Health.Status {
+			case container.Healthy:
+				log.Info("Container %s is healthy", name)
+				return nil
+			case container.Unhealthy:
+				return fmt.Errorf("container %s is unhealthy", name)
+			}
+		case probe != nil && state.Running:
+			host, network := probeHost(info)
+			if network != "" {
+				if ok, err := dc.ConnectSelf(ctx, network, log); err != nil {
+					return err
+				} else if ok {
+					joined = append(joined, network)
+				}
+			}
+			if err := runProbe(ctx, host, probe); err == nil {
+				log.Info("Container %s answers the %s probe on port %s", name, probe.Type, probe.Port)
+				return nil
+			}
+		case state.Running:
+			if runningSince.IsZero() {
+				runningSince = time.Now()
+			}
+			if time.Since(runningSince) >= stableRunningPeriod {
+				log.Info("Container %s has been running for %s", name, stableRunningPeriod)
+				return nil
+			}
+		}
+		restarts = info.RestartCount
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("container %s did not become healthy within %s", name, timeout)
+		case <-time.After(healthPollInterval):
+		}
+	}
+}
+
+// probeHost returns the address the server reaches the container at, along
+// with the network it is reached through (empty in host network mode).
+func probeHost(info container.InspectResponse) (host string, network string) {
+	if info.HostConfig != nil && info.HostConfig.NetworkMode.IsHost() {
+		return "127.0.0.1", ""
+	}
+	if info.NetworkSettings != nil {
+		for network, endpoint := range info.NetworkSettings.Networks {
+			if endpoint != nil && endpoint.IPAddress != "" {
+				return endpoint.IPAddress, network
+			}
+		}
+	}
+	return "", ""
+}
+
+// probeClient never follows redirects, which could send the probe to another
+// host, a redirect is an answer of the container.
+var probeClient = &http.Client{
+	Timeout: healthPollInterval,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func runProbe(ctx context.Context, host string, probe *model.HealthProbe) error {
+	if host == "" {
+		return fmt.Errorf("container has no IP address")
+	}
+	if _, err := strconv.ParseUint(probe.Port, 10, 16); err != nil {
+		return fmt.Errorf("invalid probe port %q", probe.Port)
+	}
+	address := net.JoinHostPort(host, probe.Port)
+
+	ctx, cancel := context.WithTimeout(ctx, healthPollInterval)
+	defer cancel()
+
+	if probe.Type == model.ProbeTCP {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	path := probe.Path
+	if path == "" {
+		path = "/"
+	}
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("probe path %q must start with /", path)
+	}
+	probeURL := url.URL{Scheme: "http", Host: address, Path: path}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := probeClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("probe answered %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunProbeHTTP(t *testing.T) {
+	var redirected atomic.Bool
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected.Store(true)
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/", "/health":
+			w.WriteHeader(http.StatusOK)
+		case "/redirect":
+			http.Redirect(w, r, other.URL, http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		port    string
+		path    string
+		wantErr bool
+	}{
+		{name: "default path", port: port},
+		{name: "path", port: port, path: "/health"},
+		{name: "unhealthy", port: port, path: "/down", wantErr: true},
+		{name: "redirect not followed", port: port, path: "/redirect"},
+		{name: "relative path", port: port, path: "health", wantErr: true},
+		{name: "path changing the host", port: port, path: "@" + other.Listener.Addr().String() + "/", wantErr: true},
+		{name: "invalid port", port: port + "/x", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			probe := &model.HealthProbe{Type: model.ProbeHTTP, Port: test.port, Path: test.path}
+			err := runProbe(context.Background(), host, probe)
+			if (err != nil) != test.wantErr {
+				t.Errorf("runProbe() = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+	if redirected.Load() {
+		t.Error("the probe followed a redirect to another host")
+	}
+}
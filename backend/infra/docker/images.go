@@ -12,37 +12,71 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types/build"
+	dImage "github.com/docker/docker/api/types/image"
 	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/secrets/secretsprovider"
 )
 
 type buildMsg struct {
-	Stream      string          `json:"stream"`
-	Error       string          `json:"error"`
-	ErrorDetail json.RawMessage `json:"errorDetail"`
-	Status      string          `json:"status"`
+	ID          string            `json:"id"`
+	Stream      string            `json:"stream"`
+	Error       string            `json:"error"`
+	ErrorDetail *buildErrorDetail `json:"errorDetail"`
+	Status      string            `json:"status"`
+	Aux         json.RawMessage   `json:"aux"`
 }
 
-func (dc *DockerClient) BuildImage(
-	ctx context.Context,
-	contextDir string,
-	dockerfile string,
-	imageName string,
-	log *logger.Logger,
-) error {
-	log.Info("Building Docker image %s from directory %s", imageName, contextDir)
+// imageIDAuxID is the id of the aux message holding the ID of the built image.
+const imageIDAuxID = "moby.image.id"
 
-	buf, err := createTarFromDir(contextDir)
+type buildErrorDetail struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// BuildSpec describes everything needed to build an image, as found in a
+// compose `build:` section or in a build request.
+type BuildSpec struct {
+	ContextDir string
+	Dockerfile string
+	Tags       []string
+	Args       map[string]string
+	Target     string
+	Labels     map[string]string
+	CacheFrom  []string
+	Network    string
+	Platform   string
+	// Secrets are exposed to the build through the BuildKit session
+	// (RUN --mount=type=secret,id=...) and never end up in image layers.
+	Secrets map[string][]byte
+	// DockerfileContent is a generated Dockerfile, added to the build
+	// context as Dockerfile without being written in ContextDir.
+	DockerfileContent string
+}
+
+// BuildImage builds spec and returns the ID of the resulting image.
+func (dc *DockerClient) BuildImage(ctx context.Context, spec BuildSpec, log *logger.Logger) (string, error) {
+	log.Info("Building Docker image %s from directory %s", strings.Join(spec.Tags, ", "), spec.ContextDir)
+
+	dockerfile := spec.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+
+	buf, err := createTarFromDir(spec.ContextDir, dockerfile, spec.DockerfileContent)
 	if err != nil {
-		return fmt.Errorf("error creating tar from directory %s: %w", contextDir, err)
+		return "", fmt.Errorf("error creating tar from directory %s: %w", spec.ContextDir, err)
 	}
 
 	// Create a new session for the build
 	sess, err := session.NewSession(ctx, "axolotl-cloud")
 	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
+		return "", fmt.Errorf("failed to create session: %w", err)
 	}
+	sess.Allow(secretsprovider.FromMap(spec.Secrets))
 
 	dialSession := func(ctx context.Context, proto string, meta map[string][]string) (net.Conn, error) {
 		return dc.cli.DialHijack(ctx, "/session", proto, meta)
@@ -56,22 +90,36 @@ func (dc *DockerClient) BuildImage(
 		}
 	}()
 
+	buildArgs := make(map[string]*string, len(spec.Args))
+	for k, v := range spec.Args {
+		buildArgs[k] = &v
+	}
+
 	buildOptions := build.ImageBuildOptions{
-		Tags:        []string{imageName},
+		Tags:        spec.Tags,
 		Remove:      true,
 		ForceRemove: true,
 		Version:     build.BuilderBuildKit,
 		SessionID:   sess.ID(),
 		Dockerfile:  dockerfile,
+		BuildArgs:   buildArgs,
+		Target:      spec.Target,
+		Labels:      spec.Labels,
+		CacheFrom:   spec.CacheFrom,
+		NetworkMode: spec.Network,
+		Platform:    spec.Platform,
 	}
-	log.Info("Building image with options: %+v", buildOptions)
+	log.Info("Building image with dockerfile=%s target=%s platform=%s args=%v secrets=%v",
+		dockerfile, spec.Target, spec.Platform, keys(spec.Args), keys(spec.Secrets))
 
 	response, err := dc.cli.ImageBuild(ctx, buf, buildOptions)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer response.Body.Close()
 
+	imageID := ""
+	progress := newBuildProgress(log)
 	dec := json.NewDecoder(response.Body)
 	for {
 		var m buildMsg
@@ -79,20 +127,87 @@ func (dc *DockerClient) BuildImage(
 			if err == io.EOF {
 				break
 			}
-			return err
+			return "", err
+		}
+		if m.ErrorDetail != nil && m.ErrorDetail.Message != "" {
+			return "", fmt.Errorf("build error: %s", m.ErrorDetail.Message)
 		}
 		if m.Error != "" {
-			return fmt.Errorf("build error: %s", m.Error)
+			return "", fmt.Errorf("build error: %s", m.Error)
+		}
+		if m.ID == imageIDAuxID && len(m.Aux) > 0 {
+			var aux struct {
+				ID string `json:"ID"`
+			}
+			if err := json.Unmarshal(m.Aux, &aux); err == nil {
+				imageID = aux.ID
+			}
+			continue
+		}
+		if m.ID == buildKitTraceID && len(m.Aux) > 0 {
+			if err := progress.handleTrace(m.Aux); err != nil {
+				log.Error("%v", err)
+			}
+			continue
+		}
+		if m.Status != "" {
+			log.Info("%s", strings.TrimSpace(m.Status))
 		}
 		if m.Stream != "" && m.Stream != "\n" {
-			log.Info(strings.TrimSpace(m.Stream))
+			log.Info("%s", strings.TrimSpace(m.Stream))
 		}
 	}
 
+	return imageID, nil
+}
+
+// ImageID returns the ID (content digest) of a local image.
+func (dc *DockerClient) ImageID(ctx context.Context, image string) (string, error) {
+	info, err := dc.cli.ImageInspect(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image %s: %w", image, err)
+	}
+	return info.ID, nil
+}
+
+// ImageEnv returns the environment variables defined by an image.
+func (dc *DockerClient) ImageEnv(ctx context.Context, image string) ([]string, error) {
+	info, err := dc.cli.ImageInspect(ctx, image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image %s: %w", image, err)
+	}
+	if info.Config == nil {
+		return nil, nil
+	}
+	return info.Config.Env, nil
+}
+
+func (dc *DockerClient) TagImage(ctx context.Context, source string, target string) error {
+	if err := dc.cli.ImageTag(ctx, source, target); err != nil {
+		return fmt.Errorf("failed to tag image %s as %s: %w", source, target, err)
+	}
 	return nil
 }
 
-func createTarFromDir(dir string) (*bytes.Buffer, error) {
+func (dc *DockerClient) RemoveImage(ctx context.Context, image string, log *logger.Logger) error {
+	if _, err := dc.cli.ImageRemove(ctx, image, dImage.RemoveOptions{PruneChildren: true}); err != nil {
+		return fmt.Errorf("failed to remove image %s: %w", image, err)
+	}
+	log.Info("Image %s removed successfully", image)
+	return nil
+}
+
+// keys returns the keys of m, so that build args and secrets can be logged
+// without their values.
+func keys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+func createTarFromDir(dir string, dockerfile string, dockerfileContent string) (*bytes.Buffer, error) {
 	buf := new(bytes.Buffer)
 	tw := tar.NewWriter(buf)
 
@@ -105,6 +220,9 @@ func createTarFromDir(dir string) (*bytes.Buffer, error) {
 		if err != nil {
 			return err
 		}
+		if dockerfileContent != "" && (relPath == dockerfile || relPath == ".dockerignore") {
+			return nil
+		}
 
 		if fi.Mode().IsRegular() {
 			f, err := os.Open(file)
@@ -140,9 +258,36 @@ func createTarFromDir(dir string) (*bytes.Buffer, error) {
 	if err != nil {
 		return nil, err
 	}
+	if dockerfileContent != "" {
+		if err := addGeneratedDockerfile(tw, dir, dockerfile, dockerfileContent); err != nil {
+			return nil, err
+		}
+	}
 	if err := tw.Close(); err != nil {
 		return nil, err
 	}
 
 	return buf, nil
 }
+
+// addGeneratedDockerfile adds a generated Dockerfile to the build context,
+// and to its .dockerignore so that `COPY . .` leaves it out of the image, as
+// the Docker CLI does for a Dockerfile outside of the context.
+func addGeneratedDockerfile(tw *tar.Writer, dir string, dockerfile string, content string) error {
+	ignore, err := os.ReadFile(filepath.Join(dir, ".dockerignore"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	ignore = append(ignore, []byte("\n"+dockerfile+"\n")...)
+
+	for name, data := range map[string][]byte{dockerfile: []byte(content), ".dockerignore": ignore} {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
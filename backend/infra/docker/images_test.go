@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateTarFromDirGeneratedDockerfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		content string
+		want    map[string]string
+	}{
+		{
+			name:  "repository Dockerfile",
+			files: map[string]string{"Dockerfile": "FROM scratch", "main.go": "package main"},
+			want:  map[string]string{"Dockerfile": "FROM scratch", "main.go": "package main"},
+		},
+		{
+			name:    "generated Dockerfile",
+			files:   map[string]string{"index.html": "<html>"},
+			content: "FROM nginx",
+			want:    map[string]string{"index.html": "<html>", "Dockerfile.axolotl": "FROM nginx", ".dockerignore": "\nDockerfile.axolotl\n"},
+		},
+		{
+			name:    "generated Dockerfile replacing a repository file",
+			files:   map[string]string{"Dockerfile.axolotl": "FROM evil", ".dockerignore": "node_modules"},
+			content: "FROM nginx",
+			want:    map[string]string{"Dockerfile.axolotl": "FROM nginx", ".dockerignore": "node_modules\nDockerfile.axolotl\n"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range test.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dockerfile := "Dockerfile"
+			if test.content != "" {
+				dockerfile = "Dockerfile.axolotl"
+			}
+
+			buf, err := createTarFromDir(dir, dockerfile, test.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			tr := tar.NewReader(buf)
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hdr.Typeflag != tar.TypeReg {
+					continue
+				}
+				if _, seen := got[hdr.Name]; seen {
+					t.Errorf("%s is twice in the context", hdr.Name)
+				}
+				data, err := io.ReadAll(tr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[hdr.Name] = string(data)
+			}
+			if len(got) != len(test.want) {
+				t.Errorf("context = %v, want %v", got, test.want)
+			}
+			for name, content := range test.want {
+				if got[name] != content {
+					t.Errorf("%s = %q, want %q", name, got[name], content)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, "Dockerfile.axolotl")); test.files["Dockerfile.axolotl"] == "" && err == nil {
+				t.Error("the generated Dockerfile was written into the build context directory")
+			}
+		})
+	}
+}
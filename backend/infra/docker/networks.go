@@ -0,0 +1,42 @@
+package docker
+
+import (
+	"axolotl-cloud/infra/logger"
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+)
+
+// RemoveNetwork removes a network, doing nothing when it does not exist.
+func (dc *DockerClient) RemoveNetwork(ctx context.Context, name string, log *logger.Logger) error {
+	// left connected by an interrupted health check
+	if err := dc.DisconnectSelf(ctx, name, log); err != nil {
+		return err
+	}
+	err := dc.cli.NetworkRemove(ctx, name)
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove network %s: %w", name, err)
+	}
+	log.Info("Network %s removed", name)
+	return nil
+}
+
+// EnsureNetwork creates the bridge network if it does not exist yet.
+func (dc *DockerClient) EnsureNetwork(ctx context.Context, name string, log *logger.Logger) error {
+	if _, err := dc.cli.NetworkInspect(ctx, name, network.InspectOptions{}); err == nil {
+		return nil
+	} else if !errdefs.IsNotFound(err) {
+		return fmt.Errorf("failed to inspect network %s: %w", name, err)
+	}
+
+	if _, err := dc.cli.NetworkCreate(ctx, name, network.CreateOptions{Driver: "bridge"}); err != nil {
+		return fmt.Errorf("failed to create network %s: %w", name, err)
+	}
+	log.Info("Network %s created", name)
+	return nil
+}
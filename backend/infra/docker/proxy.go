@@ -0,0 +1,55 @@
+package docker
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const defaultProxyImage = "alpine/socat"
+
+// ProxyName returns the name of the proxy of a container.
+func ProxyName(name string) string {
+	return name + "_proxy"
+}
+
+// ProxySpec returns the spec of the proxy publishing ports (host port ->
+// container port) for the container target of network. The proxy forwards
+// each connection to whichever container answers to the name target at the
+// time, so that the container can be replaced while the ports stay bound.
+// Its image is PROXY_IMAGE, alpine/socat by default.
+func ProxySpec(target string, network string, ports map[string]string) (ContainerSpec, error) {
+	image := os.Getenv("PROXY_IMAGE")
+	if image == "" {
+		image = defaultProxyImage
+	}
+
+	published := make(map[string]string, len(ports))
+	var forwards []string
+	for _, host := range slices.Sorted(maps.Keys(ports)) {
+		port := ports[host]
+		if _, err := strconv.ParseUint(host, 10, 16); err != nil {
+			return ContainerSpec{}, fmt.Errorf("invalid host port %q", host)
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return ContainerSpec{}, fmt.Errorf("invalid container port %q", port)
+		}
+		// the proxy listens on the host port, so that two host ports can
+		// lead to the same container port
+		published[host] = host
+		forwards = append(forwards, fmt.Sprintf("socat TCP-LISTEN:%s,fork,reuseaddr TCP:%s:%s &", host, target, port))
+	}
+
+	return ContainerSpec{
+		Name:        ProxyName(target),
+		Image:       image,
+		Ports:       published,
+		NetworkMode: "bridge",
+		Network:     network,
+		Entrypoint:  []string{"/bin/sh", "-c"},
+		Cmd:         []string{strings.Join(append(forwards, "wait"), " ")},
+	}, nil
+}
@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"axolotl-cloud/infra/logger"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/docker/docker/errdefs"
+)
+
+// Self returns the ID of the container Axolotl runs in, false when it does not
+// run in a container. The container is AXOLOTL_CONTAINER when set, else the
+// one named after the hostname of the server (its short ID by default).
+func (dc *DockerClient) Self(ctx context.Context) (string, bool) {
+	dc.selfMu.Lock()
+	defer dc.selfMu.Unlock()
+
+	if !dc.selfResolved {
+		id, err := dc.findSelf(ctx)
+		if err != nil {
+			logger.Error("Failed to find the container of Axolotl", err)
+			return "", false
+		}
+		dc.selfID, dc.selfResolved = id, true
+	}
+	return dc.selfID, dc.selfID != ""
+}
+
+func (dc *DockerClient) findSelf(ctx context.Context) (string, error) {
+	name := os.Getenv("AXOLOTL_CONTAINER")
+	explicit := name != ""
+	if !explicit {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", nil
+		}
+		name = hostname
+	}
+
+	info, err := dc.cli.ContainerInspect(ctx, name)
+	if errdefs.IsNotFound(err) && !explicit {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container %s: %w", name, err)
+	}
+	// another container may be named after the hostname of the server
+	if !explicit && (info.Config == nil || info.Config.Hostname != name) {
+		return "", nil
+	}
+	return info.ID, nil
+}
+
+// ConnectSelf connects the container of Axolotl to a network, so that it
+// reaches the containers of the network. joined is false when there was
+// nothing to do: Axolotl does not run in a container or is already connected.
+func (dc *DockerClient) ConnectSelf(ctx context.Context, network string, log *logger.Logger) (joined bool, err error) {
+	id, ok := dc.Self(ctx)
+	if !ok {
+		return false, nil
+	}
+	info, err := dc.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect the container of Axolotl: %w", err)
+	}
+	if info.NetworkSettings != nil {
+		if _, connected := info.NetworkSettings.Networks[network]; connected {
+			return false, nil
+		}
+	}
+	if err := dc.cli.NetworkConnect(ctx, network, id, nil); err != nil {
+		return false, fmt.Errorf("failed to connect Axolotl to network %s: %w", network, err)
+	}
+	log.Info("Axolotl joined network %s", network)
+	return true, nil
+}
+
+// DisconnectSelf disconnects the container of Axolotl from a network, doing
+// nothing when it is not connected.
+func (dc *DockerClient) DisconnectSelf(ctx context.Context, network string, log *logger.Logger) error {
+	id, ok := dc.Self(ctx)
+	if !ok {
+		return nil
+	}
+	info, err := dc.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to inspect the container of Axolotl: %w", err)
+	}
+	if info.NetworkSettings == nil || info.NetworkSettings.Networks[network] == nil {
+		return nil
+	}
+	if err := dc.cli.NetworkDisconnect(ctx, network, id, true); err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("failed to disconnect Axolotl from network %s: %w", network, err)
+	}
+	log.Info("Axolotl left network %s", network)
+	return nil
+}
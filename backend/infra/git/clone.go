@@ -1,25 +1,20 @@
 package git
 
 import (
+	"axolotl-cloud/infra/workspace"
 	"fmt"
-	"os"
 	"os/exec"
+	"strings"
 )
 
 func CloneRepository(gitURL, destination string, accessToken string) (string, error) {
 	// Ensure the destination directory exists, if exists remove it
-	dir := fmt.Sprintf("./tmp/%s", destination)
-	if _, err := os.Stat(dir); !os.IsNotExist(err) {
-		if err := os.RemoveAll(dir); err != nil {
-			return "", err
-		}
-	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	dir, err := workspace.Prepare(destination)
+	if err != nil {
 		return "", err
 	}
 
 	cloneURL := gitURL
-	var err error
 	if accessToken != "" {
 		cloneURL, err = injectTokenIntoURL(gitURL, accessToken)
 		if err != nil {
@@ -48,3 +43,12 @@ func injectTokenIntoURL(gitURL, accessToken string) (string, error) {
 	}
 	return "", fmt.Errorf("unsupported git URL format: %s", gitURL)
 }
+
+// HeadCommit returns the SHA of the commit checked out in dir.
+func HeadCommit(dir string) (string, error) {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD commit: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"fmt"
+	"strings"
 )
 
 type LogLevel string
@@ -13,7 +14,8 @@ const (
 )
 
 type Logger struct {
-	output func(level LogLevel, msg string, args ...any)
+	output   func(level LogLevel, msg string, args ...any)
+	progress func(data any)
 }
 
 func NewLogger(output func(level LogLevel, msg string, args ...any)) *Logger {
@@ -22,6 +24,45 @@ func NewLogger(output func(level LogLevel, msg string, args ...any)) *Logger {
 	}
 }
 
+// WithProgress returns a copy of the logger that forwards structured progress
+// updates (e.g. build steps) to fn, next to the plain log lines.
+func (l *Logger) WithProgress(fn func(data any)) *Logger {
+	return &Logger{
+		output:   l.output,
+		progress: fn,
+	}
+}
+
+// Progress publishes a structured progress update. It does nothing when the
+// logger has no progress sink.
+func (l *Logger) Progress(data any) {
+	if l.progress != nil {
+		l.progress(data)
+	}
+}
+
+// WithRedaction returns a copy of the logger replacing every occurrence of the
+// values (e.g. resolved secrets) with mask.
+func (l *Logger) WithRedaction(values []string, mask string) *Logger {
+	var pairs []string
+	for _, value := range values {
+		if value != "" {
+			pairs = append(pairs, value, mask)
+		}
+	}
+	if len(pairs) == 0 {
+		return l
+	}
+	replacer := strings.NewReplacer(pairs...)
+	output := l.output
+	return &Logger{
+		output: func(level LogLevel, msg string, args ...any) {
+			output(level, "%s", replacer.Replace(fmt.Sprintf(msg, args...)))
+		},
+		progress: l.progress,
+	}
+}
+
 func (l *Logger) Info(msg string, args ...any) {
 	l.output(LevelInfo, msg, args...)
 }
@@ -0,0 +1,46 @@
+package network
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// OriginPolicy decides which browser origins may call the API and open the
+// WebSocket. The web UI, served by the same host, is always allowed.
+type OriginPolicy struct {
+	static []string
+	// dynamic returns the origins added at runtime, e.g. from the settings
+	dynamic func() []string
+}
+
+func NewOriginPolicy(static []string, dynamic func() []string) *OriginPolicy {
+	return &OriginPolicy{static: static, dynamic: dynamic}
+}
+
+// Allowed reports whether a cross-origin request from origin is allowed. There
+// is no wildcard: the API is called with credentials.
+func (p *OriginPolicy) Allowed(origin string) bool {
+	origin = strings.TrimSuffix(origin, "/")
+	if slices.Contains(p.static, origin) {
+		return true
+	}
+	if p.dynamic != nil {
+		return slices.Contains(p.dynamic(), origin)
+	}
+	return false
+}
+
+// CheckRequest reports whether the request may be served: requests without
+// Origin (not from a browser) and same-origin requests always are.
+func (p *OriginPolicy) CheckRequest(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return p.Allowed(origin)
+}
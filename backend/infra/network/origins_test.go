@@ -0,0 +1,37 @@
+package network
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOriginPolicyCheckRequest(t *testing.T) {
+	policy := NewOriginPolicy([]string{"https://dashboard.example", "*"}, func() []string {
+		return []string{"https://runtime.example"}
+	})
+	tests := []struct {
+		name   string
+		origin string
+		want   bool
+	}{
+		{name: "no origin", origin: "", want: true},
+		{name: "same origin", origin: "http://axolotl.example", want: true},
+		{name: "static origin", origin: "https://dashboard.example", want: true},
+		{name: "trailing slash", origin: "https://dashboard.example/", want: true},
+		{name: "setting origin", origin: "https://runtime.example", want: true},
+		{name: "other origin", origin: "https://evil.example", want: false},
+		{name: "wildcard is no wildcard", origin: "https://other.example", want: false},
+		{name: "other scheme", origin: "http://dashboard.example", want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://axolotl.example/api/v1/ws", nil)
+			if test.origin != "" {
+				r.Header.Set("Origin", test.origin)
+			}
+			if got := policy.CheckRequest(r); got != test.want {
+				t.Errorf("CheckRequest() with Origin %q = %v, want %v", test.origin, got, test.want)
+			}
+		})
+	}
+}
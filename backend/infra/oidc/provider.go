@@ -0,0 +1,174 @@
+package oidc
+
+import (
+	"axolotl-cloud/infra/shared"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Flow holds the values of an authorization request, kept by the browser
+// until the provider redirects back.
+type Flow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"` // PKCE code verifier
+}
+
+// Identity is the user described by a verified ID token.
+type Identity struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Email    string // empty unless verified by the provider
+	Groups   []string
+}
+
+// Provider runs the authorization code flow with PKCE against an OpenID
+// Connect provider. Discovery happens on first use and is retried until it
+// succeeds, so that the provider being down does not prevent startup.
+type Provider struct {
+	config shared.OIDC
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+}
+
+func NewProvider(config shared.OIDC) *Provider {
+	return &Provider{config: config}
+}
+
+// Enabled reports whether single sign-on is configured.
+func (p *Provider) Enabled() bool {
+	return p != nil && p.config.Issuer != ""
+}
+
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *gooidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth2 != nil {
+		return p.oauth2, p.verifier, nil
+	}
+
+	provider, err := gooidc.NewProvider(ctx, p.config.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover OIDC provider %s: %w", p.config.Issuer, err)
+	}
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       []string{gooidc.ScopeOpenID, "profile", "email", "groups"},
+	}
+	p.verifier = provider.Verifier(&gooidc.Config{ClientID: p.config.ClientID})
+	return p.oauth2, p.verifier, nil
+}
+
+// Start begins a login: it returns the URL of the provider to redirect the
+// browser to, and the flow to keep until the callback.
+func (p *Provider) Start(ctx context.Context) (string, *Flow, error) {
+	config, _, err := p.discover(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	state, err := randomString()
+	if err != nil {
+		return "", nil, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", nil, err
+	}
+	flow := &Flow{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}
+	url := config.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(flow.Verifier))
+	return url, flow, nil
+}
+
+// Finish exchanges the code of the callback and verifies the ID token
+// against the keys of the provider.
+func (p *Provider) Finish(ctx context.Context, flow *Flow, state string, code string) (*Identity, error) {
+	if flow == nil || state == "" || state != flow.State {
+		return nil, errors.New("invalid login state, please retry")
+	}
+	config, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange the authorization code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("the provider returned no ID token")
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if idToken.Nonce != flow.Nonce {
+		return nil, errors.New("invalid ID token nonce")
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token claims: %w", err)
+	}
+	identity := &Identity{
+		Issuer:   idToken.Issuer,
+		Subject:  idToken.Subject,
+		Username: stringClaim(claims, "preferred_username"),
+		Groups:   stringsClaim(claims, p.config.GroupsClaim),
+	}
+	// An unverified email is anyone's, it never names an account
+	if verified, _ := claims["email_verified"].(bool); verified {
+		identity.Email = stringClaim(claims, "email")
+	}
+	if identity.Username == "" {
+		identity.Username = identity.Email
+	}
+	if identity.Username == "" {
+		identity.Username = identity.Subject
+	}
+	return identity, nil
+}
+
+func stringClaim(claims map[string]any, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// stringsClaim reads a list of strings, or a single string, claim.
+func stringsClaim(claims map[string]any, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,269 @@
+package oidc
+
+import (
+	"axolotl-cloud/infra/shared"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// testProvider is an OpenID Connect provider issuing a single code, whose ID
+// token claims and signing key can be tampered with.
+type testProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu        sync.Mutex
+	challenge string
+	nonce     string
+	claims    func(claims map[string]any)
+	signer    *rsa.PrivateKey
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, 200, map[string]any{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/authorize",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, 200, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"kid": "test",
+			"n":   encode(key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// authorize plays the part of the browser at the provider: it records the
+// PKCE challenge and nonce of the authorization URL.
+func (p *testProvider) authorize(t *testing.T, authURL string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization URL without S256 PKCE challenge: %s", authURL)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.challenge = query.Get("code_challenge")
+	p.nonce = query.Get("nonce")
+}
+
+func (p *testProvider) token(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("code") != "code" {
+		writeJSON(w, 400, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if encode(challenge[:]) != p.challenge {
+		writeJSON(w, 400, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss":                p.URL,
+		"sub":                "42",
+		"aud":                "axolotl",
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+		"nonce":              p.nonce,
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"groups":             []string{"devs"},
+	}
+	if p.claims != nil {
+		p.claims(claims)
+	}
+	signer := p.key
+	if p.signer != nil {
+		signer = p.signer
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": "test"})
+	payload, _ := json.Marshal(claims)
+	signed := encode(header) + "." + encode(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, signer, crypto.SHA256, digest[:])
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, 200, map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"id_token":     signed + "." + encode(signature),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestProviderFinish(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// flow tampers with the flow kept by the browser and the state of the
+		// callback
+		flow   func(flow *Flow, state *string)
+		claims func(claims map[string]any)
+		signer *rsa.PrivateKey
+		want   *Identity
+		// wantErr is part of the expected error
+		wantErr string
+	}{
+		{
+			name: "valid",
+			want: &Identity{Subject: "42", Username: "alice", Email: "alice@example.com", Groups: []string{"devs"}},
+		},
+		{
+			name:    "state mismatch",
+			flow:    func(flow *Flow, state *string) { *state = "forged" },
+			wantErr: "invalid login state",
+		},
+		{
+			name:    "no state",
+			flow:    func(flow *Flow, state *string) { *state = ""; flow.State = "" },
+			wantErr: "invalid login state",
+		},
+		{
+			name:    "PKCE verifier mismatch",
+			flow:    func(flow *Flow, state *string) { flow.Verifier = oauth2.GenerateVerifier() },
+			wantErr: "failed to exchange",
+		},
+		{
+			name:    "signed by another key",
+			signer:  otherKey,
+			wantErr: "invalid ID token",
+		},
+		{
+			name:    "other audience",
+			claims:  func(claims map[string]any) { claims["aud"] = "other-client" },
+			wantErr: "invalid ID token",
+		},
+		{
+			name:    "other issuer",
+			claims:  func(claims map[string]any) { claims["iss"] = "https://evil.example" },
+			wantErr: "invalid ID token",
+		},
+		{
+			name: "expired",
+			claims: func(claims map[string]any) {
+				claims["iat"] = time.Now().Add(-2 * time.Hour).Unix()
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			},
+			wantErr: "invalid ID token",
+		},
+		{
+			name:    "nonce mismatch",
+			claims:  func(claims map[string]any) { claims["nonce"] = "replayed" },
+			wantErr: "invalid ID token nonce",
+		},
+		{
+			name:    "nonce of another flow",
+			flow:    func(flow *Flow, state *string) { flow.Nonce = "other" },
+			wantErr: "invalid ID token nonce",
+		},
+		{
+			name:   "verified email as username",
+			claims: func(claims map[string]any) { delete(claims, "preferred_username") },
+			want:   &Identity{Subject: "42", Username: "alice@example.com", Email: "alice@example.com", Groups: []string{"devs"}},
+		},
+		{
+			name: "unverified email ignored",
+			claims: func(claims map[string]any) {
+				delete(claims, "preferred_username")
+				claims["email"] = "admin@example.com"
+				claims["email_verified"] = false
+			},
+			want: &Identity{Subject: "42", Username: "42", Groups: []string{"devs"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newTestProvider(t)
+			server.claims = test.claims
+			server.signer = test.signer
+			provider := NewProvider(shared.OIDC{
+				Issuer:      server.URL,
+				ClientID:    "axolotl",
+				RedirectURL: "http://axolotl.example/api/v1/auth/oidc/callback",
+				GroupsClaim: "groups",
+			})
+			ctx := context.Background()
+
+			authURL, flow, err := provider.Start(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server.authorize(t, authURL)
+			state := flow.State
+			if test.flow != nil {
+				test.flow(flow, &state)
+			}
+
+			identity, err := provider.Finish(ctx, flow, state, "code")
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("Finish() = %v, want an error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Finish() = %v", err)
+			}
+			if identity.Issuer != server.URL || identity.Subject != test.want.Subject ||
+				identity.Username != test.want.Username || identity.Email != test.want.Email ||
+				!slices.Equal(identity.Groups, test.want.Groups) {
+				t.Errorf("Finish() = %+v, want %+v", identity, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Version is the version of the OpenAPI specification the documents follow.
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Servers    []Server                         `json:"servers,omitempty"`
+	Security   []SecurityRequirement            `json:"security,omitempty"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+
+	// Errors is the schema of the error responses, the default response of
+	// every operation when set.
+	Errors *Schema `json:"-"`
+
+	// types maps the component names to their Go type, to tell homonyms apart
+	types     map[string]reflect.Type
+	overrides map[reflect.Type]*Schema
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+// SecurityRequirement maps security scheme names to their scopes.
+type SecurityRequirement map[string][]string
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type Operation struct {
+	Tags        []string               `json:"tags,omitempty"`
+	Summary     string                 `json:"summary,omitempty"`
+	OperationID string                 `json:"operationId"`
+	Parameters  []Parameter            `json:"parameters,omitempty"`
+	RequestBody *RequestBody           `json:"requestBody,omitempty"`
+	Responses   map[string]Response    `json:"responses"`
+	Security    *[]SecurityRequirement `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Route describes an API route to add to a document.
+type Route struct {
+	Method string
+	// Path uses the gin syntax, e.g. /projects/:id
+	Path    string
+	Tag     string
+	Summary string
+	// Public routes do not require a session or an API token.
+	Public bool
+	// Query is a struct whose form tags are the query parameters.
+	Query any
+	// Body and Response are values of the request and response types, or
+	// *Schema. A nil Response means no content.
+	Body         any
+	BodyType     string // defaults to application/json
+	Status       int    // defaults to 200
+	Response     any
+	ResponseType string // defaults to application/json
+}
+
+// NewDocument returns an empty document, whose Go types marshaled to JSON by
+// their own methods are described by overrides.
+func NewDocument(info Info, overrides map[reflect.Type]*Schema) *Document {
+	return &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      make(map[string]map[string]*Operation),
+		Components: Components{Schemas: make(map[string]*Schema)},
+		types:      make(map[string]reflect.Type),
+		overrides:  overrides,
+	}
+}
+
+// Add describes the routes in the document.
+func (d *Document) Add(routes ...Route) {
+	for _, route := range routes {
+		path, parameters := pathParameters(route.Path)
+		operation := &Operation{
+			Summary:     route.Summary,
+			OperationID: operationID(route.Method, route.Path),
+			Parameters:  append(parameters, d.queryParameters(route.Query)...),
+			Responses:   make(map[string]Response),
+		}
+		if route.Tag != "" {
+			operation.Tags = []string{route.Tag}
+		}
+		if route.Public {
+			operation.Security = &[]SecurityRequirement{}
+		}
+		if route.Body != nil {
+			operation.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{contentType(route.BodyType): {Schema: d.schema(route.Body)}},
+			}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		response := Response{Description: http.StatusText(status)}
+		if route.Response != nil {
+			response.Content = map[string]MediaType{contentType(route.ResponseType): {Schema: d.schema(route.Response)}}
+		}
+		operation.Responses[strconv.Itoa(status)] = response
+		if d.Errors != nil {
+			operation.Responses["default"] = Response{
+				Description: "Error",
+				Content:     map[string]MediaType{"application/json": {Schema: d.Errors}},
+			}
+		}
+
+		if d.Paths[path] == nil {
+			d.Paths[path] = make(map[string]*Operation)
+		}
+		d.Paths[path][strings.ToLower(route.Method)] = operation
+	}
+}
+
+// Has reports whether the route of a gin method and path is described.
+func (d *Document) Has(method, path string) bool {
+	path, _ = pathParameters(path)
+	_, ok := d.Paths[path][strings.ToLower(method)]
+	return ok
+}
+
+func (d *Document) schema(value any) *Schema {
+	if schema, ok := value.(*Schema); ok {
+		return schema
+	}
+	return d.SchemaOf(reflect.TypeOf(value))
+}
+
+// pathParameters converts a gin path to an OpenAPI one, and returns its
+// parameters. Parameters named id or ending with Id are integers.
+func pathParameters(path string) (string, []Parameter) {
+	var parameters []Parameter
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		name, ok := strings.CutPrefix(segment, ":")
+		if !ok {
+			continue
+		}
+		schema := String()
+		if name == "id" || name == "number" || strings.HasSuffix(name, "Id") {
+			schema = Integer()
+		}
+		parameters = append(parameters, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+		segments[i] = "{" + name + "}"
+	}
+	return strings.Join(segments, "/"), parameters
+}
+
+// queryParameters lists the form fields of the query struct.
+func (d *Document) queryParameters(query any) []Parameter {
+	if query == nil {
+		return nil
+	}
+	var parameters []Parameter
+	t := reflect.TypeOf(query)
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name := field.Tag.Get("form")
+		if name == "" || name == "-" {
+			continue
+		}
+		schema := d.SchemaOf(field.Type)
+		schema.Nullable = false
+		parameters = append(parameters, Parameter{
+			Name:     name,
+			In:       "query",
+			Required: hasRule(field.Tag.Get("binding"), "required"),
+			Schema:   schema,
+		})
+	}
+	return parameters
+}
+
+// operationID derives a unique ID from the route, e.g. get_projects_id.
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for segment := range strings.SplitSeq(path, "/") {
+		if segment = strings.TrimPrefix(segment, ":"); segment != "" {
+			id += "_" + segment
+		}
+	}
+	return id
+}
+
+func contentType(value string) string {
+	if value == "" {
+		return "application/json"
+	}
+	return value
+}
@@ -0,0 +1,173 @@
+package openapi
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+func String() *Schema  { return &Schema{Type: "string"} }
+func Integer() *Schema { return &Schema{Type: "integer"} }
+func Boolean() *Schema { return &Schema{Type: "boolean"} }
+
+func Array(items *Schema) *Schema { return &Schema{Type: "array", Items: items} }
+
+// Object returns an object schema with the given properties, all required.
+func Object(properties map[string]*Schema) *Schema {
+	schema := &Schema{Type: "object", Properties: properties}
+	for name := range properties {
+		schema.Required = append(schema.Required, name)
+	}
+	slices.Sort(schema.Required)
+	return schema
+}
+
+// Ref references a schema of the components.
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaOf describes a Go type as marshaled by encoding/json. Named structs
+// are added to the components and referenced.
+func (d *Document) SchemaOf(t reflect.Type) *Schema {
+	if override, ok := d.overrides[t]; ok {
+		schema := *override
+		return &schema
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := d.SchemaOf(t.Elem())
+		if schema.Ref != "" {
+			// nullable is ignored next to $ref
+			return &Schema{AllOf: []*Schema{schema}, Nullable: true}
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Integer()
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return String()
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return Array(d.SchemaOf(t.Elem()))
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.SchemaOf(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return d.component(t)
+	default:
+		// interfaces hold any value
+		return &Schema{}
+	}
+}
+
+// component adds the schema of a named struct to the components, once.
+func (d *Document) component(t reflect.Type) *Schema {
+	name := t.Name()
+	if existing, ok := d.types[name]; ok && existing != t {
+		name = pathBase(t.PkgPath()) + name
+	}
+	if _, ok := d.types[name]; !ok {
+		// registered first, for the types referencing themselves
+		d.types[name] = t
+		d.Components.Schemas[name] = &Schema{}
+		*d.Components.Schemas[name] = *d.structSchema(t)
+	}
+	return Ref(name)
+}
+
+// structSchema describes the JSON fields of a struct, embedded structs being
+// flattened. Fields with a required binding are required.
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := d.structSchema(field.Type)
+			for property, propertySchema := range embedded.Properties {
+				schema.Properties[property] = propertySchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := d.SchemaOf(field.Type)
+		binding := field.Tag.Get("binding")
+		if enum := oneOf(binding); enum != nil {
+			target := property
+			if strings.Contains(binding, "dive") && property.Items != nil {
+				target = property.Items
+			}
+			target.Enum = enum
+		}
+		if hasRule(binding, "required") {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+// hasRule reports whether a binding tag holds the rule.
+func hasRule(binding, rule string) bool {
+	for part := range strings.SplitSeq(binding, ",") {
+		if part == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// oneOf returns the values of the oneof rule of a binding tag.
+func oneOf(binding string) []string {
+	for part := range strings.SplitSeq(binding, ",") {
+		if values, ok := strings.CutPrefix(part, "oneof="); ok {
+			return strings.Fields(values)
+		}
+	}
+	return nil
+}
+
+func pathBase(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// sweepInterval is how often the buckets of idle clients are dropped.
+const sweepInterval = time.Minute
+
+// Limiter keeps a token bucket per client key: a request takes a token, and a
+// token is added back every interval, up to burst.
+type Limiter struct {
+	mu        sync.Mutex
+	interval  time.Duration
+	burst     int
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+func NewLimiter(interval time.Duration, burst int) *Limiter {
+	return &Limiter{
+		interval: interval,
+		burst:    burst,
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket of key. It returns 0 when the request
+// is allowed, or how long to wait for the next token.
+func (l *Limiter) Allow(key string) time.Duration {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Every(l.interval), l.burst)}
+		l.buckets[key] = b
+	}
+	b.seen = now
+
+	reservation := b.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+	}
+	return delay
+}
+
+// sweep drops the buckets that are full again, they are the same as new ones.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	refill := l.interval * time.Duration(l.burst)
+	for key, b := range l.buckets {
+		if now.Sub(b.seen) > refill {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	interval := 50 * time.Millisecond
+	l := NewLimiter(interval, 2)
+
+	for i := range 2 {
+		if wait := l.Allow("1.2.3.4"); wait != 0 {
+			t.Fatalf("request %d: Allow() = %v, want 0 within the burst", i+1, wait)
+		}
+	}
+	wait := l.Allow("1.2.3.4")
+	if wait <= 0 || wait > interval {
+		t.Fatalf("Allow() after the burst = %v, want a wait up to %v", wait, interval)
+	}
+	if got := l.Allow("5.6.7.8"); got != 0 {
+		t.Errorf("Allow() of another key = %v, want 0", got)
+	}
+
+	// A refused request takes no token, the next one comes after the wait
+	time.Sleep(wait)
+	if got := l.Allow("1.2.3.4"); got != 0 {
+		t.Errorf("Allow() after the wait = %v, want 0", got)
+	}
+	if got := l.Allow("1.2.3.4"); got == 0 {
+		t.Errorf("Allow() = 0, want a wait for the token after")
+	}
+
+	// The bucket refills up to the burst only
+	time.Sleep(3 * interval)
+	for i := range 2 {
+		if got := l.Allow("1.2.3.4"); got != 0 {
+			t.Errorf("request %d after the refill: Allow() = %v, want 0", i+1, got)
+		}
+	}
+	if got := l.Allow("1.2.3.4"); got == 0 {
+		t.Errorf("Allow() after the refilled burst = 0, want a wait")
+	}
+}
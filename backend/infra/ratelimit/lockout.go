@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Lockout locks a key out after repeated failures, for a delay doubling with
+// every further failure.
+type Lockout struct {
+	mu       sync.Mutex
+	free     int
+	base     time.Duration
+	max      time.Duration
+	failures map[string]*failure
+	// lastSweep is when the forgotten failures were last dropped
+	lastSweep time.Time
+}
+
+type failure struct {
+	count int
+	until time.Time
+	seen  time.Time
+}
+
+// NewLockout allows free failures, then locks out for base, 2*base... up to
+// max. Failures are forgotten after max without any.
+func NewLockout(free int, base, max time.Duration) *Lockout {
+	return &Lockout{
+		free:     free,
+		base:     base,
+		max:      max,
+		failures: make(map[string]*failure),
+	}
+}
+
+// Remaining returns how long key is still locked out, or 0.
+func (l *Lockout) Remaining(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.failures[key]
+	if !ok {
+		return 0
+	}
+	return max(time.Until(f.until), 0)
+}
+
+// Fail records a failure of key, and returns the lockout it starts, or 0.
+func (l *Lockout) Fail(key string) time.Duration {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	f, ok := l.failures[key]
+	if !ok {
+		f = &failure{}
+		l.failures[key] = f
+	}
+	f.count++
+	f.seen = now
+	if f.count <= l.free {
+		return 0
+	}
+
+	delay := l.base
+	for i := l.free + 1; i < f.count && delay < l.max; i++ {
+		delay *= 2
+	}
+	delay = min(delay, l.max)
+	f.until = now.Add(delay)
+	return delay
+}
+
+// Reset forgets the failures of key, after a success.
+func (l *Lockout) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, key)
+}
+
+func (l *Lockout) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, f := range l.failures {
+		if now.Sub(f.seen) > l.max && now.After(f.until) {
+			delete(l.failures, key)
+		}
+	}
+}
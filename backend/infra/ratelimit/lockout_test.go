@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutFail(t *testing.T) {
+	l := NewLockout(2, time.Minute, 4*time.Minute)
+
+	// The failures of a key in order, with the lockout each one starts
+	want := []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute}
+	for i, delay := range want {
+		if got := l.Fail("alice"); got != delay {
+			t.Errorf("failure %d: Fail() = %v, want %v", i+1, got, delay)
+		}
+	}
+	if got := l.Remaining("alice"); got <= 3*time.Minute || got > 4*time.Minute {
+		t.Errorf("Remaining() = %v, want about 4m", got)
+	}
+	if got := l.Remaining("bob"); got != 0 {
+		t.Errorf("Remaining() of another key = %v, want 0", got)
+	}
+
+	l.Reset("alice")
+	if got := l.Remaining("alice"); got != 0 {
+		t.Errorf("Remaining() after Reset() = %v, want 0", got)
+	}
+	if got := l.Fail("alice"); got != 0 {
+		t.Errorf("Fail() after Reset() = %v, want 0", got)
+	}
+}
+
+func TestLockoutRemaining(t *testing.T) {
+	l := NewLockout(0, 20*time.Millisecond, time.Second)
+	if got := l.Fail("alice"); got != 20*time.Millisecond {
+		t.Fatalf("Fail() = %v, want 20ms", got)
+	}
+	if got := l.Remaining("alice"); got == 0 {
+		t.Errorf("Remaining() = 0, want a lockout")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got := l.Remaining("alice"); got != 0 {
+		t.Errorf("Remaining() after the lockout = %v, want 0", got)
+	}
+}
@@ -0,0 +1,40 @@
+package secrets
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RefPrefix marks an environment value referencing a secret, e.g.
+// secret://db_password.
+const RefPrefix = "secret://"
+
+// Mask replaces secret values in API responses and logs.
+const Mask = "******"
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ValidName reports whether name can be used as a secret name.
+func ValidName(name string) bool {
+	return len(name) <= 128 && namePattern.MatchString(name)
+}
+
+// Ref returns the name of the secret referenced by an environment value.
+func Ref(value string) (string, bool) {
+	if !strings.HasPrefix(value, RefPrefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(value, RefPrefix)
+	return name, ValidName(name)
+}
+
+// Refs returns the names of the secrets referenced by env.
+func Refs(env map[string]string) []string {
+	var names []string
+	for _, value := range env {
+		if name, ok := Ref(value); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
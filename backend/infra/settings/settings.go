@@ -5,4 +5,17 @@ import "axolotl-cloud/internal/app/model"
 const (
 	JobTimeout model.SettingKey = "job_timeout"
 	Language   model.SettingKey = "language"
+	// BuildRetention is the number of builds (and images) kept per container.
+	BuildRetention model.SettingKey = "build_retention"
+	// TrashPurgeDelay is the number of hours deleted projects and containers
+	// stay in the trash before being purged.
+	TrashPurgeDelay model.SettingKey = "trash_purge_delay"
+	// SessionLifetime is the number of hours a login session lasts.
+	SessionLifetime model.SettingKey = "session_lifetime"
+	// TwoFactorRequired ("true" or "false") makes users with a password set
+	// up TOTP before using anything but their account.
+	TwoFactorRequired model.SettingKey = "two_factor_required"
+	// AllowedOrigins is a comma separated list of the browser origins allowed
+	// to call the API, on top of CORS_ALLOWED_ORIGINS.
+	AllowedOrigins model.SettingKey = "allowed_origins"
 )
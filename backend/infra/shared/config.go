@@ -1,8 +1,12 @@
 package shared
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -12,8 +16,47 @@ var expectedEnvVars = []string{
 	"VOLUMES_PATH_HOST",
 	"VOLUMES_PATH_CONTAINER",
 	"DATABASE_PATH",
+	"SECRETS_MASTER_KEY",
 }
 
+// masterKeySize is the size of the decoded SECRETS_MASTER_KEY (AES-256).
+const masterKeySize = 32
+
+var masterKey []byte
+
+// OIDC configures the single sign-on, disabled when Issuer is empty.
+type OIDC struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string // empty for a public client, PKCE protects the code
+	RedirectURL  string // <public URL>/api/v1/auth/oidc/callback
+	GroupsClaim  string // ID token claim holding the groups of the user
+}
+
+var oidcConfig OIDC
+
+// Network configures how the server is reached: the origins allowed to call
+// the API from a browser, the reverse proxies trusted for the client IP, and
+// HTTPS.
+type Network struct {
+	AllowedOrigins []string // on top of the web UI itself
+	TrustedProxies []string // IPs or CIDRs, none by default
+	RealIPHeader   string   // header holding the client IP set by the proxies
+	TLSCertFile    string
+	TLSKeyFile     string
+	// TLSSelfSigned serves HTTPS with a generated certificate valid for
+	// localhost, the hostname and TLSHosts.
+	TLSSelfSigned bool
+	TLSHosts      []string
+}
+
+// TLS reports whether the server serves HTTPS itself.
+func (n Network) TLS() bool {
+	return n.TLSCertFile != "" || n.TLSSelfSigned
+}
+
+var networkConfig Network
+
 func LoadEnv() error {
 	if os.Getenv("ENV") != "production" {
 		err := godotenv.Load()
@@ -27,9 +70,86 @@ func LoadEnv() error {
 			return fmt.Errorf("environment variable %s is not set", envVar)
 		}
 	}
+
+	key, err := base64.StdEncoding.DecodeString(os.Getenv("SECRETS_MASTER_KEY"))
+	if err != nil || len(key) != masterKeySize {
+		return fmt.Errorf("SECRETS_MASTER_KEY must be %d random bytes encoded in base64 (openssl rand -base64 %d)", masterKeySize, masterKeySize)
+	}
+	masterKey = key
+
+	oidcConfig = OIDC{
+		Issuer:       os.Getenv("OIDC_ISSUER"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		GroupsClaim:  os.Getenv("OIDC_GROUPS_CLAIM"),
+	}
+	if oidcConfig.GroupsClaim == "" {
+		oidcConfig.GroupsClaim = "groups"
+	}
+	if oidcConfig.Issuer != "" && (oidcConfig.ClientID == "" || oidcConfig.RedirectURL == "") {
+		return fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL must be set along OIDC_ISSUER")
+	}
+
+	networkConfig = Network{
+		AllowedOrigins: splitList(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		TrustedProxies: splitList(os.Getenv("TRUSTED_PROXIES")),
+		RealIPHeader:   os.Getenv("REAL_IP_HEADER"),
+		TLSCertFile:    os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:     os.Getenv("TLS_KEY_FILE"),
+		TLSSelfSigned:  os.Getenv("TLS_SELF_SIGNED") == "true",
+		TLSHosts:       splitList(os.Getenv("TLS_HOSTS")),
+	}
+	if os.Getenv("CORS_ALLOWED_ORIGINS") == "" && os.Getenv("ENV") != "production" {
+		// the Vite dev server
+		networkConfig.AllowedOrigins = []string{"http://localhost:5173"}
+	}
+	if slices.Contains(networkConfig.AllowedOrigins, "*") {
+		return fmt.Errorf("CORS_ALLOWED_ORIGINS cannot be *, the API is called with credentials: list the allowed origins")
+	}
+	if networkConfig.RealIPHeader == "" {
+		networkConfig.RealIPHeader = "X-Forwarded-For"
+	}
+	if (networkConfig.TLSCertFile == "") != (networkConfig.TLSKeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if networkConfig.TLSCertFile != "" && networkConfig.TLSSelfSigned {
+		return fmt.Errorf("TLS_SELF_SIGNED cannot be used along TLS_CERT_FILE")
+	}
+	for _, proxy := range networkConfig.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("TRUSTED_PROXIES: %s is neither an IP nor a CIDR", proxy)
+		}
+	}
 	return nil
 }
 
+// splitList splits a comma separated environment variable.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// NetworkConfig returns the CORS, proxies and TLS configuration.
+func NetworkConfig() Network {
+	return networkConfig
+}
+
+// OIDCConfig returns the single sign-on configuration.
+func OIDCConfig() OIDC {
+	return oidcConfig
+}
+
+// MasterKey returns the key encrypting the secrets store.
+func MasterKey() []byte {
+	return masterKey
+}
+
 func GetEnv(key string) string {
 	return os.Getenv(key)
 }
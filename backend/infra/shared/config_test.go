@@ -0,0 +1,48 @@
+package shared
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// TestLoadEnvAllowedOrigins checks that CORS_ALLOWED_ORIGINS never allows
+// every origin, as the API is called with credentials.
+func TestLoadEnvAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", origins: "", want: nil},
+		{name: "list", origins: "https://a.example, https://b.example", want: []string{"https://a.example", "https://b.example"}},
+		{name: "wildcard", origins: "*", wantErr: true},
+		{name: "wildcard in list", origins: "https://a.example,*", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("ENV", "production")
+			t.Setenv("HTTP_PORT", "8080")
+			t.Setenv("VOLUMES_PATH_HOST", "/volumes")
+			t.Setenv("VOLUMES_PATH_CONTAINER", "/volumes")
+			t.Setenv("DATABASE_PATH", "/data/data.db")
+			t.Setenv("SECRETS_MASTER_KEY", base64.StdEncoding.EncodeToString(make([]byte, masterKeySize)))
+			t.Setenv("CORS_ALLOWED_ORIGINS", test.origins)
+
+			err := LoadEnv()
+			if test.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "CORS_ALLOWED_ORIGINS") {
+					t.Fatalf("LoadEnv() = %v, want a CORS_ALLOWED_ORIGINS error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadEnv() = %v", err)
+			}
+			if got := NetworkConfig().AllowedOrigins; strings.Join(got, ",") != strings.Join(test.want, ",") {
+				t.Errorf("AllowedOrigins = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -4,4 +4,7 @@ type WebSocketConnection interface {
 	Send(data WSMessage[any]) error
 	Close() error
 	Done() <-chan struct{}
+	// CanSubscribe reports whether the connection may receive the messages
+	// of the topic.
+	CanSubscribe(topic string) bool
 }
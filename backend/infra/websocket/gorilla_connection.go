@@ -15,17 +15,22 @@ type GorillaConnection struct {
 	send chan message
 	done chan struct{}
 	once sync.Once
+	// authorize checks subscriptions, every topic is allowed when nil
+	authorize TopicAuthorizer
 }
 
 type message struct {
 	data WSMessage[any]
 }
 
-func NewGorillaConnection(w http.ResponseWriter, r *http.Request) (*GorillaConnection, error) {
-	upgrader := websocket.Upgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
-		CheckOrigin:     func(r *http.Request) bool { return true },
+// NewGorillaConnection upgrades the request with upgrader. Without upgrader,
+// only same-origin upgrades are accepted.
+func NewGorillaConnection(w http.ResponseWriter, r *http.Request, upgrader *websocket.Upgrader) (*GorillaConnection, error) {
+	if upgrader == nil {
+		upgrader = &websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		}
 	}
 
 	ws, err := upgrader.Upgrade(w, r, nil)
@@ -66,6 +71,10 @@ func (c *GorillaConnection) Done() <-chan struct{} {
 	return c.done
 }
 
+func (c *GorillaConnection) CanSubscribe(topic string) bool {
+	return c.authorize == nil || c.authorize(topic)
+}
+
 func (c *GorillaConnection) writePump() {
 	defer c.Close()
 
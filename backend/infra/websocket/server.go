@@ -9,18 +9,33 @@ import (
 )
 
 type WebSocketServer struct {
+	// Upgrader checks the origin of the upgrade requests, only same-origin
+	// ones are accepted when nil.
 	Upgrader     *websocket.Upgrader
 	OnConnect    func(conn WebSocketConnection)
 	OnMessage    func(conn WebSocketConnection, data WSMessage[any])
 	OnDisconnect func(conn WebSocketConnection, err error)
 }
 
-func (s *WebSocketServer) HandleHTTP(w http.ResponseWriter, r *http.Request) {
-	conn, err := NewGorillaConnection(w, r)
+// NewUpgrader returns an upgrader accepting the requests allowed by
+// checkOrigin.
+func NewUpgrader(checkOrigin func(r *http.Request) bool) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     checkOrigin,
+	}
+}
+
+// HandleHTTP upgrades the request to a WebSocket connection, whose topic
+// subscriptions are checked by authorize.
+func (s *WebSocketServer) HandleHTTP(w http.ResponseWriter, r *http.Request, authorize TopicAuthorizer) {
+	conn, err := NewGorillaConnection(w, r, s.Upgrader)
 	if err != nil {
 		http.Error(w, "WebSocket upgrade failed", http.StatusBadRequest)
 		return
 	}
+	conn.authorize = authorize
 
 	// Notify new connection
 	if s.OnConnect != nil {
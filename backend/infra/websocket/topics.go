@@ -7,6 +7,11 @@ type JobLogPayload struct {
 	Log   model.JobLog `json:"log"`
 }
 
+type JobProgressPayload struct {
+	JobID uint              `json:"job_id"`
+	Steps []model.BuildStep `json:"steps"`
+}
+
 type ContainerStatusPayload struct {
 	ContainerID string `json:"container_id"`
 	Status      string `json:"status"`
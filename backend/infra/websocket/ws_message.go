@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"fmt"
 	"sync"
 )
 
@@ -10,8 +11,13 @@ const (
 	SubscribeMessageType    WSMessageType = "subscribe"
 	UnsubscribeMessageType  WSMessageType = "unsubscribe"
 	JobLogUpdateMessageType WSMessageType = "job_log_update"
+	JobProgressMessageType  WSMessageType = "job_progress"
+	ErrorMessageType        WSMessageType = "error"
 )
 
+// TopicAuthorizer reports whether a connection may subscribe to a topic.
+type TopicAuthorizer func(topic string) bool
+
 type WSMessage[T any] struct {
 	Type WSMessageType `json:"type"`
 	Data T             `json:"data"`
@@ -45,6 +51,14 @@ func (h *WSMessageHandler) HandleMessage(message WSMessage[any]) {
 }
 
 func (h *WSMessageHandler) Subscribe(topicName string) {
+	if !h.conn.CanSubscribe(topicName) {
+		h.conn.Send(WSMessage[any]{
+			Type: ErrorMessageType,
+			Data: fmt.Sprintf("Not allowed to subscribe to %s", topicName),
+		})
+		return
+	}
+
 	topicsMutex.Lock()
 	defer topicsMutex.Unlock()
 
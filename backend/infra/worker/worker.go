@@ -55,7 +55,16 @@ func (w *Worker) Start(ctx context.Context) {
 	}()
 }
 
+type jobIDKey struct{}
+
+// JobID returns the ID of the job running with ctx, 0 outside of a job.
+func JobID(ctx context.Context) uint {
+	id, _ := ctx.Value(jobIDKey{}).(uint)
+	return id
+}
+
 func RunJob(ctx context.Context, j *model.Job, repo *repository.JobRepository) {
+	ctx = context.WithValue(ctx, jobIDKey{}, j.ID)
 	repo.UpdateStatus(j.ID, model.JobStatusRunning)
 	repo.AddLog(j.ID, fmt.Sprintf("[INFO] Starting job: %s", j.Name))
 	topicName := fmt.Sprintf("job:%d", j.ID)
@@ -75,6 +84,18 @@ func RunJob(ctx context.Context, j *model.Job, repo *repository.JobRepository) {
 				Log:   *log,
 			},
 		})
+	}).WithProgress(func(data any) {
+		steps, ok := data.([]model.BuildStep)
+		if !ok {
+			return
+		}
+		websocket.SendMessageToTopic(topicName, websocket.WSMessage[websocket.JobProgressPayload]{
+			Type: websocket.JobProgressMessageType,
+			Data: websocket.JobProgressPayload{
+				JobID: j.ID,
+				Steps: steps,
+			},
+		})
 	})
 
 	if err := j.Run(ctx, jobLogger); err != nil {
@@ -0,0 +1,28 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+)
+
+// Prepare returns an empty temporary directory in which the sources of a
+// build (git clone, uploaded archive, ...) are placed. Any previous content is
+// removed. Builds use a directory of their own, removed with Remove once
+// they are over.
+func Prepare(name string) (string, error) {
+	dir := fmt.Sprintf("./tmp/%s", name)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		if err := os.RemoveAll(dir); err != nil {
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// Remove deletes a directory returned by Prepare.
+func Remove(dir string) error {
+	return os.RemoveAll(dir)
+}
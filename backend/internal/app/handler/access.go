@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/infra/websocket"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/internal/app/repository"
+	"axolotl-cloud/utils"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Access checks the permissions of requests on projects. A user has the role
+// of its project membership, global admins act as owners of every project. A
+// service token has the role matching its scopes on every project, or only on
+// its project when restricted.
+type Access struct {
+	MemberRepository    *repository.ProjectMemberRepository
+	ContainerRepository *repository.ContainerRepository
+}
+
+// principal is who a request acts as. It is kept apart from the gin context
+// for WebSocket connections, which outlive their upgrade request.
+type principal struct {
+	user  *model.User
+	token *model.APIToken
+}
+
+func principalOf(c *gin.Context) principal {
+	return principal{user: CurrentUser(c), token: CurrentToken(c)}
+}
+
+// admin reports whether the principal is a global admin: an admin user, or an
+// unrestricted service token with the admin scope.
+func (p principal) admin() bool {
+	if p.token != nil && p.token.ProjectID != nil {
+		return false
+	}
+	if p.user != nil {
+		return p.user.Admin
+	}
+	return p.token != nil && p.token.HasScope(model.ScopeAdmin)
+}
+
+// role returns the role of the principal on the project, "" for none.
+func (a *Access) role(ctx context.Context, p principal, projectID uint) (model.ProjectRole, error) {
+	if p.token != nil && p.token.ProjectID != nil && *p.token.ProjectID != projectID {
+		return "", nil
+	}
+	if p.user != nil {
+		if p.user.Admin {
+			return model.RoleOwner, nil
+		}
+		return a.MemberRepository.FindRole(ctx, projectID, p.user.ID)
+	}
+	if p.token != nil {
+		switch {
+		case p.token.HasScope(model.ScopeAdmin):
+			return model.RoleOwner, nil
+		case p.token.HasScope(model.ScopeDeploy):
+			return model.RoleDeployer, nil
+		case p.token.HasScope(model.ScopeRead):
+			return model.RoleViewer, nil
+		}
+	}
+	return "", nil
+}
+
+// RequireRole returns a middleware aborting unless the request has at least
+// role on the project of the :id route parameter. Projects the request cannot
+// read are reported as not found.
+func (a *Access) RequireRole(role model.ProjectRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		projectID, exists := utils.ParamUInt(c, "id")
+		if !exists {
+			respondError(c, 400, "Invalid project ID")
+			return
+		}
+
+		actual, err := a.role(c.Request.Context(), principalOf(c), projectID)
+		if err != nil {
+			respondError(c, 500, "Failed to check permissions")
+			return
+		}
+		if !actual.Includes(model.RoleViewer) {
+			respondError(c, 404, "Project not found")
+			return
+		}
+		if !actual.Includes(role) {
+			respondError(c, 403, fmt.Sprintf("This requires the %s role on the project", role))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin aborts with a 403 unless the request is made by a global
+// admin.
+func RequireAdmin(c *gin.Context) {
+	if !principalOf(c).admin() {
+		respondError(c, 403, "Admin access required")
+		return
+	}
+	c.Next()
+}
+
+// RequireProjectCreator aborts with a 403 unless the request is made by a
+// global admin or a user allowed to create projects.
+func RequireProjectCreator(c *gin.Context) {
+	p := principalOf(c)
+	creator := p.user != nil && p.user.ProjectCreator && (p.token == nil || p.token.ProjectID == nil)
+	if !p.admin() && !creator {
+		respondError(c, 403, "Only admins and project creators can create projects")
+		return
+	}
+	c.Next()
+}
+
+// allowHostAccess aborts with a 403 when a request other than an admin's
+// gives a container access to the host, see hostAccess. before is the
+// container being replaced, nil for a new one.
+func allowHostAccess(c *gin.Context, container *model.Container, before *model.Container) bool {
+	if principalOf(c).admin() {
+		return true
+	}
+	if access := hostAccess(container, before); access != "" {
+		respondError(c, 403, fmt.Sprintf("Only admins can give container %s access to %s", container.Name, access))
+		return false
+	}
+	return true
+}
+
+// hostAccess returns what gives the container access to the host, "" for
+// nothing: the host network, or a volume outside of the volumes directory.
+// The ones before already had, granted by an admin, are left out.
+func hostAccess(container *model.Container, before *model.Container) string {
+	if container.NetworkMode == "host" && (before == nil || before.NetworkMode != "host") {
+		return "the host network"
+	}
+	for hostPath := range container.Volumes {
+		if !utils.IsAbsolutePath(hostPath) && filepath.IsLocal(hostPath) {
+			continue
+		}
+		if before != nil {
+			if _, ok := before.Volumes[hostPath]; ok {
+				continue
+			}
+		}
+		return fmt.Sprintf("host path %s", hostPath)
+	}
+	return ""
+}
+
+// HasRole reports whether the request has at least role on the project.
+func (a *Access) HasRole(c *gin.Context, projectID uint, role model.ProjectRole) bool {
+	actual, err := a.role(c.Request.Context(), principalOf(c), projectID)
+	if err != nil {
+		logger.Error("Failed to check permissions", err)
+		return false
+	}
+	return actual.Includes(role)
+}
+
+// ReadableProjects returns the IDs of the projects the request can read, or
+// all set when it can read every project.
+func (a *Access) ReadableProjects(c *gin.Context) (ids []uint, all bool, err error) {
+	p := principalOf(c)
+	if p.token != nil && p.token.ProjectID != nil {
+		return []uint{*p.token.ProjectID}, false, nil
+	}
+	if p.admin() || (p.user == nil && p.token != nil) {
+		return nil, true, nil
+	}
+	if p.user == nil {
+		return nil, false, nil
+	}
+	ids, err = a.MemberRepository.FindProjectIDs(c.Request.Context(), p.user.ID)
+	return ids, false, err
+}
+
+// JobFilter returns a check of the jobs the request can read: jobs of a
+// project, or of a container of a project, need read access to the project,
+// the other ones are for admins. Roles are looked up once per project.
+func (a *Access) JobFilter(c *gin.Context) func(job *model.Job) bool {
+	return a.jobFilter(c.Request.Context(), principalOf(c))
+}
+
+func (a *Access) jobFilter(ctx context.Context, p principal) func(job *model.Job) bool {
+	projects := map[uint]uint{}
+	readable := map[uint]bool{}
+	return func(job *model.Job) bool {
+		var projectID uint
+		switch {
+		case job.ProjectID != nil:
+			projectID = *job.ProjectID
+		case job.ContainerID != nil:
+			// jobs created before jobs recorded their project
+			var ok bool
+			projectID, ok = projects[*job.ContainerID]
+			if !ok {
+				container, err := a.ContainerRepository.FindByID(ctx, *job.ContainerID)
+				if err != nil {
+					return p.admin()
+				}
+				projectID = container.ProjectID
+				projects[*job.ContainerID] = projectID
+			}
+		default:
+			return p.admin()
+		}
+		allowed, ok := readable[projectID]
+		if !ok {
+			role, err := a.role(ctx, p, projectID)
+			if err != nil {
+				logger.Error("Failed to check permissions", err)
+			}
+			allowed = role.Includes(model.RoleViewer)
+			readable[projectID] = allowed
+		}
+		return allowed
+	}
+}
+
+// TopicAuthorizer returns the check of the WebSocket subscriptions of the
+// upgrade request: job topics need read access to the job.
+func (a *Access) TopicAuthorizer(c *gin.Context, jobs *repository.JobRepository) websocket.TopicAuthorizer {
+	p := principalOf(c)
+	return func(topic string) bool {
+		raw, ok := strings.CutPrefix(topic, "job:")
+		if !ok {
+			return false
+		}
+		jobID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return false
+		}
+		job, err := jobs.GetByID(uint(jobID))
+		if err != nil {
+			return false
+		}
+		return a.jobFilter(context.Background(), p)(job)
+	}
+}
+
+// filterProjects keeps the projects among ids, unless all is set.
+func filterProjects(projects []model.Project, ids []uint, all bool) []model.Project {
+	if all {
+		return projects
+	}
+	return slices.DeleteFunc(projects, func(project model.Project) bool {
+		return !slices.Contains(ids, project.ID)
+	})
+}
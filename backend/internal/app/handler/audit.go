@@ -0,0 +1,328 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/internal/app/repository"
+	"axolotl-cloud/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	auditTargetKey  = "audit_target"
+	auditProjectKey = "audit_project"
+	auditChangesKey = "audit_changes"
+	auditJobKey     = "audit_job"
+)
+
+// auditRoute names the action of a mutating route, and its target: the type,
+// and the route parameter holding its ID. Handlers set the ID of the targets
+// they create with auditTarget.
+type auditRoute struct {
+	action string
+	target string
+	param  string
+}
+
+// auditRoutes are keyed by method and route, without APIPrefix. Routes
+// missing here are recorded with their method and route as action.
+var auditRoutes = map[string]auditRoute{
+	"POST /auth/setup":                                             {"auth.setup", "user", ""},
+	"POST /auth/login":                                             {"auth.login", "user", ""},
+	"POST /auth/login/verify":                                      {"auth.login_verify", "user", ""},
+	"POST /auth/logout":                                            {"auth.logout", "user", ""},
+	"PUT /auth/password":                                           {"user.change_password", "user", ""},
+	"DELETE /auth/sessions/:sessionId":                             {"session.revoke", "session", "sessionId"},
+	"POST /auth/2fa/setup":                                         {"user.2fa_setup", "user", ""},
+	"POST /auth/2fa/enable":                                        {"user.2fa_enable", "user", ""},
+	"POST /auth/2fa/disable":                                       {"user.2fa_disable", "user", ""},
+	"POST /auth/2fa/recovery_codes":                                {"user.2fa_recovery_codes", "user", ""},
+	"POST /oidc/mappings":                                          {"oidc_mapping.create", "oidc_mapping", ""},
+	"DELETE /oidc/mappings/:mappingId":                             {"oidc_mapping.delete", "oidc_mapping", "mappingId"},
+	"POST /tokens":                                                 {"token.create", "token", ""},
+	"DELETE /tokens/:tokenId":                                      {"token.delete", "token", "tokenId"},
+	"POST /users":                                                  {"user.create", "user", ""},
+	"PUT /users/:userId":                                           {"user.update", "user", "userId"},
+	"DELETE /users/:userId":                                        {"user.delete", "user", "userId"},
+	"DELETE /users/:userId/2fa":                                    {"user.2fa_reset", "user", "userId"},
+	"POST /projects":                                               {"project.create", "project", ""},
+	"PUT /projects/:id":                                            {"project.update", "project", "id"},
+	"DELETE /projects/:id":                                         {"project.delete", "project", "id"},
+	"PUT /projects/:id/members/:userId":                            {"project.member_set", "user", "userId"},
+	"DELETE /projects/:id/members/:userId":                         {"project.member_remove", "user", "userId"},
+	"POST /projects/:id/containers":                                {"container.create", "container", ""},
+	"PUT /projects/:id/containers/:containerId":                    {"container.update", "container", "containerId"},
+	"DELETE /projects/:id/containers/:containerId":                 {"container.delete", "container", "containerId"},
+	"POST /projects/:id/containers/:containerId/start":             {"container.start", "container", "containerId"},
+	"POST /projects/:id/containers/:containerId/stop":              {"container.stop", "container", "containerId"},
+	"POST /projects/:id/containers/:containerId/rollback/:buildId": {"container.rollback", "container", "containerId"},
+	"POST /projects/:id/containers/:containerId/revisions/:number/restore": {"container.revision_restore", "container", "containerId"},
+	"POST /projects/:id/containers/import":                                 {"project.compose_import", "project", "id"},
+	"POST /projects/:id/containers/reimport":                               {"project.compose_reimport", "project", "id"},
+	"POST /projects/:id/containers/build_from_source":                      {"container.build", "project", "id"},
+	"POST /projects/:id/containers/build_from_archive":                     {"container.build", "project", "id"},
+	"POST /reconcile":                             {"reconcile.run", "container", ""},
+	"DELETE /jobs/:id":                            {"job.delete", "job", "id"},
+	"POST /secrets":                               {"secret.create", "secret", ""},
+	"PUT /secrets/:name":                          {"secret.rotate", "secret", "name"},
+	"DELETE /secrets/:name":                       {"secret.delete", "secret", "name"},
+	"POST /settings":                              {"setting.save", "setting", ""},
+	"DELETE /settings/:key":                       {"setting.delete", "setting", "key"},
+	"POST /trash/projects/:id/restore":            {"project.restore", "project", "id"},
+	"DELETE /trash/projects/:id":                  {"project.purge", "project", "id"},
+	"POST /trash/containers/:containerId/restore": {"container.restore", "container", "containerId"},
+	"DELETE /trash/containers/:containerId":       {"container.purge", "container", "containerId"},
+}
+
+// auditIgnoredFields are left out of the changes: they change on every save.
+var auditIgnoredFields = []string{"created_at", "updated_at", "deleted_at", "containers"}
+
+// auditRedactedFields may hold credentials, only the names of their changed
+// keys are recorded.
+var auditRedactedFields = []string{"env"}
+
+type AuditHandler struct {
+	AuditRepository *repository.AuditEventRepository
+}
+
+type RequestAuditQuery struct {
+	Actor      string    `form:"actor"`
+	Action     string    `form:"action"`
+	TargetType string    `form:"target_type"`
+	TargetID   string    `form:"target_id"`
+	ProjectID  *uint     `form:"project_id"`
+	Since      time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until      time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit      int       `form:"limit" binding:"omitempty,min=1,max=1000"`
+	Offset     int       `form:"offset" binding:"omitempty,min=0"`
+}
+
+type AuditResponse struct {
+	Events []model.AuditEvent `json:"events"`
+	// Total is the number of events matching the filters.
+	Total int64 `json:"total"`
+}
+
+// AuditTrail returns a middleware recording an AuditEvent for every mutating
+// API request once it has been answered, whatever its outcome.
+func AuditTrail(events *repository.AuditEventRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		method := c.Request.Method
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+			return
+		}
+		path := c.FullPath()
+		if path == "" || !strings.HasPrefix(path, APIPrefix+"/") {
+			return
+		}
+
+		route, ok := auditRoutes[method+" "+strings.TrimPrefix(path, APIPrefix)]
+		if !ok {
+			route = auditRoute{action: strings.ToLower(method) + " " + path}
+		}
+		event := &model.AuditEvent{
+			IP:         c.ClientIP(),
+			Action:     route.action,
+			TargetType: route.target,
+			Method:     method,
+			Path:       c.Request.URL.Path,
+			Status:     c.Writer.Status(),
+		}
+
+		user, token := CurrentUser(c), CurrentToken(c)
+		if user != nil {
+			event.Actor = user.Username
+			event.UserID = &user.ID
+		}
+		if token != nil {
+			event.TokenID = &token.ID
+			if user == nil {
+				event.Actor = token.Name
+			}
+		}
+
+		if target, ok := c.Get(auditTargetKey); ok {
+			event.TargetID = fmt.Sprint(target)
+		} else if route.param != "" {
+			event.TargetID = c.Param(route.param)
+		} else if route.target == "user" && user != nil {
+			event.TargetID = strconv.FormatUint(uint64(user.ID), 10)
+		}
+		if projectID, ok := c.Get(auditProjectKey); ok {
+			id := projectID.(uint)
+			event.ProjectID = &id
+		} else if strings.HasPrefix(path, APIPrefix+"/projects/:id") || strings.HasPrefix(path, APIPrefix+"/trash/projects/:id") {
+			if id, err := strconv.ParseUint(c.Param("id"), 10, 32); err == nil {
+				projectID := uint(id)
+				event.ProjectID = &projectID
+			}
+		}
+		if changes, ok := c.Get(auditChangesKey); ok {
+			event.Changes = changes.(types.JSONMap)
+		}
+		if jobID, ok := c.Get(auditJobKey); ok {
+			id := jobID.(uint)
+			event.JobID = &id
+		}
+
+		if err := events.Create(c.Request.Context(), event); err != nil {
+			logger.Error("Failed to record audit event:", err)
+		}
+	}
+}
+
+// auditTarget sets the ID of the target of the request, for targets created
+// by the request or missing from the route.
+func auditTarget(c *gin.Context, id any) {
+	c.Set(auditTargetKey, id)
+}
+
+// auditProject sets the project of the request, when it is not the :id route
+// parameter.
+func auditProject(c *gin.Context, projectID uint) {
+	c.Set(auditProjectKey, projectID)
+}
+
+// auditJob records the job started by the request.
+func auditJob(c *gin.Context, jobID uint) {
+	c.Set(auditJobKey, jobID)
+}
+
+// auditChanges records the fields changed between before and after, as they
+// are serialized to JSON.
+func auditChanges(c *gin.Context, before any, after any) {
+	changes, err := diffFields(before, after)
+	if err != nil {
+		logger.Error("Failed to compute audit changes:", err)
+		return
+	}
+	if len(changes) > 0 {
+		c.Set(auditChangesKey, changes)
+	}
+}
+
+func diffFields(before any, after any) (types.JSONMap, error) {
+	b, err := toJSONMap(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toJSONMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := types.JSONMap{}
+	for key := range mergeKeys(b, a) {
+		if slices.Contains(auditIgnoredFields, key) || reflect.DeepEqual(b[key], a[key]) {
+			continue
+		}
+		if slices.Contains(auditRedactedFields, key) {
+			changes[key] = map[string]any{"changed_keys": changedKeys(b[key], a[key])}
+			continue
+		}
+		changes[key] = map[string]any{"before": b[key], "after": a[key]}
+	}
+	return changes, nil
+}
+
+func toJSONMap(value any) (map[string]any, error) {
+	m := map[string]any{}
+	if value == nil {
+		return m, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+func mergeKeys(maps ...map[string]any) map[string]struct{} {
+	keys := map[string]struct{}{}
+	for _, m := range maps {
+		for key := range m {
+			keys[key] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// changedKeys returns the keys added, removed or changed between two JSON
+// objects.
+func changedKeys(before any, after any) []string {
+	b, _ := before.(map[string]any)
+	a, _ := after.(map[string]any)
+	keys := []string{}
+	for key := range mergeKeys(b, a) {
+		if !reflect.DeepEqual(b[key], a[key]) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (q RequestAuditQuery) filter() repository.AuditFilter {
+	return repository.AuditFilter{
+		Actor:      q.Actor,
+		Action:     q.Action,
+		TargetType: q.TargetType,
+		TargetID:   q.TargetID,
+		ProjectID:  q.ProjectID,
+		Since:      q.Since,
+		Until:      q.Until,
+		Limit:      q.Limit,
+		Offset:     q.Offset,
+	}
+}
+
+// GetAuditEvents returns a page of the events matching the query, newest
+// first, 100 by default.
+func (h *AuditHandler) GetAuditEvents(c *gin.Context) {
+	var query RequestAuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondInvalid(c, err, "Invalid filters")
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = 100
+	}
+
+	events, total, err := h.AuditRepository.Find(c.Request.Context(), query.filter())
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve audit events")
+		return
+	}
+	c.JSON(200, AuditResponse{Events: events, Total: total})
+}
+
+// ExportAuditEvents downloads every event matching the query as a JSON file.
+func (h *AuditHandler) ExportAuditEvents(c *gin.Context) {
+	var query RequestAuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondInvalid(c, err, "Invalid filters")
+		return
+	}
+	query.Limit, query.Offset = 0, 0
+
+	events, _, err := h.AuditRepository.Find(c.Request.Context(), query.filter())
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve audit events")
+		return
+	}
+	filename := fmt.Sprintf("axolotl-audit-%s.json", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.JSON(200, events)
+}
@@ -0,0 +1,383 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/auth"
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/infra/ratelimit"
+	"axolotl-cloud/infra/secrets"
+	"axolotl-cloud/infra/settings"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/internal/app/repository"
+	"axolotl-cloud/utils"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionCookie is the name of the HttpOnly cookie holding the session token.
+const SessionCookie = "axolotl_session"
+
+// APIPrefix is the path of the current version of the API. Its routes are
+// also served under the deprecated /api prefix.
+const APIPrefix = "/api/v1"
+
+// loginCookiePath scopes the cookies of the login steps to the API, whatever
+// the prefix the client or the OIDC redirect URL uses.
+const loginCookiePath = "/api"
+
+const (
+	userContextKey    = "user"
+	sessionContextKey = "session"
+)
+
+type AuthHandler struct {
+	UserRepository      *repository.UserRepository
+	SessionRepository   *repository.SessionRepository
+	ChallengeRepository *repository.LoginChallengeRepository
+	SettingRepository   *repository.SettingRepository
+	// Cipher encrypts the TOTP secrets.
+	Cipher *secrets.Cipher
+	// OIDCEnabled shows the single sign-on button on the login page.
+	OIDCEnabled bool
+	// Lockout slows down password and code guessing, per username and IP.
+	Lockout *ratelimit.Lockout
+	// UserLockout does the same per username from any IP, against guesses
+	// spread over many IPs. It allows more failures so that failed logins
+	// elsewhere hardly keep the owner of the username out.
+	UserLockout *ratelimit.Lockout
+}
+
+type RequestCredentials struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RequestChangePassword struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type SessionResponse struct {
+	model.Session
+	Current bool `json:"current"`
+}
+
+// CurrentUser returns the user authenticated by RequireSession.
+func CurrentUser(c *gin.Context) *model.User {
+	user, _ := c.Get(userContextKey)
+	u, _ := user.(*model.User)
+	return u
+}
+
+func currentSession(c *gin.Context) *model.Session {
+	session, _ := c.Get(sessionContextKey)
+	s, _ := session.(*model.Session)
+	return s
+}
+
+// RequireSession returns a middleware aborting with a 401 unless the request
+// carries the cookie of a valid session. While two-factor authentication is
+// required, users without it can only reach their account routes to set it
+// up.
+func RequireSession(sessions *repository.SessionRepository, settingRepository *repository.SettingRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := c.Cookie(SessionCookie)
+		if err != nil || token == "" {
+			respondError(c, 401, "Authentication required")
+			return
+		}
+		session, err := sessions.FindByTokenHash(c.Request.Context(), auth.HashToken(token))
+		if err != nil || session.User == nil {
+			clearSessionCookie(c)
+			respondError(c, 401, "Session expired")
+			return
+		}
+		if !strings.HasPrefix(c.Request.URL.Path, APIPrefix+"/auth/") && needsTwoFactorSetup(settingRepository, session.User) {
+			respondError(c, 403, "Two-factor authentication must be set up first")
+			return
+		}
+
+		// Only record activity once a minute, not on every request
+		if now := time.Now(); now.Sub(session.LastSeenAt) > time.Minute {
+			if err := sessions.Touch(c.Request.Context(), session.ID, now); err != nil {
+				logger.Error("Failed to update session activity", err)
+			}
+		}
+
+		c.Set(userContextKey, session.User)
+		c.Set(sessionContextKey, session)
+		c.Next()
+	}
+}
+
+// GetAuthStatus tells the web UI whether the first admin must be created, and
+// whether single sign-on is available.
+func (h *AuthHandler) GetAuthStatus(c *gin.Context) {
+	count, err := h.UserRepository.Count(c.Request.Context())
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve users")
+		return
+	}
+	c.JSON(200, gin.H{"setup_required": count == 0, "oidc_enabled": h.OIDCEnabled})
+}
+
+// Setup creates the first admin and logs it in. It is refused once a user
+// exists.
+func (h *AuthHandler) Setup(c *gin.Context) {
+	var request RequestCredentials
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid input")
+		return
+	}
+
+	hash, err := auth.HashPassword(request.Password)
+	if err != nil {
+		respondError(c, 400, err.Error())
+		return
+	}
+	user := &model.User{Username: request.Username, PasswordHash: hash}
+	if err := h.UserRepository.CreateFirstAdmin(c.Request.Context(), user); err != nil {
+		if errors.Is(err, repository.ErrAlreadyBootstrapped) {
+			respondError(c, 409, "Setup has already been completed")
+			return
+		}
+		respondError(c, 500, "Failed to create user")
+		return
+	}
+	logger.Info("First admin %s created", user.Username)
+
+	if err := h.startSession(c, user); err != nil {
+		respondError(c, 500, "Failed to create session")
+		return
+	}
+	c.JSON(201, user)
+}
+
+func (h *AuthHandler) Login(c *gin.Context) {
+	var request RequestCredentials
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid input")
+		return
+	}
+
+	// Checked first, so that a locked out password cannot be confirmed
+	if wait := h.lockoutRemaining(c, request.Username); wait > 0 {
+		tooManyRequests(c, wait, "Too many failed logins, please retry later")
+		return
+	}
+
+	var hash []byte
+	user, err := h.UserRepository.FindByUsername(c.Request.Context(), request.Username)
+	if err == nil {
+		hash = user.PasswordHash
+	}
+	if !auth.CheckPassword(hash, request.Password) {
+		h.failLogin(c, request.Username)
+		respondError(c, 401, "Invalid username or password")
+		return
+	}
+
+	if user.TOTPEnabled {
+		if err := h.startChallenge(c, user); err != nil {
+			respondError(c, 500, "Failed to start login")
+			return
+		}
+		c.JSON(200, gin.H{"two_factor_required": true})
+		return
+	}
+	if err := h.startSession(c, user); err != nil {
+		respondError(c, 500, "Failed to create session")
+		return
+	}
+	h.resetLogin(c, request.Username)
+	c.JSON(200, user)
+}
+
+// loginLockoutKey locks out a username from an IP only, so that failed logins
+// elsewhere do not keep its owner out.
+func loginLockoutKey(c *gin.Context, username string) string {
+	return username + "|" + c.ClientIP()
+}
+
+// lockoutRemaining returns how long the logins of username from the IP of the
+// request are still locked out, or 0.
+func (h *AuthHandler) lockoutRemaining(c *gin.Context, username string) time.Duration {
+	return max(h.Lockout.Remaining(loginLockoutKey(c, username)), h.UserLockout.Remaining(username))
+}
+
+func (h *AuthHandler) failLogin(c *gin.Context, username string) {
+	h.Lockout.Fail(loginLockoutKey(c, username))
+	h.UserLockout.Fail(username)
+}
+
+func (h *AuthHandler) resetLogin(c *gin.Context, username string) {
+	h.Lockout.Reset(loginLockoutKey(c, username))
+	h.UserLockout.Reset(username)
+}
+
+// startSession creates a session for the user and sets its cookie.
+func (h *AuthHandler) startSession(c *gin.Context, user *model.User) error {
+	ctx := c.Request.Context()
+	if err := h.SessionRepository.DeleteExpired(ctx); err != nil {
+		logger.Error("Failed to delete expired sessions", err)
+	}
+
+	token, err := auth.NewToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	lifetime := h.sessionLifetime()
+	session := &model.Session{
+		UserID:     user.ID,
+		TokenHash:  auth.HashToken(token),
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(lifetime),
+	}
+	if err := h.SessionRepository.Create(ctx, session); err != nil {
+		return err
+	}
+	if err := h.UserRepository.SetLastLogin(ctx, user.ID, now); err != nil {
+		logger.Error("Failed to record last login", err)
+	}
+	user.LastLoginAt = &now
+
+	setSessionCookie(c, token, int(lifetime.Seconds()))
+	// The user is known from now on, e.g. for the audit log
+	c.Set(userContextKey, user)
+	return nil
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	user, session := CurrentUser(c), currentSession(c)
+	if session != nil {
+		if err := h.SessionRepository.Delete(c.Request.Context(), user.ID, session.ID); err != nil {
+			respondError(c, 500, "Failed to delete session")
+			return
+		}
+	}
+	clearSessionCookie(c)
+	c.Status(204)
+}
+
+func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
+	c.JSON(200, CurrentUser(c))
+}
+
+// ChangePassword changes the password of the current user and revokes its
+// other sessions.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var request RequestChangePassword
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid input")
+		return
+	}
+
+	user := CurrentUser(c)
+	if user.OIDCSubject != nil && user.PasswordHash == nil {
+		respondError(c, 400, "Single sign-on users have no password")
+		return
+	}
+	if !auth.CheckPassword(user.PasswordHash, request.CurrentPassword) {
+		respondError(c, 403, "Current password is incorrect")
+		return
+	}
+	hash, err := auth.HashPassword(request.NewPassword)
+	if err != nil {
+		respondError(c, 400, err.Error())
+		return
+	}
+	if err := h.UserRepository.UpdatePassword(c.Request.Context(), user.ID, hash); err != nil {
+		respondError(c, 500, "Failed to update password")
+		return
+	}
+
+	var keep uint
+	if session := currentSession(c); session != nil {
+		keep = session.ID
+	}
+	if err := h.SessionRepository.DeleteAllByUserID(c.Request.Context(), user.ID, keep); err != nil {
+		respondError(c, 500, "Failed to revoke other sessions")
+		return
+	}
+	c.Status(204)
+}
+
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	sessions, err := h.SessionRepository.FindAllByUserID(c.Request.Context(), CurrentUser(c).ID)
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve sessions")
+		return
+	}
+
+	var currentID uint
+	if session := currentSession(c); session != nil {
+		currentID = session.ID
+	}
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{Session: session, Current: session.ID == currentID})
+	}
+	c.JSON(200, response)
+}
+
+// RevokeSession logs out one of the sessions of the current user.
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	sessionID, exists := utils.ParamUInt(c, "sessionId")
+	if !exists {
+		respondError(c, 400, "Invalid session ID")
+		return
+	}
+
+	user := CurrentUser(c)
+	if err := h.SessionRepository.Delete(c.Request.Context(), user.ID, sessionID); err != nil {
+		respondLookupError(c, err, "Session not found")
+		return
+	}
+	if session := currentSession(c); session != nil && session.ID == sessionID {
+		clearSessionCookie(c)
+	}
+	c.Status(204)
+}
+
+// sessionLifetime reads the session lifetime setting, 7 days by default.
+func (h *AuthHandler) sessionLifetime() time.Duration {
+	hours := 168
+	if setting, err := h.SettingRepository.GetByKey(settings.SessionLifetime); err == nil {
+		if n, err := strconv.Atoi(setting.Value); err == nil && n > 0 {
+			hours = n
+		}
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func setSessionCookie(c *gin.Context, token string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(SessionCookie, token, maxAge, "/", "", secureRequest(c), true)
+}
+
+// secureRequest reports whether the client reached the server over HTTPS,
+// directly or through a TLS terminating proxy. The cookies are then Secure.
+func secureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
+
+func clearSessionCookie(c *gin.Context) {
+	setSessionCookie(c, "", -1)
+}
+
+// lastAdmin reports whether user is the only admin left.
+func lastAdmin(ctx context.Context, users *repository.UserRepository, user *model.User) (bool, error) {
+	if !user.Admin {
+		return false, nil
+	}
+	count, err := users.CountAdmins(ctx)
+	return count <= 1, err
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/auth"
+	"axolotl-cloud/infra/ratelimit"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/internal/app/repository"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestLoginLockout checks that failed logins lock a username out from an IP
+// first, then from any IP.
+func TestLoginLockout(t *testing.T) {
+	users := newTestUserRepository(t)
+	h := &AuthHandler{
+		UserRepository:    users,
+		SessionRepository: &repository.SessionRepository{DB: users.DB},
+		SettingRepository: repository.NewSettingRepository(users.DB),
+		Lockout:           ratelimit.NewLockout(2, time.Minute, time.Hour),
+		UserLockout:       ratelimit.NewLockout(4, time.Minute, time.Hour),
+	}
+	hash, err := auth.HashPassword("right password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Create(t.Context(), &model.User{Username: "alice", PasswordHash: hash}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The attempts run in order, each counts against the ones after
+	attempts := []struct {
+		name     string
+		ip       string
+		username string
+		password string
+		want     int
+	}{
+		{name: "first failure", ip: "10.0.0.1", username: "alice", password: "wrong", want: 401},
+		{name: "second failure", ip: "10.0.0.1", username: "alice", password: "wrong", want: 401},
+		{name: "failure locking the IP out", ip: "10.0.0.1", username: "alice", password: "wrong", want: 401},
+		{name: "right password from the locked out IP", ip: "10.0.0.1", username: "alice", password: "right password", want: 429},
+		{name: "failure from another IP", ip: "10.0.0.2", username: "alice", password: "wrong", want: 401},
+		{name: "failure locking the username out", ip: "10.0.0.3", username: "alice", password: "wrong", want: 401},
+		{name: "right password from a new IP", ip: "10.0.0.4", username: "alice", password: "right password", want: 429},
+		{name: "another username from the same IP", ip: "10.0.0.4", username: "bob", password: "wrong", want: 401},
+	}
+	for _, attempt := range attempts {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body := `{"username": "` + attempt.username + `", "password": "` + attempt.password + `"}`
+		c.Request = httptest.NewRequest("POST", "/api/v1/auth/login", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.RemoteAddr = attempt.ip + ":1234"
+		h.Login(c)
+		if w.Code != attempt.want {
+			t.Errorf("%s: Login() = %d %s, want %d", attempt.name, w.Code, w.Body.String(), attempt.want)
+		}
+	}
+}
@@ -0,0 +1,706 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/archive"
+	"axolotl-cloud/infra/buildpack"
+	"axolotl-cloud/infra/compose"
+	"axolotl-cloud/infra/docker"
+	"axolotl-cloud/infra/git"
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/infra/settings"
+	"axolotl-cloud/infra/worker"
+	"axolotl-cloud/infra/workspace"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BuildOptions are the settings a build request can set: which compose files
+// to load and build settings applied on top of the compose `build:` sections.
+type BuildOptions struct {
+	ComposePath  string   `json:"compose_path" binding:"omitempty"`  // replaces the discovery of the default compose file
+	ComposeFiles []string `json:"compose_files" binding:"omitempty"` // merged after the compose file and its override
+	Profiles     []string `json:"profiles" binding:"omitempty"`
+
+	BuildArgs map[string]string `json:"build_args" binding:"omitempty"`
+	Target    string            `json:"target" binding:"omitempty"`
+	Platform  string            `json:"platform" binding:"omitempty"`
+	Labels    map[string]string `json:"labels" binding:"omitempty"`
+	CacheFrom []string          `json:"cache_from" binding:"omitempty"`
+	Network   string            `json:"network" binding:"omitempty"`
+	Secrets   []string          `json:"secrets" binding:"omitempty"`
+}
+
+type RequestBuildFromSource struct {
+	GitURL      string `json:"git_url" binding:"required"`
+	AccessToken string `json:"access_token" binding:"omitempty"`
+	BuildOptions
+}
+
+func (h *ContainerHandler) BuildFromSource(c *gin.Context) {
+	projectID, exists := utils.ParamUInt(c, "id")
+	if !exists {
+		respondError(c, 400, "Invalid project ID")
+		return
+	}
+
+	var body RequestBuildFromSource
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondInvalid(c, err, "Invalid request data")
+		return
+	}
+
+	project, err := h.ProjectRepository.FindByID(c.Request.Context(), projectID)
+	if err != nil {
+		respondLookupError(c, err, "Project not found")
+		return
+	}
+
+	admin := principalOf(c).admin()
+	jobId, err := h.JobWorker.AddJob(&model.Job{
+		Name:      fmt.Sprintf("Clone and build image from source for project %d", projectID),
+		ProjectID: &projectID,
+		Run: func(ctx context.Context, log *logger.Logger) error {
+			log.Info("Cloning repository from %s", body.GitURL)
+			dir, err := git.CloneRepository(body.GitURL, jobWorkspace(ctx, projectID), body.AccessToken)
+			if dir != "" {
+				defer removeWorkspace(dir, log)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to clone repository: %w", err)
+			}
+			log.Info("Successfully cloned repository to %s", dir)
+
+			origin := buildOrigin{Source: model.BuildSourceGit, Ref: body.GitURL}
+			if sha, err := git.HeadCommit(dir); err == nil {
+				origin.CommitSHA = sha
+				log.Info("Building commit %s", sha)
+			} else {
+				log.Error("%v", err)
+			}
+
+			return h.buildFromWorkspace(ctx, log, project, dir, body.BuildOptions, origin, admin)
+		},
+	}, nil)
+	if err != nil {
+		respondError(c, 500, "Failed to add job to build from source")
+		return
+	}
+
+	auditJob(c, jobId)
+	c.JSON(201, gin.H{
+		"job_id": jobId,
+	})
+}
+
+// BuildFromArchive runs the build pipeline on the sources of a multipart
+// upload: an `archive` file (.tar.gz, .tgz or .zip) and optional `options`
+// holding BuildOptions as JSON.
+func (h *ContainerHandler) BuildFromArchive(c *gin.Context) {
+	projectID, exists := utils.ParamUInt(c, "id")
+	if !exists {
+		respondError(c, 400, "Invalid project ID")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, archive.MaxUploadSize)
+	fileHeader, err := c.FormFile("archive")
+	if err != nil {
+		respondError(c, 400, "Missing or too large archive")
+		return
+	}
+	if !archive.IsSupported(fileHeader.Filename) {
+		respondError(c, 400, archive.ErrUnsupportedFormat.Error())
+		return
+	}
+
+	var options BuildOptions
+	if raw := c.PostForm("options"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &options); err != nil {
+			respondError(c, 400, "Invalid build options")
+			return
+		}
+	}
+
+	project, err := h.ProjectRepository.FindByID(c.Request.Context(), projectID)
+	if err != nil {
+		respondLookupError(c, err, "Project not found")
+		return
+	}
+
+	// The job outlives the request, keep the upload until it has been extracted.
+	upload, err := os.CreateTemp("", "axolotl-upload-*")
+	if err != nil {
+		respondError(c, 500, "Failed to store archive")
+		return
+	}
+	upload.Close()
+	if err := c.SaveUploadedFile(fileHeader, upload.Name()); err != nil {
+		os.Remove(upload.Name())
+		respondError(c, 500, "Failed to store archive")
+		return
+	}
+
+	admin := principalOf(c).admin()
+	jobId, err := h.JobWorker.AddJob(&model.Job{
+		Name:      fmt.Sprintf("Build image from archive %s for project %d", fileHeader.Filename, projectID),
+		ProjectID: &projectID,
+		Run: func(ctx context.Context, log *logger.Logger) error {
+			defer os.Remove(upload.Name())
+
+			dir, err := workspace.Prepare(jobWorkspace(ctx, projectID))
+			if err != nil {
+				return fmt.Errorf("failed to prepare workspace: %w", err)
+			}
+			defer removeWorkspace(dir, log)
+			log.Info("Extracting archive %s (%d bytes)", fileHeader.Filename, fileHeader.Size)
+			if err := archive.Extract(upload.Name(), fileHeader.Filename, dir); err != nil {
+				return fmt.Errorf("failed to extract archive: %w", err)
+			}
+			log.Info("Successfully extracted archive to %s", dir)
+
+			origin := buildOrigin{Source: model.BuildSourceArchive, Ref: fileHeader.Filename}
+			return h.buildFromWorkspace(ctx, log, project, dir, options, origin, admin)
+		},
+	}, nil)
+	if err != nil {
+		os.Remove(upload.Name())
+		respondError(c, 500, "Failed to add job to build from archive")
+		return
+	}
+
+	auditJob(c, jobId)
+	c.JSON(201, gin.H{
+		"job_id": jobId,
+	})
+}
+
+// jobWorkspace names the workspace of the running build job, so that two
+// builds of the same project never share their sources.
+func jobWorkspace(ctx context.Context, projectID uint) string {
+	return fmt.Sprintf("project-%d-job-%d", projectID, worker.JobID(ctx))
+}
+
+func removeWorkspace(dir string, log *logger.Logger) {
+	if err := workspace.Remove(dir); err != nil {
+		log.Error("Failed to remove workspace %s: %v", dir, err)
+	}
+}
+
+// buildOrigin describes where the sources of a build come from.
+type buildOrigin struct {
+	Source    model.BuildSource
+	Ref       string
+	CommitSHA string
+}
+
+// buildFromWorkspace builds the images of the sources found in dir and creates
+// the matching containers: one per compose service when the sources hold a
+// compose file, a single default container otherwise. Containers that already
+// exist are repointed to the new image. Unless admin, the compose file cannot
+// create containers with access to the host.
+func (h *ContainerHandler) buildFromWorkspace(ctx context.Context, log *logger.Logger, project *model.Project, dir string, options BuildOptions, origin buildOrigin, admin bool) error {
+	projectID := project.ID
+
+	number, err := h.BuildRepository.NextNumber(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to reserve build number: %w", err)
+	}
+	log.Info("Starting build #%d", number)
+
+	log.Info("Checking for Compose file in source directory")
+	var composeFile model.ComposeFile
+	var parsedContainers []model.Container
+
+	composePaths, err := compose.Discover(dir, options.ComposePath, options.ComposeFiles)
+	if err != nil {
+		return fmt.Errorf("failed to find compose files: %w", err)
+	}
+	hasComposeFile := len(composePaths) > 0
+
+	if hasComposeFile {
+		log.Info("Found Compose files %s", strings.Join(composePaths, ", "))
+		loader := &compose.Loader{Dir: dir, Profiles: options.Profiles, Variables: project.Variables}
+		composeFile, err = loader.LoadFiles(composePaths)
+		if err != nil {
+			return fmt.Errorf("failed to parse compose file: %w", err)
+		}
+		for _, warning := range loader.Warnings {
+			log.Info("Warning: %s", warning)
+		}
+		parsedContainers, err = utils.ParseComposeFile(composeFile, project)
+		if err != nil {
+			return fmt.Errorf("failed to parse compose file: %w", err)
+		}
+		log.Info("Successfully parsed Compose file with %d services", len(composeFile.Services))
+
+		for _, container := range parsedContainers {
+			if _, err := h.ContainerRepository.FindByName(ctx, projectID, container.Name); err == nil || admin {
+				continue
+			}
+			if access := hostAccess(&container, nil); access != "" {
+				return fmt.Errorf("only admins can give container %s access to %s", container.Name, access)
+			}
+		}
+	}
+
+	// Case: Compose file exists (single or multiple services)
+	if hasComposeFile {
+		builds := make(map[string]*model.Build)
+		for serviceName, service := range composeFile.Services {
+			if service.Build != nil {
+				// Build service image from compose definition
+				buildContext, err := compose.ResolveContext(dir, service.Build.Context)
+				if err != nil {
+					return fmt.Errorf("service %s: %w", serviceName, err)
+				}
+
+				// Determine image name (use compose image name or generate)
+				repository := imageRepository(service.Image)
+				if repository == "" {
+					repository = fmt.Sprintf("project-%d-%s", projectID, serviceName)
+				}
+
+				spec, err := h.buildSpec(ctx, projectID, options, buildContext, repository, service.Build, service.Platform)
+				if err != nil {
+					return fmt.Errorf("failed to prepare build for service %s: %w", serviceName, err)
+				}
+
+				log.Info("Building image for service %s from %s", serviceName, buildContext)
+				build, err := h.runBuild(ctx, log, project, number, serviceName, origin, spec)
+				if err != nil {
+					return fmt.Errorf("failed to build image for service %s: %w", serviceName, err)
+				}
+				builds[utils.FormatContainerName(project.Name, serviceName)] = build
+
+				// Update container model with actual image name
+				for i, c := range parsedContainers {
+					if c.Name == utils.FormatContainerName(project.Name, serviceName) {
+						parsedContainers[i].DockerImage = build.Tag
+					}
+				}
+			} else if service.Image == "" {
+				return fmt.Errorf("service %s has no image and no build context", serviceName)
+			}
+		}
+
+		// Create all containers from compose definition
+		for _, container := range parsedContainers {
+			if err := h.saveBuiltContainer(ctx, log, &container, builds[container.Name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Case: No compose file - build from root Dockerfile
+	repository := fmt.Sprintf("project-%d-image", projectID)
+	spec, err := h.buildSpec(ctx, projectID, options, dir, repository, nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to prepare build: %w", err)
+	}
+
+	ports := make(map[string]string)
+	if _, err := os.Stat(filepath.Join(dir, "Dockerfile")); os.IsNotExist(err) {
+		content, port, err := generateDockerfile(dir, project, log)
+		if err != nil {
+			return err
+		}
+		spec.Dockerfile = buildpack.GeneratedDockerfile
+		spec.DockerfileContent = content
+		if port != "" {
+			ports, err = h.generatedImagePorts(ctx, log, projectID, utils.FormatContainerName(project.Name, "default"), port)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Info("Building image from source directory %s", dir)
+	build, err := h.runBuild(ctx, log, project, number, "default", origin, spec)
+	if err != nil {
+		return fmt.Errorf("failed to build image from source: %w", err)
+	}
+
+	// Create default container
+	container := model.Container{
+		ProjectID:   projectID,
+		Name:        utils.FormatContainerName(project.Name, "default"),
+		DockerImage: build.Tag,
+		Ports:       ports,
+		Env:         make(map[string]string),
+		Volumes:     make(map[string]string),
+		Networks:    []string{},
+		NetworkMode: "bridge",
+	}
+	return h.saveBuiltContainer(ctx, log, &container, build)
+}
+
+// Host ports published for the generated images are picked in this range,
+// away from the port of Axolotl and the usual ports of the host.
+const (
+	hostPortRangeStart = 20000
+	hostPortRangeEnd   = 29999
+)
+
+// generatedImagePorts publishes the port exposed by a generated image on a
+// free host port, for a container that does not exist yet. Existing
+// containers keep their ports.
+func (h *ContainerHandler) generatedImagePorts(ctx context.Context, log *logger.Logger, projectID uint, name string, port string) (map[string]string, error) {
+	if _, err := h.ContainerRepository.FindByName(ctx, projectID, name); err == nil {
+		return map[string]string{}, nil
+	}
+	hostPort, err := h.freeHostPort(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Publishing port %s of the generated image on host port %s", port, hostPort)
+	return map[string]string{hostPort: port}, nil
+}
+
+// freeHostPort returns a host port of the range published by no container,
+// neither in the database nor on the Docker daemon.
+func (h *ContainerHandler) freeHostPort(ctx context.Context) (string, error) {
+	used := map[string]bool{}
+	containers, err := h.ContainerRepository.GetAllContainers(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list containers: %w", err)
+	}
+	for _, container := range containers {
+		for hostPort := range container.Ports {
+			used[hostPort] = true
+		}
+	}
+	dockerContainers, err := h.DockerClient.ListContainers(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, dc := range dockerContainers {
+		for _, p := range dc.Ports {
+			if p.PublicPort != 0 {
+				used[strconv.Itoa(int(p.PublicPort))] = true
+			}
+		}
+	}
+
+	for port := hostPortRangeStart; port <= hostPortRangeEnd; port++ {
+		if !used[strconv.Itoa(port)] {
+			return strconv.Itoa(port), nil
+		}
+	}
+	return "", fmt.Errorf("no free host port between %d and %d", hostPortRangeStart, hostPortRangeEnd)
+}
+
+// runBuild builds spec, whose single tag is the image repository, and records
+// it as build number of the project. The image is tagged with the build number
+// and, for git sources, with the short commit SHA.
+func (h *ContainerHandler) runBuild(ctx context.Context, log *logger.Logger, project *model.Project, number uint, service string, origin buildOrigin, spec docker.BuildSpec) (*model.Build, error) {
+	repository := spec.Tags[0]
+	build := &model.Build{
+		ProjectID: project.ID,
+		Number:    number,
+		Service:   service,
+		Source:    origin.Source,
+		SourceRef: origin.Ref,
+		CommitSHA: origin.CommitSHA,
+		Tag:       fmt.Sprintf("%s:%d", repository, number),
+		Status:    model.BuildStatusRunning,
+		JobID:     worker.JobID(ctx),
+	}
+	// Linked right away, so that failed builds are listed and pruned along
+	// with the other builds of the container
+	if container, err := h.ContainerRepository.FindByName(ctx, project.ID, utils.FormatContainerName(project.Name, service)); err == nil {
+		build.ContainerID = &container.ID
+	}
+	if err := h.BuildRepository.Save(ctx, build); err != nil {
+		return nil, fmt.Errorf("failed to save build: %w", err)
+	}
+	spec.Tags = buildTags(build)
+
+	started := time.Now()
+	imageID, err := h.DockerClient.BuildImage(ctx, spec, log)
+	build.Duration = time.Since(started).Milliseconds()
+	build.ImageID = imageID
+	build.Status = model.BuildStatusSucceeded
+	if err != nil {
+		build.Status = model.BuildStatusFailed
+	}
+	if saveErr := h.BuildRepository.Save(ctx, build); saveErr != nil {
+		log.Error("Failed to save build #%d: %v", number, saveErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("Successfully built image %s in %.1fs", build.Tag, float64(build.Duration)/1000)
+	return build, nil
+}
+
+// saveBuiltContainer creates the container, or repoints the existing container
+// of the same name to the new image, then attaches the build to it, along
+// with the builds of its service made before it existed.
+func (h *ContainerHandler) saveBuiltContainer(ctx context.Context, log *logger.Logger, container *model.Container, build *model.Build) error {
+	existing, err := h.ContainerRepository.FindByName(ctx, container.ProjectID, container.Name)
+	if err == nil {
+		if err := h.ContainerRepository.UpdateImage(ctx, existing.ID, container.DockerImage); err != nil {
+			return fmt.Errorf("failed to update container %s: %w", container.Name, err)
+		}
+		existing.DockerImage = container.DockerImage
+		container = existing
+		log.Info("Updated container %s to image %s", container.Name, container.DockerImage)
+	} else {
+		if err := h.nameInUseElsewhere(ctx, container); err != nil {
+			return err
+		}
+		if err := h.ContainerRepository.Create(ctx, container); err != nil {
+			return fmt.Errorf("failed to create container %s: %w", container.Name, err)
+		}
+		log.Info("Created container %s", container.Name)
+	}
+
+	if build == nil {
+		return nil
+	}
+	build.ContainerID = &container.ID
+	if err := h.BuildRepository.Save(ctx, build); err != nil {
+		return fmt.Errorf("failed to save build: %w", err)
+	}
+	if err := h.BuildRepository.LinkService(ctx, container.ProjectID, build.Service, container.ID); err != nil {
+		log.Error("Failed to link the previous builds of container %s: %v", container.Name, err)
+	}
+	h.pruneBuilds(ctx, log, container)
+	return nil
+}
+
+// pruneBuilds removes the builds of a container beyond the retention setting,
+// along with their images. The build the container runs is always kept.
+func (h *ContainerHandler) pruneBuilds(ctx context.Context, log *logger.Logger, container *model.Container) {
+	retention := 10
+	if setting, err := h.SettingRepository.GetByKey(settings.BuildRetention); err == nil {
+		if n, err := strconv.Atoi(setting.Value); err == nil && n > 0 {
+			retention = n
+		}
+	}
+
+	builds, err := h.BuildRepository.FindAllByContainerID(ctx, container.ID)
+	if err != nil {
+		log.Error("Failed to list builds of container %s: %v", container.Name, err)
+		return
+	}
+	if len(builds) <= retention {
+		return
+	}
+
+	for _, build := range builds[retention:] {
+		if build.Tag == container.DockerImage {
+			continue
+		}
+		if build.Status == model.BuildStatusSucceeded {
+			for _, tag := range buildTags(&build) {
+				if err := h.DockerClient.RemoveImage(ctx, tag, log); err != nil {
+					log.Error("Failed to remove image of build #%d: %v", build.Number, err)
+				}
+			}
+		}
+		if err := h.BuildRepository.Delete(ctx, build.ID); err != nil {
+			log.Error("Failed to delete build #%d: %v", build.Number, err)
+		}
+	}
+}
+
+// buildTags returns the tags of the image of a build.
+func buildTags(build *model.Build) []string {
+	tags := []string{build.Tag}
+	if len(build.CommitSHA) >= 12 {
+		tags = append(tags, imageRepository(build.Tag)+":"+build.CommitSHA[:12])
+	}
+	return tags
+}
+
+// imageRepository strips the tag from an image reference, keeping the port of
+// a registry host (registry:5000/app:1 -> registry:5000/app).
+func imageRepository(image string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i]
+	}
+	return image
+}
+
+// buildSpec merges the build options of the request on top of the ones
+// coming from a compose `build:` section (nil when building the root
+// Dockerfile). Request values win.
+func (h *ContainerHandler) buildSpec(ctx context.Context, projectID uint, o BuildOptions, contextDir string, imageName string, compose *model.ComposeBuild, platform string) (docker.BuildSpec, error) {
+	spec := docker.BuildSpec{
+		ContextDir: contextDir,
+		Dockerfile: "Dockerfile",
+		Tags:       []string{imageName},
+		Args:       map[string]string{},
+		Labels:     map[string]string{},
+		Platform:   platform,
+	}
+	secretIDs := o.Secrets
+
+	if compose != nil {
+		if compose.Dockerfile != "" {
+			spec.Dockerfile = compose.Dockerfile
+		}
+		maps.Copy(spec.Args, compose.Args)
+		maps.Copy(spec.Labels, compose.Labels)
+		spec.Target = compose.Target
+		spec.CacheFrom = compose.CacheFrom
+		spec.Network = compose.Network
+		secretIDs = append(slices.Clone(compose.Secrets), secretIDs...)
+	}
+
+	maps.Copy(spec.Args, o.BuildArgs)
+	maps.Copy(spec.Labels, o.Labels)
+	if o.Target != "" {
+		spec.Target = o.Target
+	}
+	if o.Platform != "" {
+		spec.Platform = o.Platform
+	}
+	if len(o.CacheFrom) > 0 {
+		spec.CacheFrom = o.CacheFrom
+	}
+	if o.Network != "" {
+		spec.Network = o.Network
+	}
+
+	secrets, err := h.resolveBuildSecrets(ctx, projectID, secretIDs)
+	if err != nil {
+		return docker.BuildSpec{}, err
+	}
+	spec.Secrets = secrets
+	return spec, nil
+}
+
+// resolveBuildSecrets looks up the value of every build secret ID in the
+// secrets store: secret `npm_token` of a build is the secret of that name,
+// which must be allowed for the project.
+func (h *ContainerHandler) resolveBuildSecrets(ctx context.Context, projectID uint, ids []string) (map[string][]byte, error) {
+	secrets := make(map[string][]byte, len(ids))
+	for _, id := range ids {
+		if _, done := secrets[id]; done {
+			continue
+		}
+		value, err := h.SecretRepository.Reveal(ctx, id, projectID)
+		if err != nil {
+			return nil, fmt.Errorf("build secret %s: %w", id, err)
+		}
+		secrets[id] = []byte(value)
+	}
+	return secrets, nil
+}
+
+// generateDockerfile returns the Dockerfile of the project, or one generated
+// from the detected stack of dir, for repositories that ship none, and the
+// port exposed by the generated image. It is never written into dir, the
+// build adds it to the context.
+func generateDockerfile(dir string, project *model.Project, log *logger.Logger) (string, string, error) {
+	content := project.Dockerfile
+	port := ""
+	if content != "" {
+		log.Info("No Dockerfile found, using the Dockerfile of project %s", project.Name)
+	} else {
+		result, err := buildpack.Detect(dir)
+		if err != nil {
+			return "", "", fmt.Errorf("no Dockerfile found and none could be generated: %w", err)
+		}
+		log.Info("No Dockerfile found, generated one for a %s project (port %s)", result.Stack, result.Port)
+		content = result.Dockerfile
+		port = result.Port
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		log.Info("  %s", line)
+	}
+
+	return content, port, nil
+}
+
+func (h *ContainerHandler) GetContainerBuilds(c *gin.Context) {
+	containerID, exists := utils.ParamUInt(c, "containerId")
+	if !exists {
+		respondError(c, 400, "Invalid container ID")
+		return
+	}
+
+	builds, err := h.BuildRepository.FindAllByContainerID(c.Request.Context(), containerID)
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve builds")
+		return
+	}
+	c.JSON(200, builds)
+}
+
+// RollbackContainer repoints a container to the image of one of its previous
+// builds and recreates it.
+func (h *ContainerHandler) RollbackContainer(c *gin.Context) {
+	containerID, exists := utils.ParamUInt(c, "containerId")
+	if !exists {
+		respondError(c, 400, "Invalid container ID")
+		return
+	}
+	buildID, exists := utils.ParamUInt(c, "buildId")
+	if !exists {
+		respondError(c, 400, "Invalid build ID")
+		return
+	}
+
+	container, err := h.ContainerRepository.FindByID(c.Request.Context(), containerID)
+	if err != nil {
+		respondLookupError(c, err, "Container not found")
+		return
+	}
+
+	build, err := h.BuildRepository.FindByID(c.Request.Context(), buildID)
+	if err != nil {
+		respondLookupError(c, err, "Build not found")
+		return
+	}
+	if build.ContainerID == nil || *build.ContainerID != containerID {
+		respondError(c, 404, "Build not found")
+		return
+	}
+	if build.Status != model.BuildStatusSucceeded {
+		respondError(c, 400, fmt.Sprintf("Build #%d did not succeed", build.Number))
+		return
+	}
+
+	if err := h.ContainerRepository.UpdateImage(c.Request.Context(), containerID, build.Tag); err != nil {
+		respondError(c, 500, "Failed to update container")
+		return
+	}
+	container.DockerImage = build.Tag
+
+	jobId, err := h.JobWorker.AddJob(&model.Job{
+		Name:      fmt.Sprintf("Rollback container %s to build #%d", container.Name, build.Number),
+		ProjectID: &container.ProjectID,
+		Run: func(ctx context.Context, log *logger.Logger) error {
+			log.Info("Rolling back to image %s (build #%d)", build.Tag, build.Number)
+			return h.deployContainer(ctx, container, log)
+		},
+	}, &containerID)
+	if err != nil {
+		respondError(c, 500, fmt.Sprintf("Failed to add job to rollback container %s", container.Name))
+		return
+	}
+
+	auditJob(c, jobId)
+	c.JSON(201, gin.H{
+		"job_id": jobId,
+	})
+}
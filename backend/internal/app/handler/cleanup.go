@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/docker"
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/utils"
+	"context"
+	"strconv"
+	"strings"
+)
+
+// CleanupOptions selects what is removed on top of the Docker containers and
+// networks when deleting.
+type CleanupOptions struct {
+	Volumes bool `form:"volumes"` // volume directories
+	Images  bool `form:"images"`  // images built by Axolotl
+}
+
+// stopContainers stops the Docker containers of containers moved to the trash.
+// Containers that do not exist are skipped.
+func stopContainers(ctx context.Context, dockerClient *docker.DockerClient, containers []model.Container, log *logger.Logger) error {
+	for _, container := range containers {
+		for _, name := range []string{container.Name, container.Name + "_next", docker.ProxyName(container.Name)} {
+			if err := dockerClient.StopContainerIfExists(ctx, name, log); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeContainerResources removes the Docker container, its ports proxy, the
+// leftovers of an interrupted blue/green deployment and, optionally, the
+// volume directories.
+func removeContainerResources(ctx context.Context, dockerClient *docker.DockerClient, container *model.Container, options CleanupOptions, log *logger.Logger) error {
+	for _, name := range []string{container.Name, container.Name + "_next", container.Name + "_previous", docker.ProxyName(container.Name)} {
+		if err := dockerClient.RemoveContainerIfExists(ctx, name, log); err != nil {
+			return err
+		}
+	}
+	if options.Volumes {
+		return dockerClient.RemoveVolumes(container.Name, log)
+	}
+	return nil
+}
+
+// removeProjectResources removes the Docker containers of a project, the ones
+// labeled with it included, its network and, optionally, its volume
+// directories and built images. Images are removed last and their failures
+// are only logged, an image can still be used by another container.
+func removeProjectResources(ctx context.Context, dockerClient *docker.DockerClient, project *model.Project, containers []model.Container, builds []model.Build, options CleanupOptions, log *logger.Logger) error {
+	log.Info("Removing the %d containers of project %s", len(containers), project.Name)
+	for _, container := range containers {
+		if err := removeContainerResources(ctx, dockerClient, &container, options, log); err != nil {
+			return err
+		}
+	}
+
+	dockerContainers, err := dockerClient.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+	projectID := strconv.FormatUint(uint64(project.ID), 10)
+	for _, dc := range dockerContainers {
+		if dc.Labels[docker.ProjectLabel] == projectID {
+			log.Info("Removing orphaned container %s", strings.TrimPrefix(dc.Names[0], "/"))
+			if err := dockerClient.RemoveContainerIfExists(ctx, dc.ID, log); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := dockerClient.RemoveNetwork(ctx, utils.FormatNetworkName(project.ID), log); err != nil {
+		return err
+	}
+
+	if options.Images {
+		for _, build := range builds {
+			if build.Status != model.BuildStatusSucceeded {
+				continue
+			}
+			for _, tag := range buildTags(&build) {
+				if err := dockerClient.RemoveImage(ctx, tag, log); err != nil {
+					log.Error("Failed to remove image %s: %v", tag, err)
+				}
+			}
+		}
+	}
+	return nil
+}
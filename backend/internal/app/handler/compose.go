@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/compose"
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/utils"
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RequestImportComposeFile struct {
+	ComposeFile string            `json:"compose_file"`
+	Overrides   []string          `json:"overrides"` // merged on top of the compose file, in order
+	Profiles    []string          `json:"profiles"`
+	EnvFile     string            `json:"env_file"` // content of a .env file
+	Files       map[string]string `json:"files"`    // files referenced by include, extends or env_file, by path
+}
+
+func (h *ContainerHandler) ImportComposeFile(c *gin.Context) {
+	projectID, exists := utils.ParamUInt(c, "id")
+	if !exists {
+		respondError(c, 400, "Invalid project ID")
+		return
+	}
+
+	var request RequestImportComposeFile
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid request data")
+		return
+	}
+
+	project, err := h.ProjectRepository.FindByID(c.Request.Context(), projectID)
+	if err != nil {
+		respondLookupError(c, err, "Project not found")
+		return
+	}
+
+	template := &model.ComposeTemplate{
+		ProjectID: projectID,
+		Content:   request.ComposeFile,
+		Overrides: request.Overrides,
+		Profiles:  request.Profiles,
+		EnvFile:   request.EnvFile,
+		Files:     request.Files,
+	}
+	containers, err := h.renderComposeTemplate(project, template)
+	if err != nil {
+		logger.Error("Failed to parse compose file", err)
+		respondError(c, 400, fmt.Sprintf("Invalid compose file: %v", err))
+		return
+	}
+	if !h.checkImportedNames(c, containers) || !h.allowImportedHostAccess(c, containers) {
+		return
+	}
+
+	if err := h.TemplateRepository.Save(c.Request.Context(), template); err != nil {
+		logger.Error("Failed to save compose template", err)
+		respondError(c, 500, "Failed to save compose file")
+		return
+	}
+
+	if err := h.saveImportedContainers(c.Request.Context(), containers); err != nil {
+		logger.Error("Failed to save imported containers", err)
+		respondError(c, 500, "Failed to create containers from compose file")
+		return
+	}
+
+	c.JSON(201, maskContainers(containers))
+}
+
+// ReimportComposeFile renders the last imported compose file again with the
+// current project variables and updates the containers accordingly.
+func (h *ContainerHandler) ReimportComposeFile(c *gin.Context) {
+	projectID, exists := utils.ParamUInt(c, "id")
+	if !exists {
+		respondError(c, 400, "Invalid project ID")
+		return
+	}
+
+	project, err := h.ProjectRepository.FindByID(c.Request.Context(), projectID)
+	if err != nil {
+		respondLookupError(c, err, "Project not found")
+		return
+	}
+
+	template, err := h.TemplateRepository.FindByProjectID(c.Request.Context(), projectID)
+	if err != nil {
+		respondLookupError(c, err, "No compose file imported in this project")
+		return
+	}
+
+	containers, err := h.renderComposeTemplate(project, template)
+	if err != nil {
+		respondError(c, 400, fmt.Sprintf("Invalid compose file: %v", err))
+		return
+	}
+	if !h.checkImportedNames(c, containers) || !h.allowImportedHostAccess(c, containers) {
+		return
+	}
+
+	if err := h.saveImportedContainers(c.Request.Context(), containers); err != nil {
+		logger.Error("Failed to save imported containers", err)
+		respondError(c, 500, "Failed to update containers from compose file")
+		return
+	}
+
+	c.JSON(200, maskContainers(containers))
+}
+
+// renderComposeTemplate resolves the variables of a template with the
+// project variables and its .env file, and returns the resulting containers.
+func (h *ContainerHandler) renderComposeTemplate(project *model.Project, template *model.ComposeTemplate) ([]model.Container, error) {
+	files := make(map[string][]byte, len(template.Files)+1)
+	for path, content := range template.Files {
+		files[filepath.Clean(path)] = []byte(content)
+	}
+	if template.EnvFile != "" {
+		files[".env"] = []byte(template.EnvFile)
+	}
+
+	composeFiles := []compose.File{{Content: []byte(template.Content)}}
+	for _, override := range template.Overrides {
+		composeFiles = append(composeFiles, compose.File{Content: []byte(override)})
+	}
+
+	loader := &compose.Loader{Files: files, Profiles: template.Profiles, Variables: project.Variables}
+	composeFile, err := loader.Load(composeFiles...)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range loader.Warnings {
+		logger.Info("Compose file of project %s: %s", project.Name, warning)
+	}
+
+	return utils.ParseComposeFile(composeFile, project)
+}
+
+// checkImportedNames checks that no container of another project has the name
+// of an imported container, see checkContainerName.
+func (h *ContainerHandler) checkImportedNames(c *gin.Context, containers []model.Container) bool {
+	for i := range containers {
+		if !h.checkContainerName(c, &containers[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowImportedHostAccess checks the access to the host of the imported
+// containers, see allowHostAccess, against the containers they update.
+func (h *ContainerHandler) allowImportedHostAccess(c *gin.Context, containers []model.Container) bool {
+	for i := range containers {
+		existing, err := h.ContainerRepository.FindByName(c.Request.Context(), containers[i].ProjectID, containers[i].Name)
+		if err != nil {
+			existing = nil
+		}
+		if !allowHostAccess(c, &containers[i], existing) {
+			return false
+		}
+	}
+	return true
+}
+
+// saveImportedContainers creates the containers, or updates the containers of
+// the project with the same name.
+func (h *ContainerHandler) saveImportedContainers(ctx context.Context, containers []model.Container) error {
+	for i := range containers {
+		container := &containers[i]
+		if existing, err := h.ContainerRepository.FindByName(ctx, container.ProjectID, container.Name); err == nil {
+			container.ID = existing.ID
+		}
+		if err := h.ContainerRepository.Save(ctx, container); err != nil {
+			return fmt.Errorf("failed to save container %s: %w", container.Name, err)
+		}
+	}
+	return nil
+}
@@ -2,15 +2,12 @@ package handler
 
 import (
 	"axolotl-cloud/infra/docker"
-	"axolotl-cloud/infra/git"
 	"axolotl-cloud/infra/logger"
 	"axolotl-cloud/infra/worker"
 	"axolotl-cloud/internal/app/model"
 	"axolotl-cloud/internal/app/repository"
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"axolotl-cloud/utils"
 
@@ -20,6 +17,11 @@ import (
 type ContainerHandler struct {
 	ContainerRepository *repository.ContainerRepository
 	ProjectRepository   *repository.ProjectRepository
+	BuildRepository     *repository.BuildRepository
+	TemplateRepository  *repository.ComposeTemplateRepository
+	RevisionRepository  *repository.ContainerRevisionRepository
+	SecretRepository    *repository.SecretRepository
+	SettingRepository   *repository.SettingRepository
 	JobWorker           *worker.Worker
 	DockerClient        *docker.DockerClient
 }
@@ -27,290 +29,228 @@ type ContainerHandler struct {
 func (h *ContainerHandler) CreateContainer(c *gin.Context) {
 	projectID, exists := utils.ParamUInt(c, "id")
 	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid project ID"})
+		respondError(c, 400, "Invalid project ID")
 		return
 	}
 
 	project, err := h.ProjectRepository.FindByID(c.Request.Context(), projectID)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Project not found"})
+		respondLookupError(c, err, "Project not found")
 		return
 	}
 
 	var container model.Container
 	if err := c.ShouldBindJSON(&container); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid container data"})
+		respondInvalid(c, err, "Invalid container data")
 		return
 	}
 	container.ProjectID = projectID
 	container.Name = utils.FormatContainerName(project.Name, container.Name)
+	if _, err := h.ContainerRepository.FindByName(c.Request.Context(), projectID, container.Name); err == nil {
+		respondError(c, 409, fmt.Sprintf("A container named %s already exists", container.Name))
+		return
+	}
+	if !h.checkContainerName(c, &container) {
+		return
+	}
+	if !allowHostAccess(c, &container, nil) {
+		return
+	}
 	if err := h.ContainerRepository.Create(c.Request.Context(), &container); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create container"})
+		respondError(c, 500, "Failed to create container")
 		return
 	}
 
+	auditTarget(c, container.ID)
+	maskContainer(&container)
 	c.JSON(201, container)
 }
 
-func (h *ContainerHandler) ImportComposeFile(c *gin.Context) {
+func (h *ContainerHandler) GetAllContainers(c *gin.Context) {
 	projectID, exists := utils.ParamUInt(c, "id")
 	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid project ID"})
-		return
-	}
-
-	var request struct {
-		ComposeFile string `json:"compose_file"`
-	}
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid request data"})
+		respondError(c, 400, "Invalid project ID")
 		return
 	}
 
 	project, err := h.ProjectRepository.FindByID(c.Request.Context(), projectID)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Project not found"})
+		respondLookupError(c, err, "Project not found")
 		return
 	}
 
-	createdContainers, _, err := utils.ParseComposeFileFromBytes([]byte(request.ComposeFile), project)
+	containers, err := h.ContainerRepository.FindAllByProjectID(c.Request.Context(), projectID)
 	if err != nil {
-		logger.Error("Failed to parse compose file", err)
-		c.JSON(500, gin.H{"error": "Failed to create containers from compose file"})
+		respondError(c, 500, "Failed to retrieve containers")
 		return
 	}
+	fillPendingChanges(project, containers, containers)
 
-	c.JSON(201, createdContainers)
+	c.JSON(200, maskContainers(containers))
 }
 
-type RequestBuildFromSource struct {
-	GitURL      string `json:"git_url" binding:"required"`
-	AccessToken string `json:"access_token" binding:"omitempty"`
-}
-
-func (h *ContainerHandler) BuildFromSource(c *gin.Context) {
+// RequireContainerInProject aborts with a 404 when the container of the route
+// does not belong to its project, so that access checks made on the project
+// also cover its containers.
+func (h *ContainerHandler) RequireContainerInProject(c *gin.Context) {
+	if c.Param("containerId") == "" {
+		c.Next()
+		return
+	}
 	projectID, exists := utils.ParamUInt(c, "id")
 	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid project ID"})
+		respondError(c, 400, "Invalid project ID")
 		return
 	}
-
-	var body RequestBuildFromSource
-	if err := c.ShouldBindJSON(&body); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid request data"})
+	containerID, exists := utils.ParamUInt(c, "containerId")
+	if !exists {
+		respondError(c, 400, "Invalid container ID")
 		return
 	}
 
-	project, err := h.ProjectRepository.FindByID(c.Request.Context(), projectID)
+	container, err := h.ContainerRepository.FindByID(c.Request.Context(), containerID)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Project not found"})
+		respondLookupError(c, err, "Container not found")
 		return
 	}
-
-	h.JobWorker.AddJob(&model.Job{
-		Name: fmt.Sprintf("Clone and build image from source for project %d", projectID),
-		Run: func(ctx context.Context, log *logger.Logger) error {
-			log.Info("Cloning repository from %s", body.GitURL)
-			dir, err := git.CloneRepository(body.GitURL, fmt.Sprintf("project-%d", projectID), body.AccessToken)
-			if err != nil {
-				return fmt.Errorf("failed to clone repository: %w", err)
-			}
-			log.Info("Successfully cloned repository to %s", dir)
-
-			log.Info("Checking for Compose file in cloned directory")
-			var composeFile model.ComposeFile
-			var parsedContainers []model.Container
-			var composeErr error
-			hasComposeFile := false
-
-			// Check for compose files
-			composePaths := []string{
-				filepath.Join(dir, "compose.yaml"),
-				filepath.Join(dir, "docker-compose.yaml"),
-			}
-			var composeContent []byte
-			for _, path := range composePaths {
-				if content, err := os.ReadFile(path); err == nil {
-					composeContent = content
-					hasComposeFile = true
-					log.Info("Found Compose file at %s", path)
-					break
-				}
-			}
-
-			if hasComposeFile {
-				composeFile, parsedContainers, composeErr = utils.ParseComposeFileFromBytes(composeContent, project)
-				if composeErr != nil {
-					return fmt.Errorf("failed to parse compose file: %w", composeErr)
-				}
-				log.Info("Successfully parsed Compose file with %d services", len(composeFile.Services))
-			}
-
-			// Case: Compose file exists (single or multiple services)
-			if hasComposeFile {
-				for serviceName, service := range composeFile.Services {
-					if service.Build != nil {
-						// Build service image from compose definition
-						buildContext := filepath.Join(dir, service.Build.Context)
-						dockerfile := service.Build.Dockerfile
-						if dockerfile == "" {
-							dockerfile = "Dockerfile"
-						}
-
-						// Determine image name (use compose image name or generate)
-						imageName := service.Image
-						if imageName == "" {
-							imageName = fmt.Sprintf("project-%d-%s", projectID, serviceName)
-						}
-
-						log.Info("Building image for service %s from %s", serviceName, buildContext)
-						if err := h.DockerClient.BuildImage(ctx, buildContext, dockerfile, imageName, log); err != nil {
-							return fmt.Errorf("failed to build image for service %s: %w", serviceName, err)
-						}
-						log.Info("Successfully built image %s", imageName)
-
-						// Update container model with actual image name
-						for i, c := range parsedContainers {
-							if c.Name == utils.FormatContainerName(project.Name, serviceName) {
-								parsedContainers[i].DockerImage = imageName
-							}
-						}
-					} else if service.Image == "" {
-						return fmt.Errorf("service %s has no image and no build context", serviceName)
-					}
-				}
-
-				// Create all containers from compose definition
-				for _, container := range parsedContainers {
-					if err := h.ContainerRepository.Create(ctx, &container); err != nil {
-						return fmt.Errorf("failed to create container %s: %w", container.Name, err)
-					}
-					log.Info("Created container %s", container.Name)
-				}
-			} else {
-				// Case: No compose file - build from root Dockerfile
-				imageName := fmt.Sprintf("project-%d-image", projectID)
-				log.Info("Building image from source directory %s", dir)
-				if err := h.DockerClient.BuildImage(ctx, dir, "Dockerfile", imageName, log); err != nil {
-					return fmt.Errorf("failed to build image from source: %w", err)
-				}
-				log.Info("Successfully built image %s", imageName)
-
-				// Create default container
-				container := model.Container{
-					ProjectID:   projectID,
-					Name:        utils.FormatContainerName(project.Name, "default"),
-					DockerImage: imageName,
-					Ports:       make(map[string]string),
-					Env:         make(map[string]string),
-					Volumes:     make(map[string]string),
-					Networks:    []string{},
-					NetworkMode: "bridge",
-				}
-				if err := h.ContainerRepository.Create(ctx, &container); err != nil {
-					return fmt.Errorf("failed to create container: %w", err)
-				}
-				log.Info("Created default container %s", container.Name)
-			}
-
-			return nil
-		},
-	}, nil)
-
-	c.JSON(200, gin.H{"message": "Job started"})
+	if container.ProjectID != projectID {
+		respondError(c, 404, "Container not found")
+		return
+	}
+	c.Next()
 }
 
-func (h *ContainerHandler) GetAllContainers(c *gin.Context) {
-	projectID, exists := utils.ParamUInt(c, "id")
+func (h *ContainerHandler) GetContainerByID(c *gin.Context) {
+	containerID, exists := utils.ParamUInt(c, "containerId")
 	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid project ID"})
+		respondError(c, 400, "Invalid container ID")
 		return
 	}
 
-	containers, err := h.ContainerRepository.FindAllByProjectID(c.Request.Context(), projectID)
+	container, err := h.ContainerRepository.FindByID(c.Request.Context(), containerID)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to retrieve containers"})
+		respondLookupError(c, err, "Container not found")
 		return
 	}
 
-	c.JSON(200, containers)
-}
-
-func (h *ContainerHandler) GetContainerByID(c *gin.Context) {
-	containerID, exists := utils.ParamUInt(c, "containerId")
-	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid container ID"})
+	project, err := h.ProjectRepository.FindByID(c.Request.Context(), container.ProjectID)
+	if err != nil {
+		respondLookupError(c, err, "Project not found")
 		return
 	}
-
-	container, err := h.ContainerRepository.FindByID(c.Request.Context(), containerID)
+	siblings, err := h.ContainerRepository.FindAllByProjectID(c.Request.Context(), container.ProjectID)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Container not found"})
+		respondError(c, 500, "Failed to retrieve containers")
 		return
 	}
-	c.JSON(200, container)
+	containers := []model.Container{*container}
+	fillPendingChanges(project, containers, siblings)
+
+	c.JSON(200, maskContainers(containers)[0])
 }
 
 func (h *ContainerHandler) UpdateContainer(c *gin.Context) {
 	projectID, exists := utils.ParamUInt(c, "id")
 	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid project ID"})
+		respondError(c, 400, "Invalid project ID")
 		return
 	}
 
 	containerID, exists := utils.ParamUInt(c, "containerId")
 	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid container ID"})
+		respondError(c, 400, "Invalid container ID")
 		return
 	}
 
 	var container model.Container
 	if err := c.ShouldBindJSON(&container); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid container data"})
+		respondInvalid(c, err, "Invalid container data")
 		return
 	}
 	container.ID = containerID
 	container.ProjectID = projectID
 
+	before, err := h.ContainerRepository.FindByID(c.Request.Context(), containerID)
+	if err != nil {
+		respondLookupError(c, err, "Container not found")
+		return
+	}
+	// The name is the name of the Docker container, it never changes
+	container.Name = before.Name
+	unmaskEnv(container.Env, before.Env)
+	if !allowHostAccess(c, &container, before) {
+		return
+	}
 	if err := h.ContainerRepository.Save(c.Request.Context(), &container); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to update container"})
+		respondError(c, 500, "Failed to update container")
 		return
 	}
+	auditChanges(c, before, container)
 }
 
+// checkContainerName responds with a 409 when a container of another project
+// has the name of the container, see nameInUseElsewhere.
+func (h *ContainerHandler) checkContainerName(c *gin.Context, container *model.Container) bool {
+	inUse, err := h.ContainerRepository.NameInUseElsewhere(c.Request.Context(), container.ProjectID, container.Name)
+	if err != nil {
+		respondError(c, 500, "Failed to check container names")
+		return false
+	}
+	if inUse {
+		respondError(c, 409, fmt.Sprintf("A container of another project is named %s", container.Name))
+		return false
+	}
+	return true
+}
+
+// nameInUseElsewhere returns an error when a container of another project,
+// including the trash, has the name of the container. Docker containers are
+// looked up by name alone, it would take over theirs.
+func (h *ContainerHandler) nameInUseElsewhere(ctx context.Context, container *model.Container) error {
+	inUse, err := h.ContainerRepository.NameInUseElsewhere(ctx, container.ProjectID, container.Name)
+	if err != nil {
+		return fmt.Errorf("failed to check the name of container %s: %w", container.Name, err)
+	}
+	if inUse {
+		return fmt.Errorf("a container of another project is named %s", container.Name)
+	}
+	return nil
+}
+
+// DeleteContainer moves the container to the trash and stops it. It is purged
+// once the trash purge delay is over.
 func (h *ContainerHandler) DeleteContainer(c *gin.Context) {
 	containerID, exists := utils.ParamUInt(c, "containerId")
 	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid container ID"})
+		respondError(c, 400, "Invalid container ID")
 		return
 	}
 
 	container, err := h.ContainerRepository.FindByID(c.Request.Context(), containerID)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Container not found"})
+		respondLookupError(c, err, "Container not found")
 		return
 	}
 
 	if err := h.ContainerRepository.Delete(c.Request.Context(), containerID); err != nil {
-		logger.Error("Failed to delete container", err)
-		c.JSON(500, gin.H{"error": "Failed to delete container"})
+		respondError(c, 500, "Failed to delete container")
 		return
 	}
 
+	// The job is not attached to the container: a purge deletes its jobs.
 	jobId, err := h.JobWorker.AddJob(&model.Job{
-		Name: fmt.Sprintf("Remove container %s", container.Name),
+		Name:      fmt.Sprintf("Stop deleted container %s", container.Name),
+		ProjectID: &container.ProjectID,
 		Run: func(ctx context.Context, log *logger.Logger) error {
-			if err := h.DockerClient.RemoveContainer(ctx, container.Name, log); err != nil {
-				return fmt.Errorf("failed to remove container %s: %w", container.Name, err)
-			}
-			return nil
+			return stopContainers(ctx, h.DockerClient, []model.Container{*container}, log)
 		},
-	}, &containerID)
+	}, nil)
 	if err != nil {
-		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to add job to remove container %s", container.Name)})
+		respondError(c, 500, fmt.Sprintf("Failed to add job to stop container %s", container.Name))
 		return
 	}
 
+	auditJob(c, jobId)
 	c.JSON(201, gin.H{
 		"job_id": jobId,
 	})
@@ -321,19 +261,19 @@ func (h *ContainerHandler) DeleteContainer(c *gin.Context) {
 func (h *ContainerHandler) GetContainerStatus(c *gin.Context) {
 	containerID, exists := utils.ParamUInt(c, "containerId")
 	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid container ID"})
+		respondError(c, 400, "Invalid container ID")
 		return
 	}
 
 	container, err := h.ContainerRepository.FindByID(c.Request.Context(), containerID)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Container not found"})
+		respondLookupError(c, err, "Container not found")
 		return
 	}
 
 	status, err := h.DockerClient.ContainerStatus(c.Request.Context(), container.Name)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to get container status"})
+		respondError(c, 500, "Failed to get container status")
 		return
 	}
 
@@ -343,49 +283,30 @@ func (h *ContainerHandler) GetContainerStatus(c *gin.Context) {
 func (h *ContainerHandler) StartContainer(c *gin.Context) {
 	containerID, exists := utils.ParamUInt(c, "containerId")
 	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid container ID"})
+		respondError(c, 400, "Invalid container ID")
 		return
 	}
 
 	container, err := h.ContainerRepository.FindByID(c.Request.Context(), containerID)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Container not found"})
-		return
-	}
-
-	containerIsCreated, err := h.DockerClient.ContainerExists(c.Request.Context(), container.Name, logger.GlobalLogger)
-	if err != nil {
-		logger.Error("Failed to check if container exists", err)
-		c.JSON(500, gin.H{"error": "Failed to check if container exists"})
+		respondLookupError(c, err, "Container not found")
 		return
 	}
 
 	jobId, err := h.JobWorker.AddJob(&model.Job{
-		Name: fmt.Sprintf("Start container %s", container.Name),
+		Name:      fmt.Sprintf("Start container %s", container.Name),
+		ProjectID: &container.ProjectID,
 		Run: func(ctx context.Context, log *logger.Logger) error {
-			if containerIsCreated {
-				log.Info("Container already exists, removing it before starting a new one")
-				if err := h.DockerClient.RemoveContainer(ctx, container.Name, log); err != nil {
-					return fmt.Errorf("failed to remove container %s: %w", container.Name, err)
-				}
-			}
-
-			if _, err := h.DockerClient.CreateContainer(ctx, container.Name, container.DockerImage, container.Ports, container.Env, container.Volumes, container.NetworkMode, log); err != nil {
-				return fmt.Errorf("failed to create container %s: %w", container.Name, err)
-			}
-
-			if _, err := h.DockerClient.StartContainer(ctx, container.Name, log); err != nil {
-				return fmt.Errorf("failed to start container %s: %w", container.Name, err)
-			}
-			return nil
+			return h.deployContainer(ctx, container, log)
 		},
 	}, &containerID)
 
 	if err != nil {
-		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to add job to start container %s", container.Name)})
+		respondError(c, 500, fmt.Sprintf("Failed to add job to start container %s", container.Name))
 		return
 	}
 
+	auditJob(c, jobId)
 	c.JSON(201, gin.H{
 		"job_id": jobId,
 	})
@@ -394,30 +315,32 @@ func (h *ContainerHandler) StartContainer(c *gin.Context) {
 func (h *ContainerHandler) StopContainer(c *gin.Context) {
 	containerID, exists := utils.ParamUInt(c, "containerId")
 	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid container ID"})
+		respondError(c, 400, "Invalid container ID")
 		return
 	}
 
 	container, err := h.ContainerRepository.FindByID(c.Request.Context(), containerID)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Container not found"})
+		respondLookupError(c, err, "Container not found")
 		return
 	}
 
 	jobId, err := h.JobWorker.AddJob(&model.Job{
-		Name: fmt.Sprintf("Stop container %s", container.Name),
+		Name:      fmt.Sprintf("Stop container %s", container.Name),
+		ProjectID: &container.ProjectID,
 		Run: func(ctx context.Context, log *logger.Logger) error {
 			if err := h.DockerClient.StopContainer(ctx, container.Name, log); err != nil {
 				return fmt.Errorf("failed to stop container %s: %w", container.Name, err)
 			}
-			return nil
+			return h.DockerClient.StopContainerIfExists(ctx, docker.ProxyName(container.Name), log)
 		},
 	}, &containerID)
 	if err != nil {
-		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to add job to stop container %s", container.Name)})
+		respondError(c, 500, fmt.Sprintf("Failed to add job to stop container %s", container.Name))
 		return
 	}
 
+	auditJob(c, jobId)
 	c.JSON(201, gin.H{
 		"job_id": jobId,
 	})
@@ -426,13 +349,13 @@ func (h *ContainerHandler) StopContainer(c *gin.Context) {
 func (h *ContainerHandler) GetContainerLogs(c *gin.Context) {
 	containerID, exists := utils.ParamUInt(c, "containerId")
 	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid container ID"})
+		respondError(c, 400, "Invalid container ID")
 		return
 	}
 
 	container, err := h.ContainerRepository.FindByID(c.Request.Context(), containerID)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Container not found"})
+		respondLookupError(c, err, "Container not found")
 		return
 	}
 
@@ -440,7 +363,7 @@ func (h *ContainerHandler) GetContainerLogs(c *gin.Context) {
 	logs, err := h.DockerClient.GetContainerLogs(c.Request.Context(), container.Name, tail)
 	if err != nil {
 		logger.Error("Failed to get container logs", err)
-		c.JSON(500, gin.H{"error": "Failed to get container logs"})
+		respondError(c, 500, "Failed to get container logs")
 		return
 	}
 
@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/docker"
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/infra/secrets"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/utils"
+	"context"
+	"fmt"
+	"strconv"
+
+	dContainer "github.com/docker/docker/api/types/container"
+)
+
+// deployContainer brings the Docker container in line with the current
+// configuration. A container created from the same configuration and image is
+// simply started, otherwise it is replaced by a new one, following the deploy
+// strategy of the container.
+func (h *ContainerHandler) deployContainer(ctx context.Context, container *model.Container, log *logger.Logger) error {
+	project, err := h.ProjectRepository.FindByID(ctx, container.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to find project of container %s: %w", container.Name, err)
+	}
+	siblings, err := h.ContainerRepository.FindAllByProjectID(ctx, container.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to list containers of project %s: %w", project.Name, err)
+	}
+	config, err := desiredConfig(project, container, siblings)
+	if err != nil {
+		return err
+	}
+	env, revealed, err := h.resolveSecrets(ctx, project.ID, config.Env)
+	if err != nil {
+		return err
+	}
+	log = log.WithRedaction(revealed, secrets.Mask)
+
+	spec := docker.ContainerSpec{
+		Name:        container.Name,
+		Image:       config.Image,
+		Ports:       config.Ports,
+		Env:         env,
+		Volumes:     config.Volumes,
+		NetworkMode: config.NetworkMode,
+		Network:     utils.FormatNetworkName(project.ID),
+	}
+	// Blue/green containers publish their ports through a proxy, which keeps
+	// them bound while the container is replaced.
+	proxied := container.DeployStrategy == model.DeployBlueGreen && spec.NetworkMode == "bridge" && len(spec.Ports) > 0
+	if proxied {
+		spec.Ports = nil
+	}
+	if err := h.DockerClient.EnsureNetwork(ctx, spec.Network, log); err != nil {
+		return err
+	}
+	if err := h.DockerClient.PullImage(ctx, spec.Image, log); err != nil {
+		return err
+	}
+	imageID, err := h.DockerClient.ImageID(ctx, spec.Image)
+	if err != nil {
+		return err
+	}
+	hash := spec.Hash(imageID)
+	spec.Labels = managedLabels(container, hash)
+	if !proxied {
+		// the proxy left by the blue_green strategy holds the ports
+		h.removeIfExists(ctx, docker.ProxyName(container.Name), log)
+	}
+
+	labels, exists, err := h.DockerClient.ContainerLabels(ctx, container.Name)
+	if err != nil {
+		return err
+	}
+
+	running := false
+	if exists {
+		status, err := h.DockerClient.ContainerStatus(ctx, container.Name)
+		if err != nil {
+			return err
+		}
+		running = status == dContainer.StateRunning
+	}
+
+	switch {
+	case exists && labels[docker.ConfigHashLabel] == hash:
+		log.Info("Configuration of container %s is unchanged, starting the existing container", container.Name)
+	case running && container.DeployStrategy == model.DeployBlueGreen && spec.NetworkMode != "host":
+		if err := h.swapContainer(ctx, container, spec, log); err != nil {
+			return err
+		}
+	default:
+		if running && container.DeployStrategy == model.DeployBlueGreen {
+			log.Info("Blue/green deployment is not possible in host network mode, recreating the container")
+		}
+		if exists {
+			log.Info("Configuration of container %s changed, recreating it", container.Name)
+			if err := h.DockerClient.RemoveContainer(ctx, container.Name, log); err != nil {
+				return fmt.Errorf("failed to remove container %s: %w", container.Name, err)
+			}
+		}
+		if _, err := h.DockerClient.CreateContainer(ctx, spec, log); err != nil {
+			return fmt.Errorf("failed to create container %s: %w", container.Name, err)
+		}
+	}
+
+	if _, err := h.DockerClient.StartContainer(ctx, container.Name, log); err != nil {
+		return fmt.Errorf("failed to start container %s: %w", container.Name, err)
+	}
+	if proxied {
+		if err := h.ensureProxy(ctx, container, spec.Network, config.Ports, log); err != nil {
+			return err
+		}
+	}
+
+	if err := h.ContainerRepository.SetAppliedConfig(ctx, container.ID, config); err != nil {
+		log.Error("Failed to record the configuration of container %s: %v", container.Name, err)
+	}
+	return nil
+}
+
+// swapContainer replaces the running container with a new one created from
+// spec without downtime (blue/green). The new container first runs under a
+// temporary name until it is healthy, the old one being kept until then. It
+// answers to the name of the container on the project network from the
+// start, which is what the other services and the ports proxy connect to.
+// The old container is renamed instead of removed until the switch is done,
+// so it can be restored.
+func (h *ContainerHandler) swapContainer(ctx context.Context, container *model.Container, spec docker.ContainerSpec, log *logger.Logger) error {
+	nextName := container.Name + "_next"
+	previousName := container.Name + "_previous"
+
+	// leftovers of an interrupted deployment
+	h.removeIfExists(ctx, nextName, log)
+	h.removeIfExists(ctx, previousName, log)
+
+	log.Info("[blue/green 1/4] Starting the new container as %s", nextName)
+	next := spec
+	next.Name = nextName
+	next.VolumeOwner = container.Name
+	next.Ports = nil
+	next.Aliases = []string{container.Name}
+	if _, err := h.DockerClient.CreateContainer(ctx, next, log); err != nil {
+		return fmt.Errorf("failed to create container %s: %w", nextName, err)
+	}
+	if _, err := h.DockerClient.StartContainer(ctx, nextName, log); err != nil {
+		h.removeIfExists(ctx, nextName, log)
+		return fmt.Errorf("failed to start container %s: %w", nextName, err)
+	}
+
+	log.Info("[blue/green 2/4] Waiting for %s to be healthy", nextName)
+	if err := h.DockerClient.WaitHealthy(ctx, nextName, container.HealthProbe, log); err != nil {
+		log.Error("New container is not healthy (%v), rolling back: %s keeps running", err, container.Name)
+		h.removeIfExists(ctx, nextName, log)
+		return fmt.Errorf("deployment rolled back: %w", err)
+	}
+
+	log.Info("[blue/green 3/4] Stopping the old container %s", container.Name)
+	if err := h.DockerClient.StopContainer(ctx, container.Name, log); err != nil {
+		h.removeIfExists(ctx, nextName, log)
+		return err
+	}
+	if err := h.DockerClient.RenameContainer(ctx, container.Name, previousName, log); err != nil {
+		h.removeIfExists(ctx, nextName, log)
+		h.DockerClient.StartContainer(ctx, container.Name, log)
+		return err
+	}
+
+	log.Info("[blue/green 4/4] Switching %s to the new container", container.Name)
+	err := h.DockerClient.RenameContainer(ctx, nextName, container.Name, log)
+	if err != nil {
+		log.Error("Failed to switch to the new container (%v), rolling back to the old one", err)
+		h.removeIfExists(ctx, nextName, log)
+		h.removeIfExists(ctx, container.Name, log)
+		if err := h.DockerClient.RenameContainer(ctx, previousName, container.Name, log); err != nil {
+			return fmt.Errorf("rollback failed, the old container is left as %s: %w", previousName, err)
+		}
+		if _, err := h.DockerClient.StartContainer(ctx, container.Name, log); err != nil {
+			return fmt.Errorf("rollback failed: %w", err)
+		}
+		return fmt.Errorf("deployment rolled back: %w", err)
+	}
+
+	h.removeIfExists(ctx, previousName, log)
+	log.Info("Container %s now runs the new configuration", container.Name)
+	return nil
+}
+
+// ensureProxy creates the proxy publishing the ports of a blue/green
+// container, recreating it when the ports changed, and starts it. The ports
+// are only closed while the proxy is recreated.
+func (h *ContainerHandler) ensureProxy(ctx context.Context, container *model.Container, network string, ports map[string]string, log *logger.Logger) error {
+	spec, err := docker.ProxySpec(container.Name, network, ports)
+	if err != nil {
+		return fmt.Errorf("failed to publish the ports of container %s: %w", container.Name, err)
+	}
+	if err := h.DockerClient.PullImage(ctx, spec.Image, log); err != nil {
+		return err
+	}
+	imageID, err := h.DockerClient.ImageID(ctx, spec.Image)
+	if err != nil {
+		return err
+	}
+	hash := spec.Hash(imageID)
+	spec.Labels = managedLabels(container, hash)
+	spec.Labels[docker.ProxyLabel] = container.Name
+
+	labels, exists, err := h.DockerClient.ContainerLabels(ctx, spec.Name)
+	if err != nil {
+		return err
+	}
+	if !exists || labels[docker.ConfigHashLabel] != hash {
+		if exists {
+			log.Info("Published ports of container %s changed, recreating its proxy", container.Name)
+			if err := h.DockerClient.RemoveContainer(ctx, spec.Name, log); err != nil {
+				return err
+			}
+		}
+		if _, err := h.DockerClient.CreateContainer(ctx, spec, log); err != nil {
+			return fmt.Errorf("failed to create proxy %s: %w", spec.Name, err)
+		}
+	}
+	if _, err := h.DockerClient.StartContainer(ctx, spec.Name, log); err != nil {
+		return fmt.Errorf("failed to start proxy %s: %w", spec.Name, err)
+	}
+	return nil
+}
+
+// managedLabels returns the labels of the Docker containers of a container.
+func managedLabels(container *model.Container, hash string) map[string]string {
+	return map[string]string{
+		docker.ConfigHashLabel: hash,
+		docker.ManagedLabel:    "true",
+		docker.ProjectLabel:    strconv.FormatUint(uint64(container.ProjectID), 10),
+		docker.ContainerLabel:  strconv.FormatUint(uint64(container.ID), 10),
+	}
+}
+
+func (h *ContainerHandler) removeIfExists(ctx context.Context, name string, log *logger.Logger) {
+	if err := h.DockerClient.RemoveContainerIfExists(ctx, name, log); err != nil {
+		log.Error("%v", err)
+	}
+}
+
+// desiredConfig returns the configuration the container should run with.
+func desiredConfig(project *model.Project, container *model.Container, siblings []model.Container) (model.ContainerConfig, error) {
+	env, err := effectiveEnv(project, container, siblings)
+	if err != nil {
+		return model.ContainerConfig{}, err
+	}
+	return model.ContainerConfig{
+		Image:       container.DockerImage,
+		Ports:       container.Ports,
+		Env:         env,
+		Volumes:     container.Volumes,
+		NetworkMode: container.NetworkMode,
+	}, nil
+}
+
+// fillPendingChanges compares the configuration of the containers with the
+// one their Docker container was created from.
+func fillPendingChanges(project *model.Project, containers []model.Container, siblings []model.Container) {
+	for i := range containers {
+		container := &containers[i]
+		if container.AppliedConfig == nil {
+			// never started by Axolotl
+			container.PendingChanges = true
+			continue
+		}
+		config, err := desiredConfig(project, container, siblings)
+		if err != nil {
+			container.PendingChanges = true
+			continue
+		}
+		container.Changes = container.AppliedConfig.Diff(config)
+		container.PendingChanges = len(container.Changes) > 0 || container.RestartRequired
+	}
+}
+
+// resolveSecrets returns env with the secret references replaced by the secret
+// values, along with the revealed values so that they can be redacted from
+// the logs. The secrets must be allowed for the project. It must only be
+// called from jobs, right before handing the env to Docker.
+func (h *ContainerHandler) resolveSecrets(ctx context.Context, projectID uint, env map[string]string) (map[string]string, []string, error) {
+	resolved := make(map[string]string, len(env))
+	var revealed []string
+	for key, value := range env {
+		if name, ok := secrets.Ref(value); ok {
+			secret, err := h.SecretRepository.Reveal(ctx, name, projectID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("env %s: %w", key, err)
+			}
+			value = secret
+			revealed = append(revealed, secret)
+		}
+		resolved[key] = value
+	}
+	return resolved, revealed, nil
+}
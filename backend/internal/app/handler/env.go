@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/utils"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serviceRefPattern matches the references to another service of the project
+// in env values: ${services.<name>.host} and ${services.<name>.port}.
+var serviceRefPattern = regexp.MustCompile(`\$\{services\.([A-Za-z0-9_.-]+?)\.(host|port)\}`)
+
+type ContainerEnvResponse struct {
+	ContainerID uint              `json:"container_id"`
+	Name        string            `json:"name"`
+	Env         map[string]string `json:"env"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// GetEffectiveEnv returns the environment each container of the project
+// starts with. Secret references are left as is, the other values masked.
+func (h *ContainerHandler) GetEffectiveEnv(c *gin.Context) {
+	projectID, exists := utils.ParamUInt(c, "id")
+	if !exists {
+		respondError(c, 400, "Invalid project ID")
+		return
+	}
+
+	project, err := h.ProjectRepository.FindByID(c.Request.Context(), projectID)
+	if err != nil {
+		respondLookupError(c, err, "Project not found")
+		return
+	}
+
+	containers, err := h.ContainerRepository.FindAllByProjectID(c.Request.Context(), projectID)
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve containers")
+		return
+	}
+
+	response := make([]ContainerEnvResponse, 0, len(containers))
+	for _, container := range containers {
+		entry := ContainerEnvResponse{ContainerID: container.ID, Name: container.Name}
+		entry.Env, err = effectiveEnv(project, &container, containers)
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		raw := maps.Clone(project.Env)
+		if raw == nil {
+			raw = map[string]string{}
+		}
+		maps.Copy(raw, container.Env)
+		entry.Env = maskEnv(entry.Env, raw)
+		response = append(response, entry)
+	}
+	c.JSON(200, response)
+}
+
+// effectiveEnv merges the project env and the container env, the container
+// winning, and resolves the references to the other services of the project.
+func effectiveEnv(project *model.Project, container *model.Container, siblings []model.Container) (map[string]string, error) {
+	env := make(map[string]string, len(project.Env)+len(container.Env))
+	maps.Copy(env, project.Env)
+	maps.Copy(env, container.Env)
+
+	for key, value := range env {
+		var refErr error
+		env[key] = serviceRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+			match := serviceRefPattern.FindStringSubmatch(ref)
+			resolved, err := resolveServiceRef(project, siblings, match[1], match[2])
+			if err != nil && refErr == nil {
+				refErr = fmt.Errorf("env %s: %w", key, err)
+			}
+			return resolved
+		})
+		if refErr != nil {
+			return nil, refErr
+		}
+	}
+	return env, nil
+}
+
+// resolveServiceRef returns the host (the container name, resolvable on the
+// project network) or the first container port of a service.
+func resolveServiceRef(project *model.Project, siblings []model.Container, service string, field string) (string, error) {
+	name := utils.FormatContainerName(project.Name, service)
+	i := slices.IndexFunc(siblings, func(c model.Container) bool { return c.Name == name })
+	if i < 0 {
+		return "", fmt.Errorf("service %s not found in project %s", service, project.Name)
+	}
+	target := siblings[i]
+
+	if field == "host" {
+		return target.Name, nil
+	}
+
+	ports := make([]int, 0, len(target.Ports))
+	for _, port := range target.Ports {
+		if p, err := strconv.Atoi(port); err == nil {
+			ports = append(ports, p)
+		}
+	}
+	if len(ports) == 0 {
+		return "", fmt.Errorf("service %s exposes no port", service)
+	}
+	return strconv.Itoa(slices.Min(ports)), nil
+}
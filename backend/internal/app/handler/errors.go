@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/logger"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// RequestIDHeader carries the ID of a request, given by a proxy or generated.
+const RequestIDHeader = "X-Request-ID"
+
+const requestIDContextKey = "request_id"
+
+// requestIDPattern restricts the IDs accepted from clients, as they end up in
+// the responses and the logs.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ErrorCode is the stable, machine readable code of an error, unlike its
+// message.
+type ErrorCode string
+
+const (
+	CodeInvalidRequest   ErrorCode = "invalid_request"
+	CodeValidationFailed ErrorCode = "validation_failed"
+	CodeUnauthorized     ErrorCode = "unauthorized"
+	CodeForbidden        ErrorCode = "forbidden"
+	CodeNotFound         ErrorCode = "not_found"
+	CodeConflict         ErrorCode = "conflict"
+	CodeTooLarge         ErrorCode = "too_large"
+	CodeRateLimited      ErrorCode = "rate_limited"
+	CodeInternal         ErrorCode = "internal_error"
+	CodeUpstream         ErrorCode = "upstream_error"
+)
+
+var errorCodes = map[int]ErrorCode{
+	400: CodeInvalidRequest,
+	401: CodeUnauthorized,
+	403: CodeForbidden,
+	404: CodeNotFound,
+	409: CodeConflict,
+	413: CodeTooLarge,
+	429: CodeRateLimited,
+	500: CodeInternal,
+	502: CodeUpstream,
+}
+
+// ErrorResponse is the body of every error of the API.
+type ErrorResponse struct {
+	// Error is the message, meant for humans.
+	Error string    `json:"error"`
+	Code  ErrorCode `json:"code"`
+	// Fields lists the invalid fields of the request body or query.
+	Fields []FieldError `json:"fields,omitempty"`
+	// Details holds data specific to the error, e.g. the containers still
+	// referencing a secret.
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id"`
+}
+
+type FieldError struct {
+	// Field is the JSON path of the field, e.g. health_probe.port
+	Field string `json:"field"`
+	// Rule is the failed binding rule, e.g. required, or type
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// Validation errors name the fields as in JSON
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// RequestID returns a middleware giving every request an ID, returned in the
+// X-Request-ID header and in the errors.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDContextKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	value := make([]byte, 8)
+	_, _ = rand.Read(value)
+	return hex.EncodeToString(value)
+}
+
+// Recover returns a middleware answering the panics of handlers with an
+// internal error.
+func Recover() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		logger.Error("Handler panicked:", fmt.Errorf("%v", recovered))
+		respondError(c, 500, "Internal server error")
+	})
+}
+
+// RespondNotFound answers the requests of unknown API routes.
+func RespondNotFound(c *gin.Context) {
+	respondError(c, 404, "Route not found")
+}
+
+// respondError aborts the request with an error.
+func respondError(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, newErrorResponse(c, status, message))
+}
+
+// newErrorResponse returns the error of the request, whose code follows from
+// the status.
+func newErrorResponse(c *gin.Context, status int, message string) ErrorResponse {
+	code, ok := errorCodes[status]
+	if !ok {
+		code = CodeInternal
+		if status < 500 {
+			code = CodeInvalidRequest
+		}
+	}
+	return ErrorResponse{
+		Error:     message,
+		Code:      code,
+		RequestID: c.GetString(requestIDContextKey),
+	}
+}
+
+// respondInvalid aborts with a 400 for a request that failed to bind,
+// detailing the invalid fields.
+func respondInvalid(c *gin.Context, err error, message string) {
+	response := newErrorResponse(c, 400, message)
+	if response.Fields = fieldErrors(err); len(response.Fields) > 0 {
+		response.Code = CodeValidationFailed
+	}
+	c.AbortWithStatusJSON(400, response)
+}
+
+// respondLookupError aborts with a 404 when err is a missing record, and with
+// a 500 for any other failure.
+func respondLookupError(c *gin.Context, err error, notFound string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		respondError(c, 404, notFound)
+		return
+	}
+	logger.Error("Failed to look up "+c.FullPath()+":", err)
+	respondError(c, 500, "Internal server error")
+}
+
+// fieldErrors lists the fields a binding error is about.
+func fieldErrors(err error) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]FieldError, 0, len(validationErrors))
+		for _, fieldErr := range validationErrors {
+			// The namespace starts with the name of the bound struct
+			_, field, _ := strings.Cut(fieldErr.Namespace(), ".")
+			fields = append(fields, FieldError{
+				Field:   field,
+				Rule:    fieldErr.Tag(),
+				Message: ruleMessage(fieldErr),
+			})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("Must be a %s", typeErr.Type),
+		}}
+	}
+	return nil
+}
+
+func ruleMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "Is required"
+	case "oneof":
+		return "Must be one of: " + strings.ReplaceAll(err.Param(), " ", ", ")
+	case "min":
+		return "Must be at least " + err.Param()
+	case "max":
+		return "Must be at most " + err.Param()
+	default:
+		return fmt.Sprintf("Fails the %s rule", err.Tag())
+	}
+}
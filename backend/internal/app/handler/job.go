@@ -2,36 +2,47 @@ package handler
 
 import (
 	"axolotl-cloud/infra/worker"
+	"axolotl-cloud/internal/app/model"
 	"axolotl-cloud/internal/app/repository"
 	"axolotl-cloud/utils"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 )
 
 type JobHandler struct {
 	JobRepository *repository.JobRepository
+	Access        *Access
 	Worker        *worker.Worker
 }
 
 func (h *JobHandler) GetAllJobs(c *gin.Context) {
 	jobs, err := h.JobRepository.GetAll()
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to retrieve jobs"})
+		respondError(c, 500, "Failed to retrieve jobs")
 		return
 	}
+	readable := h.Access.JobFilter(c)
+	jobs = slices.DeleteFunc(jobs, func(job model.Job) bool {
+		return !readable(&job)
+	})
 	c.JSON(200, jobs)
 }
 
 func (h *JobHandler) GetJobByID(c *gin.Context) {
 	id, exists := utils.ParamUInt(c, "id")
 	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid job ID"})
+		respondError(c, 400, "Invalid job ID")
 		return
 	}
 
 	job, err := h.JobRepository.GetByID(id)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Job not found"})
+		respondLookupError(c, err, "Job not found")
+		return
+	}
+	if !h.Access.JobFilter(c)(job) {
+		respondError(c, 404, "Job not found")
 		return
 	}
 	c.JSON(200, job)
@@ -40,12 +51,12 @@ func (h *JobHandler) GetJobByID(c *gin.Context) {
 func (h *JobHandler) DeleteJob(c *gin.Context) {
 	id, exists := utils.ParamUInt(c, "id")
 	if !exists {
-		c.JSON(400, gin.H{"error": "Invalid job ID"})
+		respondError(c, 400, "Invalid job ID")
 		return
 	}
 
 	if err := h.JobRepository.RemoveByID(id); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to delete job"})
+		respondError(c, 500, "Failed to delete job")
 		return
 	}
 
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/secrets"
+	"axolotl-cloud/internal/app/model"
+	"strings"
+)
+
+// Env values are masked in the API responses: only the secret references and
+// the references to other services are shown, any other value may be a
+// password typed in before the secrets store existed. Clients send the masked
+// values back unchanged to keep them.
+
+// maskEnv returns env with its values masked. raw holds the values before
+// their service references were resolved, env itself otherwise.
+func maskEnv(env map[string]string, raw map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	masked := make(map[string]string, len(env))
+	for key, value := range env {
+		if value != "" && !visibleEnvValue(raw[key]) {
+			value = secrets.Mask
+		}
+		masked[key] = value
+	}
+	return masked
+}
+
+// visibleEnvValue reports whether an env value can be shown: a secret
+// reference, or references to services only.
+func visibleEnvValue(value string) bool {
+	if _, ok := secrets.Ref(value); ok {
+		return true
+	}
+	return serviceRefPattern.MatchString(value) && serviceRefPattern.ReplaceAllString(value, "") == ""
+}
+
+// unmaskEnv sets back the stored value of the variables a client sent masked.
+func unmaskEnv(env map[string]string, stored map[string]string) {
+	for key, value := range env {
+		if previous, ok := stored[key]; ok && value == secrets.Mask {
+			env[key] = previous
+		}
+	}
+}
+
+func maskContainer(container *model.Container) {
+	container.Env = maskEnv(container.Env, container.Env)
+	container.Changes = maskChanges(container.Changes, "env.")
+}
+
+func maskContainers(containers []model.Container) []model.Container {
+	for i := range containers {
+		maskContainer(&containers[i])
+	}
+	return containers
+}
+
+func maskProject(project *model.Project) {
+	project.Env = maskEnv(project.Env, project.Env)
+	maskContainers(project.Containers)
+}
+
+func maskProjects(projects []model.Project) []model.Project {
+	for i := range projects {
+		maskProject(&projects[i])
+	}
+	return projects
+}
+
+func maskRevisions(revisions []model.ContainerRevision) []model.ContainerRevision {
+	for i := range revisions {
+		revisions[i].Snapshot.Env = maskEnv(revisions[i].Snapshot.Env, revisions[i].Snapshot.Env)
+	}
+	return revisions
+}
+
+// maskChanges masks the values of the changes of the env, whose fields start
+// with prefix.
+func maskChanges(changes []model.ConfigChange, prefix string) []model.ConfigChange {
+	for i, change := range changes {
+		if !strings.HasPrefix(change.Field, prefix) {
+			continue
+		}
+		changes[i].Old = maskValue(change.Old)
+		changes[i].New = maskValue(change.New)
+	}
+	return changes
+}
+
+func maskValue(value *string) *string {
+	if value == nil || *value == "" || visibleEnvValue(*value) {
+		return value
+	}
+	masked := secrets.Mask
+	return &masked
+}
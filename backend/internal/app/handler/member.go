@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *ProjectHandler) GetProjectMembers(c *gin.Context) {
+	id, exists := utils.ParamUInt(c, "id")
+	if !exists {
+		respondError(c, 400, "Invalid project ID")
+		return
+	}
+
+	members, err := h.MemberRepository.FindAllByProjectID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve members")
+		return
+	}
+	c.JSON(200, members)
+}
+
+// SetProjectMember adds a user to the project, or changes its role.
+func (h *ProjectHandler) SetProjectMember(c *gin.Context) {
+	id, exists := utils.ParamUInt(c, "id")
+	if !exists {
+		respondError(c, 400, "Invalid project ID")
+		return
+	}
+	userID, exists := utils.ParamUInt(c, "userId")
+	if !exists {
+		respondError(c, 400, "Invalid user ID")
+		return
+	}
+
+	var request RequestSetMember
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid input")
+		return
+	}
+
+	user, err := h.UserRepository.FindByID(c.Request.Context(), userID)
+	if err != nil {
+		respondLookupError(c, err, "User not found")
+		return
+	}
+	if request.Role != model.RoleOwner {
+		if status, message := h.checkLastOwner(c, id, userID); status != 0 {
+			respondError(c, status, message)
+			return
+		}
+	}
+
+	previous, err := h.MemberRepository.FindRole(c.Request.Context(), id, userID)
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve member")
+		return
+	}
+	member := &model.ProjectMember{ProjectID: id, UserID: userID, Role: request.Role}
+	if err := h.MemberRepository.Save(c.Request.Context(), member); err != nil {
+		respondError(c, 500, "Failed to save member")
+		return
+	}
+	auditChanges(c, gin.H{"role": previous}, gin.H{"role": member.Role})
+	member.User = user
+	c.JSON(200, member)
+}
+
+func (h *ProjectHandler) RemoveProjectMember(c *gin.Context) {
+	id, exists := utils.ParamUInt(c, "id")
+	if !exists {
+		respondError(c, 400, "Invalid project ID")
+		return
+	}
+	userID, exists := utils.ParamUInt(c, "userId")
+	if !exists {
+		respondError(c, 400, "Invalid user ID")
+		return
+	}
+
+	if status, message := h.checkLastOwner(c, id, userID); status != 0 {
+		respondError(c, status, message)
+		return
+	}
+	if err := h.MemberRepository.Delete(c.Request.Context(), id, userID); err != nil {
+		respondError(c, 500, "Failed to remove member")
+		return
+	}
+	c.Status(204)
+}
+
+// checkLastOwner returns a 409 when the user is the last owner of the
+// project, who would leave it without anyone to manage it.
+func (h *ProjectHandler) checkLastOwner(c *gin.Context, projectID uint, userID uint) (int, string) {
+	role, err := h.MemberRepository.FindRole(c.Request.Context(), projectID, userID)
+	if err != nil {
+		return 500, "Failed to retrieve members"
+	}
+	if role != model.RoleOwner {
+		return 0, ""
+	}
+	count, err := h.MemberRepository.CountOwners(c.Request.Context(), projectID)
+	if err != nil {
+		return 500, "Failed to retrieve members"
+	}
+	if count <= 1 {
+		return 409, "The last owner of the project cannot be removed"
+	}
+	return 0, ""
+}
@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/infra/oidc"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/internal/app/repository"
+	"axolotl-cloud/utils"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// oidcFlowCookie holds the state, nonce and PKCE verifier of a single sign-on
+// login between the redirection to the provider and the callback.
+const oidcFlowCookie = "axolotl_oidc_flow"
+
+// OIDCHandler signs users in with an OpenID Connect provider. Users are
+// created on their first login, and their roles follow the group mappings on
+// every login.
+type OIDCHandler struct {
+	Provider          *oidc.Provider
+	Auth              *AuthHandler
+	MappingRepository *repository.OIDCGroupMappingRepository
+	MemberRepository  *repository.ProjectMemberRepository
+	ProjectRepository *repository.ProjectRepository
+}
+
+type RequestCreateGroupMapping struct {
+	Group     string            `json:"group" binding:"required"`
+	ProjectID *uint             `json:"project_id"`
+	Role      model.ProjectRole `json:"role" binding:"omitempty,oneof=viewer deployer owner"`
+}
+
+// Login redirects the browser to the provider.
+func (h *OIDCHandler) Login(c *gin.Context) {
+	if !h.Provider.Enabled() {
+		respondError(c, 404, "Single sign-on is not configured")
+		return
+	}
+
+	url, flow, err := h.Provider.Start(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to start single sign-on:", err)
+		respondError(c, 502, "Failed to reach the identity provider")
+		return
+	}
+	value, err := json.Marshal(flow)
+	if err != nil {
+		respondError(c, 500, "Failed to start single sign-on")
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcFlowCookie, base64.RawURLEncoding.EncodeToString(value), 600, loginCookiePath, "", secureRequest(c), true)
+	c.Redirect(http.StatusFound, url)
+}
+
+// Callback ends the login started by Login: it verifies the ID token,
+// provisions the user and its roles, then starts a session.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if !h.Provider.Enabled() {
+		respondError(c, 404, "Single sign-on is not configured")
+		return
+	}
+	if message := c.Query("error"); message != "" {
+		if description := c.Query("error_description"); description != "" {
+			message = description
+		}
+		respondError(c, 401, "The identity provider refused the login: "+message)
+		return
+	}
+
+	var flow *oidc.Flow
+	if raw, err := c.Cookie(oidcFlowCookie); err == nil {
+		if value, err := base64.RawURLEncoding.DecodeString(raw); err == nil {
+			_ = json.Unmarshal(value, &flow)
+		}
+	}
+	c.SetCookie(oidcFlowCookie, "", -1, loginCookiePath, "", secureRequest(c), true)
+
+	identity, err := h.Provider.Finish(c.Request.Context(), flow, c.Query("state"), c.Query("code"))
+	if err != nil {
+		logger.Error("Single sign-on failed:", err)
+		respondError(c, 401, "Single sign-on failed, please retry")
+		return
+	}
+
+	user, status, message := h.provision(c, identity)
+	if status != 0 {
+		respondError(c, status, message)
+		return
+	}
+	if err := h.syncRoles(c, user, identity.Groups); err != nil {
+		logger.Error("Failed to apply group mappings:", err)
+		respondError(c, 500, "Failed to apply group mappings")
+		return
+	}
+
+	if err := h.Auth.startSession(c, user); err != nil {
+		respondError(c, 500, "Failed to create session")
+		return
+	}
+	c.Redirect(http.StatusFound, "/")
+}
+
+// provision returns the user of the identity, created on its first login. A
+// local account with the same username is never taken over.
+func (h *OIDCHandler) provision(c *gin.Context, identity *oidc.Identity) (*model.User, int, string) {
+	ctx := c.Request.Context()
+	subject := identity.Issuer + "|" + identity.Subject
+
+	user, err := h.Auth.UserRepository.FindByOIDCSubject(ctx, subject)
+	if err == nil {
+		return user, 0, ""
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, 500, "Failed to retrieve user"
+	}
+
+	if _, err := h.Auth.UserRepository.FindByUsername(ctx, identity.Username); err == nil {
+		return nil, 409, "A user named " + identity.Username + " already exists"
+	}
+	user = &model.User{Username: identity.Username, OIDCSubject: &subject}
+	if err := h.Auth.UserRepository.Create(ctx, user); err != nil {
+		return nil, 500, "Failed to create user"
+	}
+	logger.Info("User %s created by single sign-on", user.Username)
+	return user, 0, ""
+}
+
+// syncRoles replaces the memberships of the user coming from its groups. The
+// admin flag follows the groups too, as soon as a group is mapped to admin.
+func (h *OIDCHandler) syncRoles(c *gin.Context, user *model.User, groups []string) error {
+	ctx := c.Request.Context()
+	mappings, err := h.MappingRepository.FindAllByGroups(ctx, groups)
+	if err != nil {
+		return err
+	}
+
+	admin := false
+	roles := map[uint]model.ProjectRole{}
+	for _, mapping := range mappings {
+		if mapping.ProjectID == nil {
+			admin = true
+			continue
+		}
+		if role := roles[*mapping.ProjectID]; !role.Includes(mapping.Role) {
+			roles[*mapping.ProjectID] = mapping.Role
+		}
+	}
+	members := make([]model.ProjectMember, 0, len(roles))
+	for projectID, role := range roles {
+		members = append(members, model.ProjectMember{ProjectID: projectID, Role: role})
+	}
+	if err := h.MemberRepository.ReplaceManaged(ctx, user.ID, members); err != nil {
+		return err
+	}
+
+	adminMappings, err := h.MappingRepository.CountAdminMappings(ctx)
+	if err != nil {
+		return err
+	}
+	if adminMappings > 0 && user.Admin != admin {
+		if err := h.Auth.UserRepository.SetAdmin(ctx, user.ID, admin); err != nil {
+			return err
+		}
+		user.Admin = admin
+	}
+	return nil
+}
+
+func (h *OIDCHandler) GetAllGroupMappings(c *gin.Context) {
+	mappings, err := h.MappingRepository.FindAll(c.Request.Context())
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve group mappings")
+		return
+	}
+	c.JSON(200, mappings)
+}
+
+func (h *OIDCHandler) CreateGroupMapping(c *gin.Context) {
+	var request RequestCreateGroupMapping
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid input")
+		return
+	}
+
+	mapping := model.OIDCGroupMapping{Group: request.Group, ProjectID: request.ProjectID}
+	if request.ProjectID != nil {
+		if request.Role == "" {
+			respondError(c, 400, "A role is required for a project")
+			return
+		}
+		if _, err := h.ProjectRepository.FindByID(c.Request.Context(), *request.ProjectID); err != nil {
+			respondError(c, 400, "Project not found")
+			return
+		}
+		mapping.Role = request.Role
+	}
+
+	if err := h.MappingRepository.Create(c.Request.Context(), &mapping); err != nil {
+		respondError(c, 500, "Failed to create group mapping")
+		return
+	}
+	auditTarget(c, mapping.ID)
+	c.JSON(201, mapping)
+}
+
+// DeleteGroupMapping removes a mapping, the roles it granted are removed on
+// the next login of each user.
+func (h *OIDCHandler) DeleteGroupMapping(c *gin.Context) {
+	mappingID, exists := utils.ParamUInt(c, "mappingId")
+	if !exists {
+		respondError(c, 400, "Invalid mapping ID")
+		return
+	}
+
+	if err := h.MappingRepository.Delete(c.Request.Context(), mappingID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, 404, "Group mapping not found")
+			return
+		}
+		respondError(c, 500, "Failed to delete group mapping")
+		return
+	}
+	c.Status(204)
+}
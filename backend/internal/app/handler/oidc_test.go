@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/db"
+	"axolotl-cloud/infra/oidc"
+	"axolotl-cloud/infra/shared"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/internal/app/repository"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestUserRepository(t *testing.T) *repository.UserRepository {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("DATABASE_PATH", filepath.Join(t.TempDir(), "data.db"))
+	database, err := db.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &repository.UserRepository{DB: database}
+}
+
+// TestOIDCProvision checks that single sign-on identities get their own
+// accounts and never take over a local account.
+func TestOIDCProvision(t *testing.T) {
+	users := newTestUserRepository(t)
+	h := &OIDCHandler{Auth: &AuthHandler{UserRepository: users}}
+	local := &model.User{Username: "bob"}
+	if err := users.Create(t.Context(), local); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		identity   oidc.Identity
+		wantStatus int
+		// wantUser is the username of the account signed in
+		wantUser string
+	}{
+		{name: "first login", identity: oidc.Identity{Issuer: "https://sso", Subject: "1", Username: "alice"}, wantUser: "alice"},
+		{name: "next login", identity: oidc.Identity{Issuer: "https://sso", Subject: "1", Username: "alice"}, wantUser: "alice"},
+		{name: "renamed at the provider", identity: oidc.Identity{Issuer: "https://sso", Subject: "1", Username: "alice2"}, wantUser: "alice"},
+		{name: "local username", identity: oidc.Identity{Issuer: "https://sso", Subject: "2", Username: "bob"}, wantStatus: 409},
+		{name: "local username from another issuer", identity: oidc.Identity{Issuer: "https://other", Subject: "1", Username: "bob"}, wantStatus: 409},
+		{name: "verified email", identity: oidc.Identity{Issuer: "https://sso", Subject: "3", Username: "carol@example.com", Email: "carol@example.com"}, wantUser: "carol@example.com"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/", nil)
+
+			user, status, message := h.provision(c, &test.identity)
+			if status != test.wantStatus {
+				t.Fatalf("provision() status = %d (%s), want %d", status, message, test.wantStatus)
+			}
+			if test.wantStatus != 0 {
+				return
+			}
+			if user.Username != test.wantUser {
+				t.Errorf("provision() user = %s, want %s", user.Username, test.wantUser)
+			}
+			if user.OIDCSubject == nil || *user.OIDCSubject != test.identity.Issuer+"|"+test.identity.Subject {
+				t.Errorf("provision() subject = %v, want %s|%s", user.OIDCSubject, test.identity.Issuer, test.identity.Subject)
+			}
+		})
+	}
+
+	bob, err := users.FindByUsername(t.Context(), "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bob.OIDCSubject != nil {
+		t.Errorf("local account linked to %s", *bob.OIDCSubject)
+	}
+}
+
+// TestOIDCCallbackState checks that the callback is refused unless its state
+// is the one of the flow started by the same browser.
+func TestOIDCCallbackState(t *testing.T) {
+	h := &OIDCHandler{
+		Provider: oidc.NewProvider(shared.OIDC{Issuer: "http://127.0.0.1:1", ClientID: "axolotl"}),
+		Auth:     &AuthHandler{UserRepository: newTestUserRepository(t)},
+	}
+	flow, err := json.Marshal(oidc.Flow{State: "state", Nonce: "nonce", Verifier: "verifier"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		cookie string
+		query  string
+	}{
+		{name: "no flow", query: "state=state&code=code"},
+		{name: "other state", cookie: base64.RawURLEncoding.EncodeToString(flow), query: "state=forged&code=code"},
+		{name: "no state", cookie: base64.RawURLEncoding.EncodeToString(flow), query: "code=code"},
+		{name: "invalid flow", cookie: "not-base64!", query: "state=state&code=code"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/api/v1/auth/oidc/callback?"+test.query, nil)
+			if test.cookie != "" {
+				c.Request.AddCookie(&http.Cookie{Name: oidcFlowCookie, Value: test.cookie})
+			}
+
+			h.Callback(c)
+			if w.Code != 401 {
+				t.Errorf("Callback() status = %d, want 401", w.Code)
+			}
+		})
+	}
+}
@@ -1,85 +1,178 @@
 package handler
 
 import (
+	"axolotl-cloud/infra/docker"
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/infra/worker"
 	"axolotl-cloud/internal/app/model"
 	"axolotl-cloud/internal/app/repository"
 	"axolotl-cloud/utils"
+	"context"
+	"fmt"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ProjectHandler struct {
-	ProjectRepository *repository.ProjectRepository
+	ProjectRepository   *repository.ProjectRepository
+	ContainerRepository *repository.ContainerRepository
+	MemberRepository    *repository.ProjectMemberRepository
+	UserRepository      *repository.UserRepository
+	Access              *Access
+	DockerClient        *docker.DockerClient
+	JobWorker           *worker.Worker
+}
+
+type RequestSetMember struct {
+	Role model.ProjectRole `json:"role" binding:"required,oneof=owner deployer viewer"`
 }
 
 func (h *ProjectHandler) CreateProject(c *gin.Context) {
 	var project model.Project
 	if err := c.ShouldBindJSON(&project); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid input"})
+		respondInvalid(c, err, "Invalid input")
+		return
+	}
+
+	if !h.checkProjectName(c, &project) {
 		return
 	}
 
+	// The creator owns the project
+	if user := CurrentUser(c); user != nil {
+		project.Members = []model.ProjectMember{{UserID: user.ID, Role: model.RoleOwner}}
+	}
 	if err := h.ProjectRepository.Create(c.Request.Context(), &project); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create project"})
+		respondError(c, 500, "Failed to create project")
 		return
 	}
 
+	auditTarget(c, project.ID)
+	auditProject(c, project.ID)
+	maskProject(&project)
 	c.JSON(201, project)
 }
 
 func (h *ProjectHandler) GetAllProjects(c *gin.Context) {
 	projects, err := h.ProjectRepository.FindAll(c.Request.Context())
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to retrieve projects"})
+		respondError(c, 500, "Failed to retrieve projects")
+		return
+	}
+	ids, all, err := h.Access.ReadableProjects(c)
+	if err != nil {
+		respondError(c, 500, "Failed to check permissions")
 		return
 	}
-	c.JSON(200, projects)
+	c.JSON(200, maskProjects(filterProjects(projects, ids, all)))
 }
 
 func (h *ProjectHandler) GetProjectByID(c *gin.Context) {
 	id, exists := utils.ParamUInt(c, "id")
 	if !exists {
+		respondError(c, 400, "Invalid project ID")
 		return
 	}
 
 	project, err := h.ProjectRepository.FindByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Project not found"})
+		respondLookupError(c, err, "Project not found")
 		return
 	}
+	maskProject(project)
 	c.JSON(200, project)
 }
 
 func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 	id, exists := utils.ParamUInt(c, "id")
 	if !exists {
+		respondError(c, 400, "Invalid project ID")
 		return
 	}
 
 	var project model.Project
 	if err := c.ShouldBindJSON(&project); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid input"})
+		respondInvalid(c, err, "Invalid input")
 		return
 	}
 	project.ID = id
 
+	before, err := h.ProjectRepository.FindByID(c.Request.Context(), id)
+	if err != nil {
+		respondLookupError(c, err, "Project not found")
+		return
+	}
+	unmaskEnv(project.Env, before.Env)
+	if !h.checkProjectName(c, &project) {
+		return
+	}
 	if err := h.ProjectRepository.Save(c.Request.Context(), &project); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to update project"})
+		respondError(c, 500, "Failed to update project")
 		return
 	}
+	auditChanges(c, before, project)
+	maskProject(&project)
 	c.JSON(200, project)
 }
 
+// checkProjectName responds with a 409 when another project, including the
+// trash, has the same name once formatted into container names, so that the
+// Docker containers of two projects never share a name.
+func (h *ProjectHandler) checkProjectName(c *gin.Context, project *model.Project) bool {
+	projects, err := h.ProjectRepository.FindAllWithDeleted(c.Request.Context())
+	if err != nil {
+		respondError(c, 500, "Failed to check project names")
+		return false
+	}
+	prefix := utils.FormatContainerName(project.Name, "")
+	for _, other := range projects {
+		if other.ID != project.ID && utils.FormatContainerName(other.Name, "") == prefix {
+			respondError(c, 409, fmt.Sprintf("A project named %s already exists", other.Name))
+			return false
+		}
+	}
+	return true
+}
+
+// DeleteProject moves the project and its containers to the trash and stops
+// them. They are purged once the trash purge delay is over.
 func (h *ProjectHandler) DeleteProject(c *gin.Context) {
 	id, exists := utils.ParamUInt(c, "id")
 	if !exists {
+		respondError(c, 400, "Invalid project ID")
+		return
+	}
+
+	project, err := h.ProjectRepository.FindByID(c.Request.Context(), id)
+	if err != nil {
+		respondLookupError(c, err, "Project not found")
+		return
+	}
+	containers, err := h.ContainerRepository.FindAllByProjectID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve containers")
 		return
 	}
 
 	if err := h.ProjectRepository.Delete(c.Request.Context(), id); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to delete project"})
+		respondError(c, 500, "Failed to delete project")
+		return
+	}
+
+	jobId, err := h.JobWorker.AddJob(&model.Job{
+		Name:      fmt.Sprintf("Stop containers of deleted project %s", project.Name),
+		ProjectID: &project.ID,
+		Run: func(ctx context.Context, log *logger.Logger) error {
+			return stopContainers(ctx, h.DockerClient, containers, log)
+		},
+	}, nil)
+	if err != nil {
+		respondError(c, 500, fmt.Sprintf("Failed to add job to stop the containers of project %s", project.Name))
 		return
 	}
 
-	c.Status(204) // No Content
+	auditJob(c, jobId)
+	c.JSON(201, gin.H{
+		"job_id": jobId,
+	})
 }
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/ratelimit"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit returns a middleware taking a token of limiter for every request,
+// per API token, user, or IP before authentication.
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if wait := limiter.Allow(rateLimitKey(c)); wait > 0 {
+			tooManyRequests(c, wait, "Too many requests, please retry later")
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitKey identifies the client: the requests of an API token do not
+// take from the budget of its user in the web UI.
+func rateLimitKey(c *gin.Context) string {
+	if token := CurrentToken(c); token != nil {
+		return fmt.Sprintf("token:%d", token.ID)
+	}
+	if user := CurrentUser(c); user != nil {
+		return fmt.Sprintf("user:%d", user.ID)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// tooManyRequests aborts with a 429, telling the client when to retry.
+func tooManyRequests(c *gin.Context, wait time.Duration, message string) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	respondError(c, 429, message)
+}
@@ -0,0 +1,390 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/docker"
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/infra/secrets"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/types"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	dContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+	"github.com/gin-gonic/gin"
+)
+
+// ReconcileReport compares the containers of the database with the ones of
+// the Docker daemon.
+type ReconcileReport struct {
+	// Missing containers have a row but no Docker container.
+	Missing []ReconcileEntry `json:"missing"`
+	// Orphans are Docker containers labeled by Axolotl without a row, e.g.
+	// left running after their project was deleted.
+	Orphans []ReconcileEntry `json:"orphans"`
+	// Drifted containers differ from the configuration they were created from.
+	Drifted []ReconcileEntry `json:"drifted"`
+	// Unmanaged are Docker containers created outside of Axolotl, which can be
+	// adopted into a project.
+	Unmanaged []ReconcileEntry `json:"unmanaged"`
+}
+
+type ReconcileEntry struct {
+	ContainerID uint     `json:"container_id,omitempty"`
+	ProjectID   uint     `json:"project_id,omitempty"`
+	DockerID    string   `json:"docker_id,omitempty"`
+	Name        string   `json:"name"`
+	Image       string   `json:"image,omitempty"`
+	State       string   `json:"state,omitempty"`
+	Reasons     []string `json:"reasons,omitempty"`
+}
+
+type RequestReconcileAction struct {
+	Action      string `json:"action" binding:"required,oneof=adopt remove recreate"`
+	DockerID    string `json:"docker_id"`    // adopt, remove
+	ProjectID   uint   `json:"project_id"`   // adopt, defaults to the project label of the container
+	ContainerID uint   `json:"container_id"` // recreate
+}
+
+func (h *ContainerHandler) GetReconcileReport(c *gin.Context) {
+	report, err := h.reconcile(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to reconcile containers", err)
+		respondError(c, 500, "Failed to reconcile containers")
+		return
+	}
+	c.JSON(200, report)
+}
+
+func (h *ContainerHandler) reconcile(ctx context.Context) (*ReconcileReport, error) {
+	rows, err := h.ContainerRepository.GetAllContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The Docker containers of the trash are kept stopped until the purge
+	deleted, err := h.ContainerRepository.FindAllDeletedIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dockerContainers, err := h.DockerClient.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReconcileReport{
+		Missing:   []ReconcileEntry{},
+		Orphans:   []ReconcileEntry{},
+		Drifted:   []ReconcileEntry{},
+		Unmanaged: []ReconcileEntry{},
+	}
+	matched := make(map[uint]bool, len(rows))
+	self, _ := h.DockerClient.Self(ctx)
+
+	for _, dc := range dockerContainers {
+		if dc.ID == self {
+			continue
+		}
+		name := strings.TrimPrefix(dc.Names[0], "/")
+		entry := ReconcileEntry{DockerID: dc.ID, Name: name, Image: dc.Image, State: string(dc.State)}
+		entry.ProjectID = labelUint(dc.Labels, docker.ProjectLabel)
+		if slices.Contains(deleted, labelUint(dc.Labels, docker.ContainerLabel)) {
+			continue
+		}
+		if target := dc.Labels[docker.ProxyLabel]; target != "" && slices.ContainsFunc(rows, func(row model.Container) bool { return row.Name == target }) {
+			// compared along its container, see driftReasons
+			continue
+		}
+
+		i := slices.IndexFunc(rows, func(row model.Container) bool {
+			if id := labelUint(dc.Labels, docker.ContainerLabel); id != 0 {
+				return row.ID == id && row.Name == name
+			}
+			return row.Name == name
+		})
+		if i < 0 {
+			if dc.Labels[docker.ManagedLabel] == "true" {
+				report.Orphans = append(report.Orphans, entry)
+			} else {
+				report.Unmanaged = append(report.Unmanaged, entry)
+			}
+			continue
+		}
+
+		row := rows[i]
+		matched[row.ID] = true
+		entry.ContainerID = row.ID
+		entry.ProjectID = row.ProjectID
+		if reasons := h.driftReasons(ctx, &row, dc); len(reasons) > 0 {
+			entry.Reasons = reasons
+			report.Drifted = append(report.Drifted, entry)
+		}
+	}
+
+	for _, row := range rows {
+		if !matched[row.ID] {
+			report.Missing = append(report.Missing, ReconcileEntry{ContainerID: row.ID, ProjectID: row.ProjectID, Name: row.Name, Image: row.DockerImage})
+		}
+	}
+	return report, nil
+}
+
+// driftReasons compares a Docker container with the configuration Axolotl
+// created it from.
+func (h *ContainerHandler) driftReasons(ctx context.Context, row *model.Container, dc dContainer.Summary) []string {
+	if dc.Labels[docker.ConfigHashLabel] == "" {
+		return []string{"not created by Axolotl"}
+	}
+	if row.AppliedConfig == nil {
+		return []string{"no configuration recorded"}
+	}
+
+	var reasons []string
+	if dc.Image != row.AppliedConfig.Image {
+		reasons = append(reasons, fmt.Sprintf("runs image %s instead of %s", dc.Image, row.AppliedConfig.Image))
+	}
+	if row.DockerImage != row.AppliedConfig.Image || row.RestartRequired {
+		reasons = append(reasons, "configuration changed since the last start")
+	}
+
+	info, err := h.DockerClient.InspectContainer(ctx, dc.ID)
+	if err != nil {
+		return append(reasons, err.Error())
+	}
+	ports := portsOf(info)
+	if len(ports) == 0 {
+		// blue/green containers publish their ports through a proxy
+		if proxy, err := h.DockerClient.InspectContainer(ctx, docker.ProxyName(row.Name)); err == nil {
+			ports = portsOf(proxy)
+		}
+	}
+	if !maps.Equal(ports, row.AppliedConfig.Ports) {
+		reasons = append(reasons, "published ports differ")
+	}
+	return reasons
+}
+
+// ReconcileAction runs an action proposed by the reconcile report.
+func (h *ContainerHandler) ReconcileAction(c *gin.Context) {
+	var request RequestReconcileAction
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid request data")
+		return
+	}
+
+	switch request.Action {
+	case "adopt":
+		h.adoptContainer(c, request)
+	case "remove":
+		h.removeDockerContainer(c, request)
+	case "recreate":
+		h.forceRecreateContainer(c, request)
+	}
+}
+
+// adoptContainer imports a Docker container created outside of Axolotl (or
+// orphaned) into a project. The Docker container is left untouched until
+// the next start, which recreates it with the Axolotl labels.
+func (h *ContainerHandler) adoptContainer(c *gin.Context, request RequestReconcileAction) {
+	if request.DockerID == "" {
+		respondError(c, 400, "docker_id is required")
+		return
+	}
+
+	info, err := h.DockerClient.InspectContainer(c.Request.Context(), request.DockerID)
+	if err != nil {
+		respondDockerLookupError(c, err)
+		return
+	}
+	if h.isSelf(c.Request.Context(), info) {
+		respondError(c, 400, "Axolotl cannot adopt its own container")
+		return
+	}
+
+	projectID := request.ProjectID
+	if projectID == 0 {
+		projectID = labelUint(info.Config.Labels, docker.ProjectLabel)
+	}
+	if _, err := h.ProjectRepository.FindByID(c.Request.Context(), projectID); err != nil {
+		respondLookupError(c, err, "Project not found")
+		return
+	}
+
+	name := strings.TrimPrefix(info.Name, "/")
+	if _, err := h.ContainerRepository.FindByName(c.Request.Context(), projectID, name); err == nil {
+		respondError(c, 409, fmt.Sprintf("Project already has a container named %s", name))
+		return
+	}
+	if !h.checkContainerName(c, &model.Container{ProjectID: projectID, Name: name}) {
+		return
+	}
+
+	container, skipped, err := h.containerFromDocker(c.Request.Context(), info, projectID)
+	if err != nil {
+		logger.Error("Failed to adopt container", err)
+		respondError(c, 500, "Failed to adopt container")
+		return
+	}
+	if err := h.ContainerRepository.Create(c.Request.Context(), container); err != nil {
+		logger.Error("Failed to adopt container", err)
+		respondError(c, 500, "Failed to adopt container")
+		return
+	}
+
+	maskContainer(container)
+	c.JSON(201, gin.H{"container": container, "skipped": skipped})
+}
+
+// containerFromDocker builds a container of a project from the configuration
+// of a Docker container. It returns the settings that cannot be represented.
+// Env values holding the value of a secret are replaced by a reference to the
+// secret, or skipped when the project may not use it.
+func (h *ContainerHandler) containerFromDocker(ctx context.Context, info dContainer.InspectResponse, projectID uint) (*model.Container, []string, error) {
+	skipped := []string{}
+
+	bySecret, err := h.SecretRepository.IndexByValue(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	imageEnv, _ := h.DockerClient.ImageEnv(ctx, info.Config.Image)
+	env := types.StringMap{}
+	for _, entry := range info.Config.Env {
+		if slices.Contains(imageEnv, entry) {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		if secret, found := bySecret[value]; found && value != "" {
+			if !slices.Contains(secret.ProjectIDs, projectID) {
+				skipped = append(skipped, fmt.Sprintf("env %s, holding secret %s the project may not use", key, secret.Name))
+				continue
+			}
+			value = secrets.RefPrefix + secret.Name
+		}
+		env[key] = value
+	}
+
+	volumes := types.StringMap{}
+	for _, m := range info.Mounts {
+		if m.Type == "bind" {
+			volumes[m.Source] = m.Destination
+		} else {
+			skipped = append(skipped, fmt.Sprintf("%s mount %s", m.Type, m.Destination))
+		}
+	}
+
+	networkMode := "bridge"
+	if info.HostConfig != nil && (info.HostConfig.NetworkMode.IsHost() || info.HostConfig.NetworkMode.IsNone()) {
+		networkMode = string(info.HostConfig.NetworkMode)
+	}
+
+	return &model.Container{
+		ProjectID:      projectID,
+		Name:           strings.TrimPrefix(info.Name, "/"),
+		DockerImage:    info.Config.Image,
+		Ports:          portsOf(info),
+		Env:            env,
+		Volumes:        volumes,
+		Networks:       types.StringList{},
+		NetworkMode:    networkMode,
+		DeployStrategy: model.DeployRecreate,
+	}, skipped, nil
+}
+
+func (h *ContainerHandler) removeDockerContainer(c *gin.Context, request RequestReconcileAction) {
+	if request.DockerID == "" {
+		respondError(c, 400, "docker_id is required")
+		return
+	}
+
+	info, err := h.DockerClient.InspectContainer(c.Request.Context(), request.DockerID)
+	if err != nil {
+		respondDockerLookupError(c, err)
+		return
+	}
+	if h.isSelf(c.Request.Context(), info) {
+		respondError(c, 400, "Axolotl cannot remove its own container")
+		return
+	}
+	name := strings.TrimPrefix(info.Name, "/")
+
+	jobId, err := h.JobWorker.AddJob(&model.Job{
+		Name: fmt.Sprintf("Remove Docker container %s", name),
+		Run: func(ctx context.Context, log *logger.Logger) error {
+			return h.DockerClient.RemoveContainer(ctx, info.ID, log)
+		},
+	}, nil)
+	if err != nil {
+		respondError(c, 500, fmt.Sprintf("Failed to add job to remove container %s", name))
+		return
+	}
+	auditJob(c, jobId)
+	c.JSON(201, gin.H{"job_id": jobId})
+}
+
+func (h *ContainerHandler) forceRecreateContainer(c *gin.Context, request RequestReconcileAction) {
+	container, err := h.ContainerRepository.FindByID(c.Request.Context(), request.ContainerID)
+	if err != nil {
+		respondLookupError(c, err, "Container not found")
+		return
+	}
+
+	jobId, err := h.JobWorker.AddJob(&model.Job{
+		Name:      fmt.Sprintf("Recreate container %s", container.Name),
+		ProjectID: &container.ProjectID,
+		Run: func(ctx context.Context, log *logger.Logger) error {
+			h.removeIfExists(ctx, container.Name, log)
+			return h.deployContainer(ctx, container, log)
+		},
+	}, &container.ID)
+	if err != nil {
+		respondError(c, 500, fmt.Sprintf("Failed to add job to recreate container %s", container.Name))
+		return
+	}
+	auditJob(c, jobId)
+	c.JSON(201, gin.H{"job_id": jobId})
+}
+
+// portsOf returns the published ports of a Docker container, host port to
+// container port.
+func portsOf(info dContainer.InspectResponse) types.StringMap {
+	ports := types.StringMap{}
+	if info.HostConfig == nil {
+		return ports
+	}
+	for port, bindings := range info.HostConfig.PortBindings {
+		for _, binding := range bindings {
+			if binding.HostPort != "" {
+				ports[binding.HostPort] = port.Port()
+			}
+		}
+	}
+	return ports
+}
+
+// isSelf reports whether a Docker container is the one Axolotl runs in.
+func (h *ContainerHandler) isSelf(ctx context.Context, info dContainer.InspectResponse) bool {
+	self, ok := h.DockerClient.Self(ctx)
+	return ok && info.ID == self
+}
+
+func labelUint(labels map[string]string, key string) uint {
+	id, _ := strconv.ParseUint(labels[key], 10, 64)
+	return uint(id)
+}
+
+// respondDockerLookupError aborts with a 404 when the Docker container does
+// not exist, and with a 502 when Docker failed.
+func respondDockerLookupError(c *gin.Context, err error) {
+	if errdefs.IsNotFound(err) {
+		respondError(c, 404, "Docker container not found")
+		return
+	}
+	logger.Error("Failed to inspect Docker container:", err)
+	respondError(c, 502, "Failed to inspect Docker container")
+}
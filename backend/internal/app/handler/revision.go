@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *ContainerHandler) GetContainerRevisions(c *gin.Context) {
+	containerID, exists := utils.ParamUInt(c, "containerId")
+	if !exists {
+		respondError(c, 400, "Invalid container ID")
+		return
+	}
+
+	revisions, err := h.RevisionRepository.FindAllByContainerID(c.Request.Context(), containerID)
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve revisions")
+		return
+	}
+	c.JSON(200, maskRevisions(revisions))
+}
+
+// DiffContainerRevisions returns the field-level changes between revisions
+// ?from= and ?to= (the latest revision when omitted).
+func (h *ContainerHandler) DiffContainerRevisions(c *gin.Context) {
+	containerID, exists := utils.ParamUInt(c, "containerId")
+	if !exists {
+		respondError(c, 400, "Invalid container ID")
+		return
+	}
+
+	from, err := strconv.ParseUint(c.Query("from"), 10, 64)
+	if err != nil {
+		respondError(c, 400, "Invalid from revision")
+		return
+	}
+
+	fromRevision, err := h.RevisionRepository.FindByNumber(c.Request.Context(), containerID, uint(from))
+	if err != nil {
+		respondLookupError(c, err, "Revision not found")
+		return
+	}
+
+	var to uint64
+	if c.Query("to") != "" {
+		if to, err = strconv.ParseUint(c.Query("to"), 10, 64); err != nil {
+			respondError(c, 400, "Invalid to revision")
+			return
+		}
+	} else {
+		revisions, err := h.RevisionRepository.FindAllByContainerID(c.Request.Context(), containerID)
+		if err != nil || len(revisions) == 0 {
+			respondError(c, 500, "Failed to retrieve revisions")
+			return
+		}
+		to = uint64(revisions[0].Number)
+	}
+
+	toRevision, err := h.RevisionRepository.FindByNumber(c.Request.Context(), containerID, uint(to))
+	if err != nil {
+		respondLookupError(c, err, "Revision not found")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"from":    fromRevision.Number,
+		"to":      toRevision.Number,
+		"changes": maskChanges(fromRevision.Snapshot.Diff(toRevision.Snapshot), "env."),
+	})
+}
+
+// RestoreContainerRevision sets the configuration of a revision back on the
+// container, recording it as a new revision. The Docker container is only
+// updated on the next start.
+func (h *ContainerHandler) RestoreContainerRevision(c *gin.Context) {
+	containerID, exists := utils.ParamUInt(c, "containerId")
+	if !exists {
+		respondError(c, 400, "Invalid container ID")
+		return
+	}
+
+	number, exists := utils.ParamUInt(c, "number")
+	if !exists {
+		respondError(c, 400, "Invalid revision number")
+		return
+	}
+
+	container, err := h.ContainerRepository.FindByID(c.Request.Context(), containerID)
+	if err != nil {
+		respondLookupError(c, err, "Container not found")
+		return
+	}
+
+	revision, err := h.RevisionRepository.FindByNumber(c.Request.Context(), containerID, number)
+	if err != nil {
+		respondLookupError(c, err, "Revision not found")
+		return
+	}
+
+	before := *container
+	revision.Snapshot.ApplyTo(container)
+	if !allowHostAccess(c, container, &before) {
+		return
+	}
+	if err := h.ContainerRepository.Save(c.Request.Context(), container); err != nil {
+		logger.Error("Failed to restore revision", err)
+		respondError(c, 500, "Failed to restore revision")
+		return
+	}
+	if err := h.ContainerRepository.SetRestartRequired(c.Request.Context(), containerID, true); err != nil {
+		logger.Error("Failed to flag container for restart", err)
+	}
+	container.RestartRequired = true
+	container.PendingChanges = true
+
+	maskContainer(container)
+	c.JSON(200, container)
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/infra/secrets"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/internal/app/repository"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SecretHandler struct {
+	SecretRepository    *repository.SecretRepository
+	ContainerRepository *repository.ContainerRepository
+	ProjectRepository   *repository.ProjectRepository
+}
+
+type RequestPutSecret struct {
+	Name       string `json:"name"`
+	Value      string `json:"value" binding:"required"`
+	ProjectIDs []uint `json:"project_ids"` // creation only, see SetSecretProjects
+}
+
+type RequestSetSecretProjects struct {
+	ProjectIDs []uint `json:"project_ids" binding:"required"`
+}
+
+// SecretResponse describes a secret without its value.
+type SecretResponse struct {
+	model.Secret
+	Value  string   `json:"value"`   // always masked
+	UsedBy []string `json:"used_by"` // names of the containers referencing the secret
+}
+
+func (h *SecretHandler) GetAllSecrets(c *gin.Context) {
+	all, err := h.SecretRepository.FindAll(c.Request.Context())
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve secrets")
+		return
+	}
+
+	response := make([]SecretResponse, 0, len(all))
+	for _, secret := range all {
+		usedBy, err := h.usedBy(c, secret.Name)
+		if err != nil {
+			respondError(c, 500, "Failed to retrieve secrets")
+			return
+		}
+		response = append(response, SecretResponse{Secret: secret, Value: secrets.Mask, UsedBy: usedBy})
+	}
+	c.JSON(200, response)
+}
+
+func (h *SecretHandler) CreateSecret(c *gin.Context) {
+	var request RequestPutSecret
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid request data")
+		return
+	}
+	if !secrets.ValidName(request.Name) {
+		respondError(c, 400, "Secret names may only contain letters, digits, '_', '-' and '.'")
+		return
+	}
+
+	if _, err := h.SecretRepository.FindByName(c.Request.Context(), request.Name); err == nil {
+		respondError(c, 409, fmt.Sprintf("Secret %s already exists", request.Name))
+		return
+	}
+	if !h.checkProjects(c, request.ProjectIDs) {
+		return
+	}
+
+	secret, err := h.SecretRepository.Put(c.Request.Context(), request.Name, request.Value)
+	if err != nil {
+		logger.Error("Failed to create secret", err)
+		respondError(c, 500, "Failed to create secret")
+		return
+	}
+	if len(request.ProjectIDs) > 0 {
+		if secret, err = h.SecretRepository.SetProjects(c.Request.Context(), secret.Name, request.ProjectIDs); err != nil {
+			logger.Error("Failed to set the projects of the secret", err)
+			respondError(c, 500, "Secret created but its projects could not be set")
+			return
+		}
+	}
+	auditTarget(c, secret.Name)
+	c.JSON(201, SecretResponse{Secret: *secret, Value: secrets.Mask, UsedBy: []string{}})
+}
+
+// RotateSecret replaces the value of a secret and flags the containers using
+// it: they keep the previous value until they are started again.
+func (h *SecretHandler) RotateSecret(c *gin.Context) {
+	name := c.Param("name")
+
+	var request RequestPutSecret
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid request data")
+		return
+	}
+
+	if _, err := h.SecretRepository.FindByName(c.Request.Context(), name); err != nil {
+		respondLookupError(c, err, "Secret not found")
+		return
+	}
+
+	secret, err := h.SecretRepository.Put(c.Request.Context(), name, request.Value)
+	if err != nil {
+		logger.Error("Failed to rotate secret", err)
+		respondError(c, 500, "Failed to rotate secret")
+		return
+	}
+
+	containers, err := h.ContainerRepository.FindAllBySecret(c.Request.Context(), name)
+	if err != nil {
+		logger.Error("Failed to find the containers using the secret", err)
+		respondError(c, 500, "Secret rotated but the containers using it could not be flagged")
+		return
+	}
+	usedBy := make([]string, 0, len(containers))
+	for _, container := range containers {
+		if err := h.ContainerRepository.SetRestartRequired(c.Request.Context(), container.ID, true); err != nil {
+			logger.Error("Failed to flag container for restart", err)
+			respondError(c, 500, "Secret rotated but the containers using it could not be flagged")
+			return
+		}
+		usedBy = append(usedBy, container.Name)
+	}
+
+	c.JSON(200, SecretResponse{Secret: *secret, Value: secrets.Mask, UsedBy: usedBy})
+}
+
+// SetSecretProjects replaces the projects whose containers and builds may use
+// a secret.
+func (h *SecretHandler) SetSecretProjects(c *gin.Context) {
+	name := c.Param("name")
+
+	var request RequestSetSecretProjects
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid request data")
+		return
+	}
+	if !h.checkProjects(c, request.ProjectIDs) {
+		return
+	}
+
+	before, err := h.SecretRepository.FindByName(c.Request.Context(), name)
+	if err != nil {
+		respondLookupError(c, err, "Secret not found")
+		return
+	}
+	secret, err := h.SecretRepository.SetProjects(c.Request.Context(), name, request.ProjectIDs)
+	if err != nil {
+		logger.Error("Failed to set the projects of the secret", err)
+		respondError(c, 500, "Failed to set the projects of the secret")
+		return
+	}
+	auditChanges(c, before, secret)
+
+	usedBy, err := h.usedBy(c, name)
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve the containers using the secret")
+		return
+	}
+	c.JSON(200, SecretResponse{Secret: *secret, Value: secrets.Mask, UsedBy: usedBy})
+}
+
+// checkProjects aborts with a 400 unless every project exists.
+func (h *SecretHandler) checkProjects(c *gin.Context, projectIDs []uint) bool {
+	for _, id := range projectIDs {
+		if _, err := h.ProjectRepository.FindByID(c.Request.Context(), id); err != nil {
+			respondError(c, 400, fmt.Sprintf("Project %d not found", id))
+			return false
+		}
+	}
+	return true
+}
+
+func (h *SecretHandler) DeleteSecret(c *gin.Context) {
+	name := c.Param("name")
+
+	usedBy, err := h.usedBy(c, name)
+	if err != nil {
+		respondError(c, 500, "Failed to delete secret")
+		return
+	}
+	if len(usedBy) > 0 {
+		response := newErrorResponse(c, 409, "Secret is still referenced")
+		response.Details = gin.H{"used_by": usedBy}
+		c.AbortWithStatusJSON(409, response)
+		return
+	}
+
+	if err := h.SecretRepository.Delete(c.Request.Context(), name); err != nil {
+		respondError(c, 500, "Failed to delete secret")
+		return
+	}
+	c.Status(204)
+}
+
+func (h *SecretHandler) usedBy(c *gin.Context, name string) ([]string, error) {
+	containers, err := h.ContainerRepository.FindAllBySecret(c.Request.Context(), name)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(containers))
+	for _, container := range containers {
+		names = append(names, container.Name)
+	}
+	return names, nil
+}
@@ -14,7 +14,7 @@ type SettingHandler struct {
 func (h *SettingHandler) GetAllSettings(c *gin.Context) {
 	settings, err := h.SettingRepository.GetAll()
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to retrieve settings"})
+		respondError(c, 500, "Failed to retrieve settings")
 		return
 	}
 	c.JSON(200, settings)
@@ -23,13 +23,13 @@ func (h *SettingHandler) GetAllSettings(c *gin.Context) {
 func (h *SettingHandler) GetSettingByKey(c *gin.Context) {
 	key := c.Param("key")
 	if key == "" {
-		c.JSON(400, gin.H{"error": "Invalid setting key"})
+		respondError(c, 400, "Invalid setting key")
 		return
 	}
 
 	setting, err := h.SettingRepository.GetByKey(model.SettingKey(key))
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Setting not found"})
+		respondLookupError(c, err, "Setting not found")
 		return
 	}
 	c.JSON(200, setting)
@@ -38,27 +38,33 @@ func (h *SettingHandler) GetSettingByKey(c *gin.Context) {
 func (h *SettingHandler) SaveSetting(c *gin.Context) {
 	var setting model.Setting
 	if err := c.ShouldBindJSON(&setting); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid JSON"})
+		respondInvalid(c, err, "Invalid JSON")
 		return
 	}
 
+	var before any
+	if previous, err := h.SettingRepository.GetByKey(setting.Key); err == nil {
+		before = gin.H{"value": previous.Value}
+	}
 	if err := h.SettingRepository.Save(&setting); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to save setting"})
+		respondError(c, 500, "Failed to save setting")
 		return
 	}
 
+	auditTarget(c, setting.Key)
+	auditChanges(c, before, gin.H{"value": setting.Value})
 	c.JSON(200, setting)
 }
 
 func (h *SettingHandler) DeleteSetting(c *gin.Context) {
 	key := c.Param("key")
 	if key == "" {
-		c.JSON(400, gin.H{"error": "Invalid setting key"})
+		respondError(c, 400, "Invalid setting key")
 		return
 	}
 
 	if err := h.SettingRepository.RemoveByKey(key); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to delete setting"})
+		respondError(c, 500, "Failed to delete setting")
 		return
 	}
 
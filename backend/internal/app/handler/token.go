@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/auth"
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/internal/app/repository"
+	"axolotl-cloud/utils"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenPrefix starts every API token, to make them easy to spot in logs and
+// secret scanners.
+const TokenPrefix = "axo_"
+
+const tokenContextKey = "api_token"
+
+// credentialRoutes can only be used with a session: a leaked token must not
+// be able to mint other tokens or take over an account.
+var credentialRoutes = []string{APIPrefix + "/auth/", APIPrefix + "/tokens", APIPrefix + "/oidc/"}
+
+// deployRoutes are the routes allowed by ScopeDeploy on top of the read-only
+// ones.
+var deployRoutes = []string{
+	"POST " + APIPrefix + "/projects/:id/containers/:containerId/start",
+	"POST " + APIPrefix + "/projects/:id/containers/:containerId/stop",
+	"POST " + APIPrefix + "/projects/:id/containers/:containerId/rollback/:buildId",
+	"POST " + APIPrefix + "/projects/:id/containers/build_from_source",
+	"POST " + APIPrefix + "/projects/:id/containers/build_from_archive",
+}
+
+type TokenHandler struct {
+	TokenRepository   *repository.APITokenRepository
+	ProjectRepository *repository.ProjectRepository
+	Access            *Access
+}
+
+type RequestCreateToken struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=read deploy admin"`
+	ProjectID *uint      `json:"project_id"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// Service creates a token without user, admins only.
+	Service bool `json:"service"`
+}
+
+// CreatedTokenResponse holds the token value, only returned on creation.
+type CreatedTokenResponse struct {
+	model.APIToken
+	Token string `json:"token"`
+}
+
+// CurrentToken returns the API token the request is authenticated with, nil
+// for a session.
+func CurrentToken(c *gin.Context) *model.APIToken {
+	token, _ := c.Get(tokenContextKey)
+	t, _ := token.(*model.APIToken)
+	return t
+}
+
+// RequireToken returns a middleware authenticating the request with the
+// Bearer token of the Authorization header, and aborting with a 403 when the
+// route is outside of its scopes or project.
+func RequireToken(tokens *repository.APITokenRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		token, err := tokens.FindByTokenHash(c.Request.Context(), auth.HashToken(value))
+		if err != nil || (token.UserID != nil && token.User == nil) {
+			respondError(c, 401, "Invalid or expired API token")
+			return
+		}
+		if denied := tokenDenies(token, c); denied != "" {
+			respondError(c, 403, denied)
+			return
+		}
+
+		// Only record usage once a minute, not on every request
+		if now := time.Now(); token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > time.Minute {
+			if err := tokens.SetLastUsed(c.Request.Context(), token.ID, now); err != nil {
+				logger.Error("Failed to update token usage", err)
+			}
+		}
+
+		c.Set(tokenContextKey, token)
+		if token.User != nil {
+			c.Set(userContextKey, token.User)
+		}
+		c.Next()
+	}
+}
+
+// tokenDenies checks the matched route against the scopes and the project
+// restriction of the token. It returns why the token is refused, or "".
+func tokenDenies(token *model.APIToken, c *gin.Context) string {
+	path := c.FullPath()
+	if path == "" {
+		// unknown route, answered with a 404
+		return ""
+	}
+	for _, prefix := range credentialRoutes {
+		if strings.HasPrefix(path, prefix) {
+			return "API tokens cannot manage credentials"
+		}
+	}
+
+	if token.ProjectID != nil {
+		restricted := fmt.Sprintf("This token is restricted to project %d", *token.ProjectID)
+		switch {
+		case strings.HasPrefix(path, APIPrefix+"/projects/:id"):
+			if c.Param("id") != strconv.FormatUint(uint64(*token.ProjectID), 10) {
+				return restricted
+			}
+		case path == APIPrefix+"/jobs/:id" && c.Request.Method == http.MethodGet, path == "/ws":
+			// job status is checked by the handler, topics on subscription
+		default:
+			return restricted
+		}
+	}
+
+	required := model.ScopeAdmin
+	switch {
+	case c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead:
+		required = model.ScopeRead
+	case slices.Contains(deployRoutes, c.Request.Method+" "+path):
+		required = model.ScopeDeploy
+	}
+	if !token.HasScope(required) {
+		return fmt.Sprintf("This token lacks the %s scope", required)
+	}
+	return ""
+}
+
+// GetAllTokens returns the personal tokens of the user, and every token for
+// admins.
+func (h *TokenHandler) GetAllTokens(c *gin.Context) {
+	user := CurrentUser(c)
+	userID := user.ID
+	if user.Admin {
+		userID = 0
+	}
+
+	tokens, err := h.TokenRepository.FindAll(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve tokens")
+		return
+	}
+	c.JSON(200, tokens)
+}
+
+func (h *TokenHandler) CreateToken(c *gin.Context) {
+	var request RequestCreateToken
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid input")
+		return
+	}
+
+	user := CurrentUser(c)
+	if request.Service && !user.Admin {
+		respondError(c, 403, "Only admins can create service tokens")
+		return
+	}
+	if slices.Contains(request.Scopes, string(model.ScopeAdmin)) && !user.Admin {
+		respondError(c, 403, "Only admins can create tokens with the admin scope")
+		return
+	}
+	if request.ExpiresAt != nil && request.ExpiresAt.Before(time.Now()) {
+		respondError(c, 400, "Expiry must be in the future")
+		return
+	}
+	if request.ProjectID != nil {
+		_, err := h.ProjectRepository.FindByID(c.Request.Context(), *request.ProjectID)
+		if err != nil || !h.Access.HasRole(c, *request.ProjectID, model.RoleViewer) {
+			respondError(c, 400, "Project not found")
+			return
+		}
+	}
+
+	random, err := auth.NewToken()
+	if err != nil {
+		respondError(c, 500, "Failed to generate token")
+		return
+	}
+	value := TokenPrefix + random
+
+	token := model.APIToken{
+		Name:      request.Name,
+		TokenHash: auth.HashToken(value),
+		Prefix:    value[:len(TokenPrefix)+6],
+		Scopes:    request.Scopes,
+		ProjectID: request.ProjectID,
+		ExpiresAt: request.ExpiresAt,
+	}
+	if !request.Service {
+		token.UserID = &user.ID
+	}
+	if err := h.TokenRepository.Create(c.Request.Context(), &token); err != nil {
+		respondError(c, 500, "Failed to create token")
+		return
+	}
+
+	auditTarget(c, token.ID)
+	c.JSON(201, CreatedTokenResponse{APIToken: token, Token: value})
+}
+
+// DeleteToken revokes a token of the user, or any token for admins.
+func (h *TokenHandler) DeleteToken(c *gin.Context) {
+	tokenID, exists := utils.ParamUInt(c, "tokenId")
+	if !exists {
+		respondError(c, 400, "Invalid token ID")
+		return
+	}
+
+	user := CurrentUser(c)
+	token, err := h.TokenRepository.FindByID(c.Request.Context(), tokenID)
+	if err != nil {
+		respondLookupError(c, err, "Token not found")
+		return
+	}
+	if !user.Admin && (token.UserID == nil || *token.UserID != user.ID) {
+		respondError(c, 404, "Token not found")
+		return
+	}
+
+	if err := h.TokenRepository.Delete(c.Request.Context(), token.ID); err != nil {
+		respondError(c, 500, "Failed to delete token")
+		return
+	}
+	c.Status(204)
+}
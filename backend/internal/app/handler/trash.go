@@ -0,0 +1,349 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/docker"
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/infra/settings"
+	"axolotl-cloud/infra/worker"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/internal/app/repository"
+	"axolotl-cloud/utils"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrashHandler lists, restores and purges the deleted projects and
+// containers. Purging removes their Docker containers, network, volume
+// directories and images, then their rows.
+type TrashHandler struct {
+	ProjectRepository   *repository.ProjectRepository
+	ContainerRepository *repository.ContainerRepository
+	BuildRepository     *repository.BuildRepository
+	SettingRepository   *repository.SettingRepository
+	DockerClient        *docker.DockerClient
+	JobWorker           *worker.Worker
+}
+
+type TrashResponse struct {
+	Projects []model.Project `json:"projects"`
+	// Containers are the containers deleted on their own, the containers of
+	// deleted projects are restored and purged with them.
+	Containers []model.Container `json:"containers"`
+	// PurgeDelay is the number of hours items stay in the trash.
+	PurgeDelay int `json:"purge_delay"`
+}
+
+// purgeOptions removes everything: nothing of a purged item can be restored.
+var purgeOptions = CleanupOptions{Volumes: true, Images: true}
+
+func (h *TrashHandler) GetTrash(c *gin.Context) {
+	projects, err := h.ProjectRepository.FindAllDeleted(c.Request.Context(), time.Time{})
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve deleted projects")
+		return
+	}
+	containers, err := h.ContainerRepository.FindAllDeleted(c.Request.Context(), time.Time{})
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve deleted containers")
+		return
+	}
+
+	c.JSON(200, TrashResponse{
+		Projects:   maskProjects(projects),
+		Containers: maskContainers(containers),
+		PurgeDelay: int(h.purgeDelay().Hours()),
+	})
+}
+
+func (h *TrashHandler) RestoreProject(c *gin.Context) {
+	id, exists := utils.ParamUInt(c, "id")
+	if !exists {
+		respondError(c, 400, "Invalid project ID")
+		return
+	}
+
+	project, err := h.ProjectRepository.FindDeletedByID(c.Request.Context(), id)
+	if err != nil {
+		respondLookupError(c, err, "Project not found in the trash")
+		return
+	}
+	containers, err := h.ContainerRepository.FindAllByProjectIDWithDeleted(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve containers")
+		return
+	}
+	for _, container := range containers {
+		if !container.DeletedAt.Time.Equal(project.DeletedAt.Time) {
+			continue
+		}
+		if status, message := h.checkName(c.Request.Context(), &container); status != 0 {
+			respondError(c, status, message)
+			return
+		}
+	}
+
+	if err := h.ProjectRepository.Restore(c.Request.Context(), id); err != nil {
+		respondError(c, 500, "Failed to restore project")
+		return
+	}
+
+	project, err = h.ProjectRepository.FindByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve project")
+		return
+	}
+	maskProject(project)
+	c.JSON(200, project)
+}
+
+func (h *TrashHandler) RestoreContainer(c *gin.Context) {
+	containerID, exists := utils.ParamUInt(c, "containerId")
+	if !exists {
+		respondError(c, 400, "Invalid container ID")
+		return
+	}
+
+	container, err := h.ContainerRepository.FindDeletedByID(c.Request.Context(), containerID)
+	if err != nil {
+		respondLookupError(c, err, "Container not found in the trash")
+		return
+	}
+	if _, err := h.ProjectRepository.FindByID(c.Request.Context(), container.ProjectID); err != nil {
+		respondError(c, 409, "The project of the container is deleted, restore the project instead")
+		return
+	}
+	if status, message := h.checkName(c.Request.Context(), container); status != 0 {
+		respondError(c, status, message)
+		return
+	}
+
+	if err := h.ContainerRepository.Restore(c.Request.Context(), containerID); err != nil {
+		respondError(c, 500, "Failed to restore container")
+		return
+	}
+
+	container, err = h.ContainerRepository.FindByID(c.Request.Context(), containerID)
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve container")
+		return
+	}
+	maskContainer(container)
+	c.JSON(200, container)
+}
+
+// checkName returns a 409 when the name of a container to restore has been
+// taken since its deletion.
+func (h *TrashHandler) checkName(ctx context.Context, container *model.Container) (int, string) {
+	inUse, err := h.ContainerRepository.NameInUse(ctx, container.Name)
+	if err != nil {
+		return 500, "Failed to check container names"
+	}
+	if inUse {
+		return 409, fmt.Sprintf("A container named %s already exists", container.Name)
+	}
+	return 0, ""
+}
+
+// PurgeProject purges a deleted project right away.
+func (h *TrashHandler) PurgeProject(c *gin.Context) {
+	id, exists := utils.ParamUInt(c, "id")
+	if !exists {
+		respondError(c, 400, "Invalid project ID")
+		return
+	}
+
+	project, err := h.ProjectRepository.FindDeletedByID(c.Request.Context(), id)
+	if err != nil {
+		respondLookupError(c, err, "Project not found in the trash")
+		return
+	}
+
+	jobId, err := h.JobWorker.AddJob(&model.Job{
+		Name:      fmt.Sprintf("Purge project %s", project.Name),
+		ProjectID: &project.ID,
+		Run: func(ctx context.Context, log *logger.Logger) error {
+			return h.purgeProject(ctx, project, log)
+		},
+	}, nil)
+	if err != nil {
+		respondError(c, 500, fmt.Sprintf("Failed to add job to purge project %s", project.Name))
+		return
+	}
+
+	auditJob(c, jobId)
+	c.JSON(201, gin.H{
+		"job_id": jobId,
+	})
+}
+
+// PurgeContainer purges a deleted container right away.
+func (h *TrashHandler) PurgeContainer(c *gin.Context) {
+	containerID, exists := utils.ParamUInt(c, "containerId")
+	if !exists {
+		respondError(c, 400, "Invalid container ID")
+		return
+	}
+
+	container, err := h.ContainerRepository.FindDeletedByID(c.Request.Context(), containerID)
+	if err != nil {
+		respondLookupError(c, err, "Container not found in the trash")
+		return
+	}
+
+	jobId, err := h.JobWorker.AddJob(&model.Job{
+		Name:      fmt.Sprintf("Purge container %s", container.Name),
+		ProjectID: &container.ProjectID,
+		Run: func(ctx context.Context, log *logger.Logger) error {
+			return h.purgeContainer(ctx, container, log)
+		},
+	}, nil)
+	if err != nil {
+		respondError(c, 500, fmt.Sprintf("Failed to add job to purge container %s", container.Name))
+		return
+	}
+
+	auditJob(c, jobId)
+	c.JSON(201, gin.H{
+		"job_id": jobId,
+	})
+}
+
+// StartPurgeScheduler checks the trash every interval and adds a job purging
+// the items deleted for longer than the purge delay, if any.
+func (h *TrashHandler) StartPurgeScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			h.schedulePurge(context.Background())
+			<-ticker.C
+		}
+	}()
+}
+
+func (h *TrashHandler) schedulePurge(ctx context.Context) {
+	before := time.Now().Add(-h.purgeDelay())
+	projects, err := h.ProjectRepository.FindAllDeleted(ctx, before)
+	if err != nil {
+		logger.Error("Failed to list expired projects of the trash:", err)
+		return
+	}
+	containers, err := h.ContainerRepository.FindAllDeleted(ctx, before)
+	if err != nil {
+		logger.Error("Failed to list expired containers of the trash:", err)
+		return
+	}
+	if len(projects) == 0 && len(containers) == 0 {
+		return
+	}
+
+	_, err = h.JobWorker.AddJob(&model.Job{
+		Name: "Purge trash",
+		Run: func(ctx context.Context, log *logger.Logger) error {
+			// Every item is tried, one failing does not block the others
+			var failed int
+			for _, project := range projects {
+				if err := h.purgeProject(ctx, &project, log); err != nil {
+					log.Error("%v", err)
+					failed++
+				}
+			}
+			for _, container := range containers {
+				if err := h.purgeContainer(ctx, &container, log); err != nil {
+					log.Error("%v", err)
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("failed to purge %d items, they are retried on the next purge", failed)
+			}
+			return nil
+		},
+	}, nil)
+	if err != nil {
+		logger.Error("Failed to add trash purge job:", err)
+	}
+}
+
+// purgeProject removes the Docker resources of the project, then its rows.
+// Nothing is deleted from the database when the cleanup fails, so that it can
+// be retried.
+func (h *TrashHandler) purgeProject(ctx context.Context, project *model.Project, log *logger.Logger) error {
+	all, err := h.ContainerRepository.FindAllByProjectIDWithDeleted(ctx, project.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list containers of project %s: %w", project.Name, err)
+	}
+	containers, err := h.withoutReusedNames(ctx, all, log)
+	if err != nil {
+		return err
+	}
+	builds, err := h.BuildRepository.FindAllByProjectID(ctx, project.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list builds of project %s: %w", project.Name, err)
+	}
+
+	if err := removeProjectResources(ctx, h.DockerClient, project, containers, builds, purgeOptions, log); err != nil {
+		return fmt.Errorf("failed to clean up project %s, it is kept: %w", project.Name, err)
+	}
+
+	if err := h.BuildRepository.DeleteAllByProjectID(ctx, project.ID); err != nil {
+		return fmt.Errorf("failed to delete builds of project %s: %w", project.Name, err)
+	}
+	if err := h.ProjectRepository.Purge(ctx, project.ID); err != nil {
+		return fmt.Errorf("failed to delete project %s: %w", project.Name, err)
+	}
+	log.Info("Project %s purged", project.Name)
+	return nil
+}
+
+func (h *TrashHandler) purgeContainer(ctx context.Context, container *model.Container, log *logger.Logger) error {
+	containers, err := h.withoutReusedNames(ctx, []model.Container{*container}, log)
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		if err := removeContainerResources(ctx, h.DockerClient, &c, purgeOptions, log); err != nil {
+			return fmt.Errorf("failed to clean up container %s, it is kept: %w", container.Name, err)
+		}
+	}
+
+	if err := h.ContainerRepository.Purge(ctx, container.ID); err != nil {
+		return fmt.Errorf("failed to delete container %s: %w", container.Name, err)
+	}
+	log.Info("Container %s purged", container.Name)
+	return nil
+}
+
+// withoutReusedNames drops the containers whose name has been given to a new
+// container since their deletion: the Docker container and volumes with this
+// name belong to the new one.
+func (h *TrashHandler) withoutReusedNames(ctx context.Context, containers []model.Container, log *logger.Logger) ([]model.Container, error) {
+	kept := make([]model.Container, 0, len(containers))
+	for _, container := range containers {
+		inUse, err := h.ContainerRepository.NameInUse(ctx, container.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the name of container %s: %w", container.Name, err)
+		}
+		if inUse {
+			log.Info("Container %s has been replaced, only its record is purged", container.Name)
+			continue
+		}
+		kept = append(kept, container)
+	}
+	return kept, nil
+}
+
+// purgeDelay reads the trash purge delay setting, 7 days by default.
+func (h *TrashHandler) purgeDelay() time.Duration {
+	hours := 168
+	if setting, err := h.SettingRepository.GetByKey(settings.TrashPurgeDelay); err == nil {
+		if n, err := strconv.Atoi(setting.Value); err == nil && n >= 0 {
+			hours = n
+		}
+	}
+	return time.Duration(hours) * time.Hour
+}
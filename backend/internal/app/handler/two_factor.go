@@ -0,0 +1,339 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/auth"
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/infra/settings"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/internal/app/repository"
+	"axolotl-cloud/types"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// challengeCookie holds the token of the login waiting for its second
+	// factor.
+	challengeCookie = "axolotl_2fa"
+	// totpIssuer names the account in authenticator apps.
+	totpIssuer = "Axolotl Cloud"
+
+	challengeLifetime    = 5 * time.Minute
+	maxChallengeAttempts = 5
+)
+
+type RequestSecondFactor struct {
+	// Code is a TOTP code or a recovery code.
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+	Required          bool `json:"required"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	// URI is the otpauth:// URI to show as a QR code.
+	URI string `json:"uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// twoFactorRequired reads the two-factor enforcement setting.
+func twoFactorRequired(settingRepository *repository.SettingRepository) bool {
+	setting, err := settingRepository.GetByKey(settings.TwoFactorRequired)
+	return err == nil && setting.Value == "true"
+}
+
+// needsTwoFactorSetup reports whether the user must set up TOTP before using
+// anything but its account. Single sign-on users rely on the second factor of
+// their identity provider.
+func needsTwoFactorSetup(settingRepository *repository.SettingRepository, user *model.User) bool {
+	return user.PasswordHash != nil && !user.TOTPEnabled && twoFactorRequired(settingRepository)
+}
+
+// startChallenge replaces the session of a password login when the user has a
+// second factor: the session is created by VerifyLogin.
+func (h *AuthHandler) startChallenge(c *gin.Context, user *model.User) error {
+	ctx := c.Request.Context()
+	if err := h.ChallengeRepository.DeleteExpired(ctx); err != nil {
+		logger.Error("Failed to delete expired login challenges", err)
+	}
+
+	token, err := auth.NewToken()
+	if err != nil {
+		return err
+	}
+	challenge := &model.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(challengeLifetime),
+	}
+	if err := h.ChallengeRepository.Create(ctx, challenge); err != nil {
+		return err
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(challengeCookie, token, int(challengeLifetime.Seconds()), loginCookiePath, "", secureRequest(c), true)
+	return nil
+}
+
+// VerifyLogin is the second step of a login with TOTP: it checks the code
+// against the user of the challenge cookie, then creates the session.
+func (h *AuthHandler) VerifyLogin(c *gin.Context) {
+	var request RequestSecondFactor
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid input")
+		return
+	}
+
+	ctx := c.Request.Context()
+	token, _ := c.Cookie(challengeCookie)
+	challenge, err := h.ChallengeRepository.FindByTokenHash(ctx, auth.HashToken(token))
+	if err != nil || challenge.User == nil {
+		respondError(c, 401, "Login expired, please sign in again")
+		return
+	}
+	// New challenges do not give more attempts at guessing the code
+	if wait := h.lockoutRemaining(c, challenge.User.Username); wait > 0 {
+		tooManyRequests(c, wait, "Too many failed logins, please retry later")
+		return
+	}
+	if challenge.Attempts >= maxChallengeAttempts {
+		if err := h.ChallengeRepository.Delete(ctx, challenge.ID); err != nil {
+			logger.Error("Failed to delete login challenge", err)
+		}
+		respondError(c, 401, "Too many invalid codes, please sign in again")
+		return
+	}
+
+	valid, err := h.checkSecondFactor(ctx, challenge.User, request.Code)
+	if err != nil {
+		respondError(c, 500, "Failed to check code")
+		return
+	}
+	if !valid {
+		if err := h.ChallengeRepository.AddAttempt(ctx, challenge.ID); err != nil {
+			logger.Error("Failed to count login attempt", err)
+		}
+		h.failLogin(c, challenge.User.Username)
+		respondError(c, 401, "Invalid code")
+		return
+	}
+
+	if err := h.ChallengeRepository.Delete(ctx, challenge.ID); err != nil {
+		respondError(c, 500, "Failed to delete login challenge")
+		return
+	}
+	c.SetCookie(challengeCookie, "", -1, loginCookiePath, "", secureRequest(c), true)
+	if err := h.startSession(c, challenge.User); err != nil {
+		respondError(c, 500, "Failed to create session")
+		return
+	}
+	h.resetLogin(c, challenge.User.Username)
+	c.JSON(200, challenge.User)
+}
+
+// checkSecondFactor checks a TOTP code, or consumes a recovery code. Each
+// code is only accepted once.
+func (h *AuthHandler) checkSecondFactor(ctx context.Context, user *model.User, code string) (bool, error) {
+	if user.TOTPSecret == nil {
+		return false, nil
+	}
+	secret, err := h.Cipher.Decrypt(user.TOTPSecret)
+	if err != nil {
+		return false, err
+	}
+	if step, ok := auth.CheckTOTP(string(secret), code, time.Now()); ok {
+		return h.UserRepository.UseTOTPStep(ctx, user.ID, step)
+	}
+	if !user.TOTPEnabled {
+		return false, nil
+	}
+	return h.UserRepository.UseRecoveryCode(ctx, user.ID, auth.HashToken(auth.NormalizeRecoveryCode(code)))
+}
+
+func (h *AuthHandler) GetTwoFactorStatus(c *gin.Context) {
+	user := CurrentUser(c)
+	c.JSON(200, TwoFactorStatusResponse{
+		Enabled:           user.TOTPEnabled,
+		RecoveryCodesLeft: len(user.RecoveryCodes),
+		Required:          twoFactorRequired(h.SettingRepository),
+	})
+}
+
+// SetupTwoFactor generates the TOTP secret of the current user. It is only
+// enabled once a code of the authenticator app is given to EnableTwoFactor.
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	user := CurrentUser(c)
+	if user.PasswordHash == nil {
+		respondError(c, 400, "Single sign-on users use the second factor of their identity provider")
+		return
+	}
+	if user.TOTPEnabled {
+		respondError(c, 409, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		respondError(c, 500, "Failed to generate secret")
+		return
+	}
+	encrypted, err := h.Cipher.Encrypt([]byte(secret))
+	if err != nil {
+		respondError(c, 500, "Failed to encrypt secret")
+		return
+	}
+	if err := h.UserRepository.SetTOTPSecret(c.Request.Context(), user.ID, encrypted); err != nil {
+		respondError(c, 500, "Failed to save secret")
+		return
+	}
+
+	c.JSON(200, TwoFactorSetupResponse{
+		Secret: secret,
+		URI:    auth.TOTPURI(totpIssuer, user.Username, secret),
+	})
+}
+
+// EnableTwoFactor confirms the setup with a first code, and returns the
+// recovery codes. The other sessions of the user, opened with the password
+// only, are revoked.
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	var request RequestSecondFactor
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid input")
+		return
+	}
+
+	user := CurrentUser(c)
+	if user.TOTPEnabled {
+		respondError(c, 409, "Two-factor authentication is already enabled")
+		return
+	}
+	if user.TOTPSecret == nil {
+		respondError(c, 400, "Two-factor authentication has not been set up")
+		return
+	}
+	valid, err := h.checkSecondFactor(c.Request.Context(), user, request.Code)
+	if err != nil {
+		respondError(c, 500, "Failed to check code")
+		return
+	}
+	if !valid {
+		respondError(c, 400, "Invalid code")
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		respondError(c, 500, "Failed to generate recovery codes")
+		return
+	}
+	if err := h.UserRepository.EnableTOTP(c.Request.Context(), user.ID, hashes); err != nil {
+		respondError(c, 500, "Failed to enable two-factor authentication")
+		return
+	}
+
+	var keep uint
+	if session := currentSession(c); session != nil {
+		keep = session.ID
+	}
+	if err := h.SessionRepository.DeleteAllByUserID(c.Request.Context(), user.ID, keep); err != nil {
+		logger.Error("Failed to revoke other sessions", err)
+	}
+	c.JSON(200, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor turns off TOTP with a last code. It is refused while the
+// second factor is required.
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var request RequestSecondFactor
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid input")
+		return
+	}
+
+	user := CurrentUser(c)
+	if !user.TOTPEnabled {
+		respondError(c, 400, "Two-factor authentication is not enabled")
+		return
+	}
+	if twoFactorRequired(h.SettingRepository) {
+		respondError(c, 403, "Two-factor authentication is required")
+		return
+	}
+	if status, message := h.requireSecondFactor(c, user, request.Code); status != 0 {
+		respondError(c, status, message)
+		return
+	}
+
+	if err := h.UserRepository.ResetTOTP(c.Request.Context(), user.ID); err != nil {
+		respondError(c, 500, "Failed to disable two-factor authentication")
+		return
+	}
+	c.Status(204)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current user.
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var request RequestSecondFactor
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid input")
+		return
+	}
+
+	user := CurrentUser(c)
+	if !user.TOTPEnabled {
+		respondError(c, 400, "Two-factor authentication is not enabled")
+		return
+	}
+	if status, message := h.requireSecondFactor(c, user, request.Code); status != 0 {
+		respondError(c, status, message)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		respondError(c, 500, "Failed to generate recovery codes")
+		return
+	}
+	if err := h.UserRepository.SetRecoveryCodes(c.Request.Context(), user.ID, hashes); err != nil {
+		respondError(c, 500, "Failed to save recovery codes")
+		return
+	}
+	c.JSON(200, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// requireSecondFactor checks the code given to confirm a change of the second
+// factor. It returns the error status and message, or 0.
+func (h *AuthHandler) requireSecondFactor(c *gin.Context, user *model.User, code string) (int, string) {
+	valid, err := h.checkSecondFactor(c.Request.Context(), user, code)
+	if err != nil {
+		return 500, "Failed to check code"
+	}
+	if !valid {
+		return 403, "Invalid code"
+	}
+	return 0, ""
+}
+
+// newRecoveryCodes returns new recovery codes, and the hashes to store.
+func newRecoveryCodes() ([]string, types.StringList, error) {
+	codes, err := auth.NewRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make(types.StringList, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashToken(code)
+	}
+	return codes, hashes, nil
+}
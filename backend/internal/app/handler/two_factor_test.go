@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/auth"
+	"axolotl-cloud/infra/ratelimit"
+	"axolotl-cloud/infra/secrets"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/internal/app/repository"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// totpCodeAt computes the code of an authenticator app for secret at a time.
+func totpCodeAt(t *testing.T, secret string, at time.Time) string {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+// newTwoFactorHandler returns an AuthHandler and a user with TOTP enabled
+// and the given recovery codes.
+func newTwoFactorHandler(t *testing.T, recoveryCodes ...string) (*AuthHandler, *model.User, string) {
+	users := newTestUserRepository(t)
+	cipher, err := secrets.NewCipher(make([]byte, secrets.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &AuthHandler{
+		UserRepository:      users,
+		SessionRepository:   &repository.SessionRepository{DB: users.DB},
+		ChallengeRepository: &repository.LoginChallengeRepository{DB: users.DB},
+		SettingRepository:   repository.NewSettingRepository(users.DB),
+		Cipher:              cipher,
+		Lockout:             ratelimit.NewLockout(100, time.Minute, time.Hour),
+		UserLockout:         ratelimit.NewLockout(100, time.Minute, time.Hour),
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := cipher.Encrypt([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{Username: "alice", TOTPSecret: encrypted, TOTPEnabled: true}
+	for _, code := range recoveryCodes {
+		user.RecoveryCodes = append(user.RecoveryCodes, auth.HashToken(code))
+	}
+	if err := users.Create(t.Context(), user); err != nil {
+		t.Fatal(err)
+	}
+	return h, user, secret
+}
+
+// TestCheckSecondFactor checks that every TOTP and recovery code is only
+// accepted once.
+func TestCheckSecondFactor(t *testing.T) {
+	h, user, secret := newTwoFactorHandler(t, "aaaaa-bbbbb", "ccccc-ddddd")
+	now := time.Now()
+	current := totpCodeAt(t, secret, now)
+	previous := totpCodeAt(t, secret, now.Add(-30*time.Second))
+	next := totpCodeAt(t, secret, now.Add(30*time.Second))
+
+	// The steps run in order, each depends on the codes used before
+	steps := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{name: "current code", code: current, want: true},
+		{name: "current code replayed", code: current, want: false},
+		{name: "previous code after the current one", code: previous, want: previous == next},
+		{name: "next code", code: next, want: true},
+		{name: "next code replayed", code: next, want: false},
+		{name: "recovery code", code: "aaaaa-bbbbb", want: true},
+		{name: "recovery code reused", code: "aaaaa-bbbbb", want: false},
+		{name: "recovery code as typed", code: "CCCCC DDDDD", want: true},
+		{name: "recovery code reused as typed", code: "cccccddddd", want: false},
+		{name: "unknown recovery code", code: "eeeee-fffff", want: false},
+	}
+	for _, step := range steps {
+		user, err := h.UserRepository.FindByID(t.Context(), user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := h.checkSecondFactor(t.Context(), user, step.code)
+		if err != nil {
+			t.Fatalf("%s: checkSecondFactor() = %v", step.name, err)
+		}
+		if got != step.want {
+			t.Errorf("%s: checkSecondFactor(%q) = %v, want %v", step.name, step.code, got, step.want)
+		}
+	}
+
+	user, err := h.UserRepository.FindByID(t.Context(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(user.RecoveryCodes) != 0 {
+		t.Errorf("%d recovery codes left, want 0", len(user.RecoveryCodes))
+	}
+}
+
+// TestCheckSecondFactorSetup checks that recovery codes are refused until
+// TOTP is enabled.
+func TestCheckSecondFactorSetup(t *testing.T) {
+	h, user, _ := newTwoFactorHandler(t, "aaaaa-bbbbb")
+	user.TOTPEnabled = false
+	if got, err := h.checkSecondFactor(t.Context(), user, "aaaaa-bbbbb"); err != nil || got {
+		t.Errorf("checkSecondFactor() = %v, %v, want false", got, err)
+	}
+	user.TOTPSecret = nil
+	if got, err := h.checkSecondFactor(t.Context(), user, "aaaaa-bbbbb"); err != nil || got {
+		t.Errorf("checkSecondFactor() without secret = %v, %v, want false", got, err)
+	}
+}
+
+// TestVerifyLoginAttempts checks that a login challenge only allows a few
+// invalid codes, even when the right one comes next.
+func TestVerifyLoginAttempts(t *testing.T) {
+	h, user, secret := newTwoFactorHandler(t)
+	token, err := auth.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge := &model.LoginChallenge{UserID: user.ID, TokenHash: auth.HashToken(token), ExpiresAt: time.Now().Add(challengeLifetime)}
+	if err := h.ChallengeRepository.Create(t.Context(), challenge); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	wrong := "000000"
+	for _, at := range []time.Time{now.Add(-30 * time.Second), now, now.Add(30 * time.Second)} {
+		if totpCodeAt(t, secret, at) == wrong {
+			wrong = "999999"
+		}
+	}
+
+	verify := func(code string) (int, string) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/v1/auth/login/verify", strings.NewReader(`{"code": "`+code+`"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.AddCookie(&http.Cookie{Name: challengeCookie, Value: token})
+		h.VerifyLogin(c)
+		return w.Code, w.Body.String()
+	}
+
+	for i := range maxChallengeAttempts {
+		if status, body := verify(wrong); status != 401 || !strings.Contains(body, "Invalid code") {
+			t.Fatalf("attempt %d: VerifyLogin() = %d %s, want 401 Invalid code", i+1, status, body)
+		}
+	}
+	valid := totpCodeAt(t, secret, time.Now())
+	if status, body := verify(valid); status != 401 || !strings.Contains(body, "Too many invalid codes") {
+		t.Fatalf("VerifyLogin() after %d invalid codes = %d %s, want 401 Too many invalid codes", maxChallengeAttempts, status, body)
+	}
+	if status, body := verify(valid); status != 401 || !strings.Contains(body, "Login expired") {
+		t.Errorf("VerifyLogin() with a deleted challenge = %d %s, want 401 Login expired", status, body)
+	}
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"axolotl-cloud/infra/auth"
+	"axolotl-cloud/infra/logger"
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/internal/app/repository"
+	"axolotl-cloud/utils"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler manages the users. Its routes are restricted to admins.
+type UserHandler struct {
+	UserRepository    *repository.UserRepository
+	SessionRepository *repository.SessionRepository
+}
+
+type RequestCreateUser struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Admin    bool   `json:"admin"`
+	// ProjectCreator allows the user to create projects without being admin.
+	ProjectCreator bool `json:"project_creator"`
+}
+
+// RequestUpdateUser changes the admin flag, the project creator flag or resets
+// the password of a user. Resetting the password revokes the sessions of the
+// user.
+type RequestUpdateUser struct {
+	Admin          *bool  `json:"admin"`
+	ProjectCreator *bool  `json:"project_creator"`
+	Password       string `json:"password"`
+}
+
+func (h *UserHandler) GetAllUsers(c *gin.Context) {
+	users, err := h.UserRepository.FindAll(c.Request.Context())
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve users")
+		return
+	}
+	c.JSON(200, users)
+}
+
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var request RequestCreateUser
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid input")
+		return
+	}
+
+	if _, err := h.UserRepository.FindByUsername(c.Request.Context(), request.Username); err == nil {
+		respondError(c, 409, fmt.Sprintf("User %s already exists", request.Username))
+		return
+	}
+	hash, err := auth.HashPassword(request.Password)
+	if err != nil {
+		respondError(c, 400, err.Error())
+		return
+	}
+
+	user := &model.User{Username: request.Username, PasswordHash: hash, Admin: request.Admin, ProjectCreator: request.ProjectCreator}
+	if err := h.UserRepository.Create(c.Request.Context(), user); err != nil {
+		respondError(c, 500, "Failed to create user")
+		return
+	}
+	auditTarget(c, user.ID)
+	c.JSON(201, user)
+}
+
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	userID, exists := utils.ParamUInt(c, "userId")
+	if !exists {
+		respondError(c, 400, "Invalid user ID")
+		return
+	}
+
+	var request RequestUpdateUser
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalid(c, err, "Invalid input")
+		return
+	}
+
+	user, err := h.UserRepository.FindByID(c.Request.Context(), userID)
+	if err != nil {
+		respondLookupError(c, err, "User not found")
+		return
+	}
+
+	before := *user
+	if request.Admin != nil && *request.Admin != user.Admin {
+		if !*request.Admin {
+			last, err := lastAdmin(c.Request.Context(), h.UserRepository, user)
+			if err != nil {
+				respondError(c, 500, "Failed to retrieve users")
+				return
+			}
+			if last {
+				respondError(c, 409, "The last admin cannot be demoted")
+				return
+			}
+		}
+		if err := h.UserRepository.SetAdmin(c.Request.Context(), user.ID, *request.Admin); err != nil {
+			respondError(c, 500, "Failed to update user")
+			return
+		}
+		user.Admin = *request.Admin
+	}
+
+	if request.ProjectCreator != nil && *request.ProjectCreator != user.ProjectCreator {
+		if err := h.UserRepository.SetProjectCreator(c.Request.Context(), user.ID, *request.ProjectCreator); err != nil {
+			respondError(c, 500, "Failed to update user")
+			return
+		}
+		user.ProjectCreator = *request.ProjectCreator
+	}
+
+	if request.Password != "" {
+		hash, err := auth.HashPassword(request.Password)
+		if err != nil {
+			respondError(c, 400, err.Error())
+			return
+		}
+		if err := h.UserRepository.UpdatePassword(c.Request.Context(), user.ID, hash); err != nil {
+			respondError(c, 500, "Failed to update password")
+			return
+		}
+		if err := h.SessionRepository.DeleteAllByUserID(c.Request.Context(), user.ID, 0); err != nil {
+			respondError(c, 500, "Failed to revoke sessions")
+			return
+		}
+	}
+
+	auditChanges(c,
+		gin.H{"admin": before.Admin, "project_creator": before.ProjectCreator, "password_reset": false},
+		gin.H{"admin": user.Admin, "project_creator": user.ProjectCreator, "password_reset": request.Password != ""})
+	c.JSON(200, user)
+}
+
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	userID, exists := utils.ParamUInt(c, "userId")
+	if !exists {
+		respondError(c, 400, "Invalid user ID")
+		return
+	}
+
+	user, err := h.UserRepository.FindByID(c.Request.Context(), userID)
+	if err != nil {
+		respondLookupError(c, err, "User not found")
+		return
+	}
+	last, err := lastAdmin(c.Request.Context(), h.UserRepository, user)
+	if err != nil {
+		respondError(c, 500, "Failed to retrieve users")
+		return
+	}
+	if last {
+		respondError(c, 409, "The last admin cannot be deleted")
+		return
+	}
+
+	if err := h.UserRepository.Delete(c.Request.Context(), user.ID); err != nil {
+		respondError(c, 500, "Failed to delete user")
+		return
+	}
+	c.Status(204)
+}
+
+// ResetTwoFactor turns off the second factor of a user who lost it. The user
+// logs in with its password only, and sets it up again when it is required.
+func (h *UserHandler) ResetTwoFactor(c *gin.Context) {
+	userID, exists := utils.ParamUInt(c, "userId")
+	if !exists {
+		respondError(c, 400, "Invalid user ID")
+		return
+	}
+
+	user, err := h.UserRepository.FindByID(c.Request.Context(), userID)
+	if err != nil {
+		respondLookupError(c, err, "User not found")
+		return
+	}
+	if err := h.UserRepository.ResetTOTP(c.Request.Context(), user.ID); err != nil {
+		respondError(c, 500, "Failed to reset two-factor authentication")
+		return
+	}
+	logger.Info("Two-factor authentication of %s reset by %s", user.Username, CurrentUser(c).Username)
+	c.Status(204)
+}
@@ -5,27 +5,39 @@ import (
 	"axolotl-cloud/infra/logger"
 	"axolotl-cloud/internal/app/model"
 	"axolotl-cloud/internal/app/repository"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 )
 
 type VolumeHandler struct {
 	ContainerRepository *repository.ContainerRepository
+	Access              *Access
 	DockerClient        *docker.DockerClient
 }
 
 func (h *VolumeHandler) GetVolumes(c *gin.Context) {
 	containers, err := h.ContainerRepository.GetAllContainers(c.Request.Context())
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to retrieve containers"})
+		respondError(c, 500, "Failed to retrieve containers")
 		return
 	}
+	ids, all, err := h.Access.ReadableProjects(c)
+	if err != nil {
+		respondError(c, 500, "Failed to check permissions")
+		return
+	}
+	if !all {
+		containers = slices.DeleteFunc(containers, func(container model.Container) bool {
+			return !slices.Contains(ids, container.ProjectID)
+		})
+	}
 	var allVolumes []*model.Volume
 	for _, container := range containers {
 		volumes, err := h.DockerClient.ContainerVolumes(c.Request.Context(), container.Name)
 		if err != nil {
 			logger.Error("Failed to get volumes for container", err)
-			c.JSON(500, gin.H{"error": "Failed to retrieve container volumes"})
+			respondError(c, 500, "Failed to retrieve container volumes")
 			return
 		}
 		for _, volume := range volumes {
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"axolotl-cloud/types"
+	"slices"
+	"time"
+)
+
+type TokenScope string
+
+const (
+	// ScopeRead allows the read-only routes.
+	ScopeRead TokenScope = "read"
+	// ScopeDeploy allows ScopeRead and starting, stopping, building and
+	// rolling back containers.
+	ScopeDeploy TokenScope = "deploy"
+	// ScopeAdmin allows every route except the ones managing credentials.
+	ScopeAdmin TokenScope = "admin"
+)
+
+// APIToken authenticates automation through the Authorization: Bearer
+// header. A personal token acts as its user, limited by its scopes, a
+// service token (without user) only has its scopes. Only the hash of the
+// token is stored.
+type APIToken struct {
+	ID         uint             `gorm:"primaryKey" json:"id"`
+	Name       string           `json:"name"`
+	UserID     *uint            `gorm:"index" json:"user_id"` // nil for a service token
+	TokenHash  string           `gorm:"uniqueIndex" json:"-"`
+	Prefix     string           `json:"prefix"` // start of the token, to recognize it
+	Scopes     types.StringList `gorm:"type:text" json:"scopes"`
+	ProjectID  *uint            `gorm:"index" json:"project_id"` // restricts the token to one project
+	ExpiresAt  *time.Time       `json:"expires_at"`
+	LastUsedAt *time.Time       `json:"last_used_at"`
+	CreatedAt  time.Time        `json:"created_at" gorm:"autoCreateTime"`
+	User       *User            `json:"-"`
+}
+
+// HasScope reports whether the token grants scope, admin including deploy
+// and deploy including read.
+func (t *APIToken) HasScope(scope TokenScope) bool {
+	granted := []TokenScope{scope}
+	switch scope {
+	case ScopeRead:
+		granted = append(granted, ScopeDeploy, ScopeAdmin)
+	case ScopeDeploy:
+		granted = append(granted, ScopeAdmin)
+	}
+	return slices.ContainsFunc(t.Scopes, func(s string) bool {
+		return slices.Contains(granted, TokenScope(s))
+	})
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"axolotl-cloud/types"
+	"time"
+)
+
+// AuditEvent records a mutating request: who did what to which target, and
+// its outcome. Events are append-only, they outlive their actor and target
+// and have no foreign keys.
+type AuditEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	// Actor is the username, or the token name for service tokens, empty for
+	// anonymous requests (e.g. failed logins).
+	Actor   string `json:"actor"`
+	UserID  *uint  `gorm:"index" json:"user_id"`
+	TokenID *uint  `json:"token_id"`
+	IP      string `json:"ip"`
+	// Action names what was done, e.g. container.start.
+	Action     string `gorm:"index" json:"action"`
+	TargetType string `gorm:"index:idx_audit_target" json:"target_type"`
+	TargetID   string `gorm:"index:idx_audit_target" json:"target_id"`
+	ProjectID  *uint  `gorm:"index" json:"project_id"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Status     int    `json:"status"`
+	// Changes maps the changed fields to their before and after values.
+	Changes types.JSONMap `gorm:"type:text" json:"changes,omitempty"`
+	// JobID is the job started by the request, if any.
+	JobID *uint `json:"job_id"`
+}
@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// BuildStep is the state of a single BuildKit vertex (a Dockerfile
+// instruction or an internal step such as loading the build context).
+type BuildStep struct {
+	Digest    string     `json:"digest"`
+	Name      string     `json:"name"`
+	Cached    bool       `json:"cached"`
+	Started   *time.Time `json:"started,omitempty"`
+	Completed *time.Time `json:"completed,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+type BuildSource string
+
+const (
+	BuildSourceGit     BuildSource = "git"
+	BuildSourceArchive BuildSource = "archive"
+)
+
+type BuildStatus string
+
+const (
+	BuildStatusRunning   BuildStatus = "running"
+	BuildStatusSucceeded BuildStatus = "succeeded"
+	BuildStatusFailed    BuildStatus = "failed"
+)
+
+// Build is one image built for a container. All the images of a single build
+// job share the same Number.
+type Build struct {
+	ID          uint        `gorm:"primaryKey" json:"id"`
+	ProjectID   uint        `json:"project_id" gorm:"index;uniqueIndex:idx_builds_number"`
+	ContainerID *uint       `json:"container_id" gorm:"index;default:null"`
+	Number      uint        `json:"number" gorm:"uniqueIndex:idx_builds_number"`
+	Service     string      `json:"service" gorm:"uniqueIndex:idx_builds_number"`
+	Source      BuildSource `json:"source"`
+	SourceRef   string      `json:"source_ref"` // git URL or archive file name
+	CommitSHA   string      `json:"commit_sha"`
+	ImageID     string      `json:"image_id"`
+	Tag         string      `json:"tag"`
+	Duration    int64       `json:"duration"` // milliseconds
+	Status      BuildStatus `json:"status"`
+	JobID       uint        `json:"job_id"`
+	CreatedAt   int64       `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -7,6 +7,7 @@ type ComposeService struct {
 	Volumes     []string          `yaml:"volumes"`
 	Networks    []string          `yaml:"networks"`
 	NetworkMode string            `yaml:"network_mode" default:"bridge"`
+	Platform    string            `yaml:"platform,omitempty"`
 	Build       *ComposeBuild     `yaml:"build,omitempty"`
 }
 
@@ -18,4 +19,9 @@ type ComposeBuild struct {
 	Context    string            `yaml:"context"`
 	Dockerfile string            `yaml:"dockerfile,omitempty"`
 	Args       map[string]string `yaml:"args,omitempty"`
+	Target     string            `yaml:"target,omitempty"`
+	Labels     map[string]string `yaml:"labels,omitempty"`
+	CacheFrom  []string          `yaml:"cache_from,omitempty"`
+	Secrets    []string          `yaml:"secrets,omitempty"`
+	Network    string            `yaml:"network,omitempty"`
 }
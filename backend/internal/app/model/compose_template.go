@@ -0,0 +1,20 @@
+package model
+
+import (
+	"axolotl-cloud/types"
+	"time"
+)
+
+// ComposeTemplate is the last compose file imported into a project. It is
+// kept with its variables unresolved so that the containers can be rendered
+// again once the project variables change.
+type ComposeTemplate struct {
+	ID        uint             `gorm:"primaryKey" json:"id"`
+	ProjectID uint             `gorm:"uniqueIndex" json:"project_id"`
+	Content   string           `gorm:"type:text" json:"compose_file"`
+	Overrides types.StringList `gorm:"type:text" json:"overrides"`
+	Profiles  types.StringList `gorm:"type:text" json:"profiles"`
+	EnvFile   string           `gorm:"type:text" json:"env_file"` // content of the .env file
+	Files     types.StringMap  `gorm:"type:text" json:"files"`    // files referenced by include, extends or env_file, by path
+	UpdatedAt time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -1,6 +1,36 @@
 package model
 
-import "axolotl-cloud/types"
+import (
+	"axolotl-cloud/types"
+
+	"gorm.io/gorm"
+)
+
+type DeployStrategy string
+
+const (
+	// DeployRecreate removes the old container before creating the new one.
+	DeployRecreate DeployStrategy = "recreate"
+	// DeployBlueGreen keeps the old container running until the new one is
+	// healthy, and rolls back when it never becomes healthy.
+	DeployBlueGreen DeployStrategy = "blue_green"
+)
+
+type ProbeType string
+
+const (
+	ProbeTCP  ProbeType = "tcp"
+	ProbeHTTP ProbeType = "http"
+)
+
+// HealthProbe checks that a new container is ready during a blue/green
+// deployment, for images without a Docker healthcheck.
+type HealthProbe struct {
+	Type    ProbeType `json:"type" binding:"required,oneof=tcp http"`
+	Port    string    `json:"port" binding:"required,numeric"`       // container port
+	Path    string    `json:"path" binding:"omitempty,startswith=/"` // HTTP only, defaults to /
+	Timeout int       `json:"timeout"`                               // seconds, defaults to 60
+}
 
 type Container struct {
 	ID          uint             `gorm:"primaryKey" json:"id"`
@@ -10,8 +40,24 @@ type Container struct {
 	Volumes     types.StringMap  `gorm:"type:text" json:"volumes"`
 	Name        string           `json:"name" binding:"required"`
 	ProjectID   uint             `json:"project_id"`
+	DeletedAt   gorm.DeletedAt   `json:"deleted_at" gorm:"index"` // set while the container is in the trash
 	Networks    types.StringList `gorm:"type:text" json:"networks"`
 	NetworkMode string           `json:"network_mode" binding:"required,oneof=bridge host none" gorm:"default:bridge"`
-	Jobs        []Job            `gorm:"foreignKey:ContainerID;constraint:OnDelete:CASCADE" json:"jobs"`
-	LastJob     Job              `gorm:"foreignKey:ContainerID;constraint:OnDelete:SET NULL" json:"last_job,omitempty"`
+	// DeployStrategy is the way the Docker container is replaced when its
+	// configuration changes.
+	DeployStrategy DeployStrategy `json:"deploy_strategy" binding:"omitempty,oneof=recreate blue_green" gorm:"default:recreate"`
+	HealthProbe    *HealthProbe   `json:"health_probe" gorm:"serializer:json"`
+	// RestartRequired is set when a secret referenced by Env is rotated or a
+	// revision is restored, until the container is started again.
+	RestartRequired bool `json:"restart_required" gorm:"default:false"`
+	// AppliedConfig is the configuration the Docker container was last created
+	// from, nil until Axolotl starts the container.
+	AppliedConfig *ContainerConfig `json:"-" gorm:"serializer:json"`
+	// PendingChanges tells whether the Docker container needs to be recreated
+	// to apply the current configuration, Changes lists what differs.
+	PendingChanges bool                `json:"pending_changes" gorm:"-"`
+	Changes        []ConfigChange      `json:"changes,omitempty" gorm:"-"`
+	Jobs           []Job               `gorm:"foreignKey:ContainerID;constraint:OnDelete:CASCADE" json:"jobs"`
+	Revisions      []ContainerRevision `gorm:"foreignKey:ContainerID;constraint:OnDelete:CASCADE" json:"-"`
+	LastJob        Job                 `gorm:"foreignKey:ContainerID;constraint:OnDelete:SET NULL" json:"last_job,omitempty"`
 }
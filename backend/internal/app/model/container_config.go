@@ -0,0 +1,69 @@
+package model
+
+import (
+	"maps"
+	"slices"
+)
+
+// ContainerConfig is the configuration a Docker container is created from.
+// Env is the effective env of the container, secret references unresolved.
+type ContainerConfig struct {
+	Image       string            `json:"image"`
+	Ports       map[string]string `json:"ports"`
+	Env         map[string]string `json:"env"`
+	Volumes     map[string]string `json:"volumes"`
+	NetworkMode string            `json:"network_mode"`
+}
+
+// ConfigChange is a field-level difference between two configurations.
+// Old is nil for added fields and New for removed ones.
+type ConfigChange struct {
+	Field string  `json:"field"`
+	Old   *string `json:"old"`
+	New   *string `json:"new"`
+}
+
+// Diff returns the changes from c to other.
+func (c ContainerConfig) Diff(other ContainerConfig) []ConfigChange {
+	changes := []ConfigChange{}
+	changes = appendChange(changes, "image", c.Image, other.Image)
+	changes = appendMapChanges(changes, "ports", c.Ports, other.Ports)
+	changes = appendMapChanges(changes, "env", c.Env, other.Env)
+	changes = appendMapChanges(changes, "volumes", c.Volumes, other.Volumes)
+	changes = appendChange(changes, "network_mode", c.NetworkMode, other.NetworkMode)
+	return changes
+}
+
+func appendChange(changes []ConfigChange, field string, old string, new string) []ConfigChange {
+	if old == new {
+		return changes
+	}
+	return append(changes, ConfigChange{Field: field, Old: &old, New: &new})
+}
+
+func appendMapChanges(changes []ConfigChange, field string, old map[string]string, new map[string]string) []ConfigChange {
+	keys := slices.Collect(maps.Keys(old))
+	for key := range new {
+		if _, ok := old[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		oldValue, inOld := old[key]
+		newValue, inNew := new[key]
+		if inOld && inNew && oldValue == newValue {
+			continue
+		}
+		change := ConfigChange{Field: field + "." + key}
+		if inOld {
+			change.Old = &oldValue
+		}
+		if inNew {
+			change.New = &newValue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
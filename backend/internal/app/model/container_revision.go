@@ -0,0 +1,73 @@
+package model
+
+import (
+	"encoding/json"
+	"maps"
+	"time"
+)
+
+// ContainerRevision is an immutable snapshot of the configuration of a
+// container, recorded on every create and update.
+type ContainerRevision struct {
+	ID          uint              `gorm:"primaryKey" json:"id"`
+	ContainerID uint              `gorm:"uniqueIndex:idx_container_revision" json:"container_id"`
+	Number      uint              `gorm:"uniqueIndex:idx_container_revision" json:"number"`
+	Snapshot    ContainerSnapshot `gorm:"serializer:json" json:"snapshot"`
+	CreatedAt   time.Time         `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// ContainerSnapshot holds the editable configuration of a container.
+type ContainerSnapshot struct {
+	DockerImage    string            `json:"docker_image"`
+	Ports          map[string]string `json:"ports"`
+	Env            map[string]string `json:"env"`
+	Volumes        map[string]string `json:"volumes"`
+	Networks       []string          `json:"networks"`
+	NetworkMode    string            `json:"network_mode"`
+	DeployStrategy DeployStrategy    `json:"deploy_strategy"`
+	HealthProbe    *HealthProbe      `json:"health_probe"`
+}
+
+func SnapshotOf(c *Container) ContainerSnapshot {
+	return ContainerSnapshot{
+		DockerImage:    c.DockerImage,
+		Ports:          maps.Clone(c.Ports),
+		Env:            maps.Clone(c.Env),
+		Volumes:        maps.Clone(c.Volumes),
+		Networks:       c.Networks,
+		NetworkMode:    c.NetworkMode,
+		DeployStrategy: c.DeployStrategy,
+		HealthProbe:    c.HealthProbe,
+	}
+}
+
+// ApplyTo sets the configuration of the snapshot on c.
+func (s ContainerSnapshot) ApplyTo(c *Container) {
+	c.DockerImage = s.DockerImage
+	c.Ports = maps.Clone(s.Ports)
+	c.Env = maps.Clone(s.Env)
+	c.Volumes = maps.Clone(s.Volumes)
+	c.Networks = s.Networks
+	c.NetworkMode = s.NetworkMode
+	c.DeployStrategy = s.DeployStrategy
+	c.HealthProbe = s.HealthProbe
+}
+
+// Diff returns the changes from s to other.
+func (s ContainerSnapshot) Diff(other ContainerSnapshot) []ConfigChange {
+	changes := []ConfigChange{}
+	changes = appendChange(changes, "docker_image", s.DockerImage, other.DockerImage)
+	changes = appendMapChanges(changes, "ports", s.Ports, other.Ports)
+	changes = appendMapChanges(changes, "env", s.Env, other.Env)
+	changes = appendMapChanges(changes, "volumes", s.Volumes, other.Volumes)
+	changes = appendChange(changes, "networks", jsonString(s.Networks), jsonString(other.Networks))
+	changes = appendChange(changes, "network_mode", s.NetworkMode, other.NetworkMode)
+	changes = appendChange(changes, "deploy_strategy", string(s.DeployStrategy), string(other.DeployStrategy))
+	changes = appendChange(changes, "health_probe", jsonString(s.HealthProbe), jsonString(other.HealthProbe))
+	return changes
+}
+
+func jsonString(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
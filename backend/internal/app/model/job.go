@@ -14,6 +14,9 @@ type Job struct {
 	CreatedAt   int64                                                     `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   int64                                                     `json:"updated_at" gorm:"autoUpdateTime"`
 	ContainerID *uint                                                     `json:"container_id" gorm:"default:null"`
+	// ProjectID is the project the job works on, which also covers the jobs
+	// without container such as builds. Null for the jobs of the server.
+	ProjectID *uint `json:"project_id" gorm:"index;default:null"`
 }
 
 type JobLog struct {
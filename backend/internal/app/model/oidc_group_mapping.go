@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// OIDCGroupMapping grants a role to the members of an OpenID Connect group.
+// Without project, the mapping makes the members global admins and Role is
+// ignored.
+type OIDCGroupMapping struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	Group     string      `gorm:"index" json:"group"`
+	ProjectID *uint       `json:"project_id"`
+	Role      ProjectRole `json:"role"`
+	CreatedAt time.Time   `json:"created_at" gorm:"autoCreateTime"`
+}
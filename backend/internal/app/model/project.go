@@ -1,15 +1,29 @@
 package model
 
 import (
+	"axolotl-cloud/types"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Project struct {
-	ID         uint        `gorm:"primaryKey" json:"id"`
-	Name       string      `json:"name" binding:"required"`
-	IconURL    string      `json:"icon_url"`
-	WebsiteURL string      `json:"website_url" gorm:"default:''"`
-	CreatedAt  time.Time   `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt  time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
-	Containers []Container `json:"containers" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	Name       string          `json:"name" binding:"required"`
+	IconURL    string          `json:"icon_url"`
+	WebsiteURL string          `json:"website_url" gorm:"default:''"`
+	Dockerfile string          `json:"dockerfile" gorm:"type:text;default:''"` // used instead of a generated Dockerfile
+	Env        types.StringMap `json:"env" gorm:"type:text"`                   // inherited by every container, container values win
+	Variables  types.StringMap `json:"variables" gorm:"type:text"`             // interpolated in compose files
+	CreatedAt  time.Time       `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt  gorm.DeletedAt  `json:"deleted_at" gorm:"index"` // set while the project is in the trash
+	Containers []Container     `json:"containers" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+
+	ComposeTemplate *ComposeTemplate   `json:"-" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+	Tokens          []APIToken         `json:"-" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+	Members         []ProjectMember    `json:"-" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+	GroupMappings   []OIDCGroupMapping `json:"-" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+
+	BuildNumber uint `json:"-" gorm:"default:0"` // number of the last build, see BuildRepository.NextNumber
 }
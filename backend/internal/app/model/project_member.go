@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// ProjectRole is the role of a user on a project. Global admins (User.Admin)
+// act as owners of every project.
+type ProjectRole string
+
+const (
+	// RoleViewer reads the project, its containers, logs and jobs.
+	RoleViewer ProjectRole = "viewer"
+	// RoleDeployer also starts, stops, builds and rolls back containers.
+	RoleDeployer ProjectRole = "deployer"
+	// RoleOwner also edits the configuration, deletes the project and manages
+	// its members.
+	RoleOwner ProjectRole = "owner"
+)
+
+// Includes reports whether the role grants the permissions of other.
+func (r ProjectRole) Includes(other ProjectRole) bool {
+	return r.rank() >= other.rank() && other.rank() > 0
+}
+
+func (r ProjectRole) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleDeployer:
+		return 2
+	case RoleOwner:
+		return 3
+	default:
+		return 0
+	}
+}
+
+type ProjectMember struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	ProjectID uint        `gorm:"uniqueIndex:idx_project_member" json:"project_id"`
+	UserID    uint        `gorm:"uniqueIndex:idx_project_member" json:"user_id"`
+	Role      ProjectRole `json:"role"`
+	// Managed memberships come from OIDC group mappings, they are replaced on
+	// every single sign-on login.
+	Managed   bool      `json:"managed"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	User      *User     `json:"user,omitempty"`
+}
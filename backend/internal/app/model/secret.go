@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Secret is a value of the secrets store, referenced from container env
+// values as secret://<name> and from build secrets by name. Value is
+// encrypted with the master key and never leaves the server.
+type Secret struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	Name    string `gorm:"uniqueIndex" json:"name" binding:"required"`
+	Value   []byte `json:"-"`
+	Version uint   `json:"version"` // incremented on every rotation
+	// ProjectIDs are the projects whose containers and builds may use the
+	// secret.
+	ProjectIDs []uint    `json:"project_ids" gorm:"type:text;serializer:json"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
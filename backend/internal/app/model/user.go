@@ -0,0 +1,62 @@
+package model
+
+import (
+	"axolotl-cloud/types"
+	"time"
+)
+
+// User is an account of the web UI and API. Admin users manage the other
+// users. Passwords are stored as bcrypt hashes. Users signing in with OpenID
+// Connect have no password, they are identified by their issuer and subject.
+type User struct {
+	ID           uint    `gorm:"primaryKey" json:"id"`
+	Username     string  `gorm:"uniqueIndex" json:"username"`
+	PasswordHash []byte  `json:"-"`
+	OIDCSubject  *string `gorm:"column:oidc_subject;uniqueIndex" json:"oidc_subject"` // <issuer>|<sub>
+	Admin        bool    `json:"admin"`
+	// ProjectCreator allows a user other than an admin to create projects.
+	ProjectCreator bool `json:"project_creator" gorm:"default:false"`
+	// TOTPSecret is encrypted with the secrets master key. It is set on
+	// enrollment, and only asked for on login once TOTPEnabled is set.
+	TOTPSecret  []byte `gorm:"column:totp_secret" json:"-"`
+	TOTPEnabled bool   `gorm:"column:totp_enabled" json:"totp_enabled"`
+	// TOTPLastStep is the time step of the last accepted code, a code is
+	// only accepted once.
+	TOTPLastStep int64 `gorm:"column:totp_last_step" json:"-"`
+	// RecoveryCodes are the hashes of the unused recovery codes.
+	RecoveryCodes types.StringList `json:"-"`
+	LastLoginAt   *time.Time       `json:"last_login_at"`
+	CreatedAt     time.Time        `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Sessions   []Session        `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Challenges []LoginChallenge `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Tokens     []APIToken       `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Members    []ProjectMember  `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// Session is a login of a user, identified by the token of its cookie. Only
+// the hash of the token is stored.
+type Session struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"index" json:"user_id"`
+	TokenHash  string    `gorm:"uniqueIndex" json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	User       *User     `json:"-"`
+}
+
+// LoginChallenge is a login waiting for its second factor. The password has
+// been checked, the session is only created once a TOTP or recovery code is
+// given with the token of its cookie.
+type LoginChallenge struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex"`
+	Attempts  int
+	ExpiresAt time.Time
+	User      *User
+}
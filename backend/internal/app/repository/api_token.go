@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APITokenRepository struct {
+	DB *gorm.DB
+}
+
+func (repo *APITokenRepository) Create(ctx context.Context, token *model.APIToken) error {
+	return repo.DB.WithContext(ctx).Create(token).Error
+}
+
+// FindAll returns every token, or the personal tokens of the user when
+// userID is not 0.
+func (repo *APITokenRepository) FindAll(ctx context.Context, userID uint) ([]model.APIToken, error) {
+	var tokens []model.APIToken
+	query := repo.DB.WithContext(ctx).Order("created_at desc")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	err := query.Find(&tokens).Error
+	return tokens, err
+}
+
+func (repo *APITokenRepository) FindByID(ctx context.Context, id uint) (*model.APIToken, error) {
+	var token model.APIToken
+	err := repo.DB.WithContext(ctx).First(&token, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// FindByTokenHash returns the unexpired token with this hash, along with its
+// user.
+func (repo *APITokenRepository) FindByTokenHash(ctx context.Context, hash string) (*model.APIToken, error) {
+	var token model.APIToken
+	err := repo.DB.WithContext(ctx).Preload("User").
+		Where("token_hash = ? AND (expires_at IS NULL OR expires_at > ?)", hash, time.Now()).
+		First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (repo *APITokenRepository) SetLastUsed(ctx context.Context, id uint, at time.Time) error {
+	return repo.DB.WithContext(ctx).Model(&model.APIToken{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+func (repo *APITokenRepository) Delete(ctx context.Context, id uint) error {
+	return repo.DB.WithContext(ctx).Delete(&model.APIToken{}, id).Error
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditEventRepository only creates and reads events, the table is
+// append-only.
+type AuditEventRepository struct {
+	DB *gorm.DB
+}
+
+// AuditFilter selects audit events, zero values match everything.
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   string
+	ProjectID  *uint
+	Since      time.Time
+	Until      time.Time
+	// Limit of events returned, 0 for all of them
+	Limit  int
+	Offset int
+}
+
+func (repo *AuditEventRepository) Create(ctx context.Context, event *model.AuditEvent) error {
+	return repo.DB.WithContext(ctx).Create(event).Error
+}
+
+// Find returns the events matching the filter, newest first, and the number
+// of matching events ignoring the limit.
+func (repo *AuditEventRepository) Find(ctx context.Context, filter AuditFilter) ([]model.AuditEvent, int64, error) {
+	query := repo.DB.WithContext(ctx).Model(&model.AuditEvent{})
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		// "container" matches every container.* action
+		query = query.Where("action = ? OR action LIKE ?", filter.Action, filter.Action+".%")
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.ProjectID != nil {
+		query = query.Where("project_id = ?", *filter.ProjectID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit).Offset(filter.Offset)
+	}
+	var events []model.AuditEvent
+	err := query.Order("id desc").Find(&events).Error
+	return events, total, err
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BuildRepository struct {
+	DB *gorm.DB
+}
+
+func (repo *BuildRepository) Save(ctx context.Context, build *model.Build) error {
+	return repo.DB.WithContext(ctx).Save(build).Error
+}
+
+func (repo *BuildRepository) FindByID(ctx context.Context, id uint) (*model.Build, error) {
+	var build model.Build
+	err := repo.DB.WithContext(ctx).First(&build, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &build, nil
+}
+
+func (repo *BuildRepository) FindAllByContainerID(ctx context.Context, containerID uint) ([]model.Build, error) {
+	var builds []model.Build
+	err := repo.DB.WithContext(ctx).Where("container_id = ?", containerID).Order("number desc").Find(&builds).Error
+	return builds, err
+}
+
+func (repo *BuildRepository) FindAllByProjectID(ctx context.Context, projectID uint) ([]model.Build, error) {
+	var builds []model.Build
+	err := repo.DB.WithContext(ctx).Where("project_id = ?", projectID).Order("number desc").Find(&builds).Error
+	return builds, err
+}
+
+// LinkService attaches to a container the builds of its service made before
+// the container existed, failed ones included.
+func (repo *BuildRepository) LinkService(ctx context.Context, projectID uint, service string, containerID uint) error {
+	return repo.DB.WithContext(ctx).Model(&model.Build{}).
+		Where("project_id = ? AND service = ? AND container_id IS NULL", projectID, service).
+		Update("container_id", containerID).Error
+}
+
+// NextNumber reserves the number of the next build of a project. The counter
+// of the project is incremented in a single statement, so that concurrent
+// builds never get the same number, and never goes back under the number of a
+// recorded build.
+func (repo *BuildRepository) NextNumber(ctx context.Context, projectID uint) (uint, error) {
+	project := model.Project{ID: projectID}
+	err := repo.DB.WithContext(ctx).Model(&project).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "build_number"}}}).
+		UpdateColumn("build_number", gorm.Expr("MAX(build_number, (SELECT COALESCE(MAX(number), 0) FROM builds WHERE project_id = ?)) + 1", projectID)).Error
+	return project.BuildNumber, err
+}
+
+func (repo *BuildRepository) DeleteAllByProjectID(ctx context.Context, projectID uint) error {
+	return repo.DB.WithContext(ctx).Where("project_id = ?", projectID).Delete(&model.Build{}).Error
+}
+
+func (repo *BuildRepository) Delete(ctx context.Context, id uint) error {
+	return repo.DB.WithContext(ctx).Delete(&model.Build{}, id).Error
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type ComposeTemplateRepository struct {
+	DB *gorm.DB
+}
+
+// Save stores the template of a project, replacing the previous one.
+func (repo *ComposeTemplateRepository) Save(ctx context.Context, template *model.ComposeTemplate) error {
+	if existing, err := repo.FindByProjectID(ctx, template.ProjectID); err == nil {
+		template.ID = existing.ID
+	}
+	return repo.DB.WithContext(ctx).Save(template).Error
+}
+
+func (repo *ComposeTemplateRepository) FindByProjectID(ctx context.Context, projectID uint) (*model.ComposeTemplate, error) {
+	var template model.ComposeTemplate
+	err := repo.DB.WithContext(ctx).Where("project_id = ?", projectID).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
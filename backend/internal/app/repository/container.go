@@ -1,8 +1,12 @@
 package repository
 
 import (
+	"axolotl-cloud/infra/secrets"
 	"axolotl-cloud/internal/app/model"
 	"context"
+	"fmt"
+	"slices"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -11,8 +15,14 @@ type ContainerRepository struct {
 	DB *gorm.DB
 }
 
+// Create stores the container along with its first revision.
 func (repo *ContainerRepository) Create(ctx context.Context, container *model.Container) error {
-	return repo.DB.WithContext(ctx).Create(container).Error
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(container).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, container.ID)
+	})
 }
 
 func (repo *ContainerRepository) FindAllByProjectID(ctx context.Context, projectID uint) ([]model.Container, error) {
@@ -30,14 +40,143 @@ func (repo *ContainerRepository) FindByID(ctx context.Context, id uint) (*model.
 	return &container, nil
 }
 
+func (repo *ContainerRepository) FindByName(ctx context.Context, projectID uint, name string) (*model.Container, error) {
+	var container model.Container
+	err := repo.DB.WithContext(ctx).Where("project_id = ? AND name = ?", projectID, name).First(&container).Error
+	if err != nil {
+		return nil, err
+	}
+	return &container, nil
+}
+
+// Save stores the configuration of the container and records a revision when
+// it changed. The deployment state (applied configuration, restart flag) is
+// only changed by the start job.
 func (repo *ContainerRepository) Save(ctx context.Context, container *model.Container) error {
-	return repo.DB.WithContext(ctx).Save(container).Error
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("AppliedConfig", "RestartRequired").Save(container).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, container.ID)
+	})
 }
 
+// SetAppliedConfig records the configuration the Docker container has been
+// created from and clears the restart flag.
+func (repo *ContainerRepository) SetAppliedConfig(ctx context.Context, id uint, config model.ContainerConfig) error {
+	return repo.DB.WithContext(ctx).Model(&model.Container{ID: id}).Select("AppliedConfig", "RestartRequired").Updates(&model.Container{AppliedConfig: &config}).Error
+}
+
+func (repo *ContainerRepository) UpdateImage(ctx context.Context, id uint, image string) error {
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Container{}).Where("id = ?", id).Update("docker_image", image).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, id)
+	})
+}
+
+// FindAllBySecret returns the containers whose env, or the env of their
+// project, references the secret.
+func (repo *ContainerRepository) FindAllBySecret(ctx context.Context, name string) ([]model.Container, error) {
+	ref := fmt.Sprintf("%%%q%%", secrets.RefPrefix+name)
+
+	var projects []model.Project
+	if err := repo.DB.WithContext(ctx).Where("env LIKE ?", ref).Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	// LIKE treats _ as a wildcard, check the matches
+	var projectIDs []uint
+	for _, project := range projects {
+		if slices.Contains(secrets.Refs(project.Env), name) {
+			projectIDs = append(projectIDs, project.ID)
+		}
+	}
+
+	var containers []model.Container
+	query := repo.DB.WithContext(ctx).Where("env LIKE ?", ref)
+	if len(projectIDs) > 0 {
+		query = query.Or("project_id IN ?", projectIDs)
+	}
+	if err := query.Find(&containers).Error; err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(containers, func(c model.Container) bool {
+		return !slices.Contains(projectIDs, c.ProjectID) && !slices.Contains(secrets.Refs(c.Env), name)
+	}), nil
+}
+
+func (repo *ContainerRepository) SetRestartRequired(ctx context.Context, id uint, required bool) error {
+	return repo.DB.WithContext(ctx).Model(&model.Container{}).Where("id = ?", id).Update("restart_required", required).Error
+}
+
+// Delete moves the container to the trash.
 func (repo *ContainerRepository) Delete(ctx context.Context, id uint) error {
 	return repo.DB.WithContext(ctx).Delete(&model.Container{}, id).Error
 }
 
+func (repo *ContainerRepository) Restore(ctx context.Context, id uint) error {
+	return repo.DB.WithContext(ctx).Unscoped().Model(&model.Container{}).Where("id = ?", id).Update("deleted_at", nil).Error
+}
+
+// FindAllDeleted returns the containers of the trash deleted on their own
+// (not with their project), deleted before the date when it is not zero.
+func (repo *ContainerRepository) FindAllDeleted(ctx context.Context, before time.Time) ([]model.Container, error) {
+	var containers []model.Container
+	query := repo.DB.WithContext(ctx).Unscoped().
+		Where("containers.deleted_at IS NOT NULL").
+		Joins("JOIN projects ON projects.id = containers.project_id AND projects.deleted_at IS NULL")
+	if !before.IsZero() {
+		query = query.Where("containers.deleted_at < ?", before)
+	}
+	err := query.Order("containers.deleted_at desc").Find(&containers).Error
+	return containers, err
+}
+
+func (repo *ContainerRepository) FindDeletedByID(ctx context.Context, id uint) (*model.Container, error) {
+	var container model.Container
+	err := repo.DB.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&container, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &container, nil
+}
+
+// FindAllDeletedIDs returns the IDs of every container of the trash.
+func (repo *ContainerRepository) FindAllDeletedIDs(ctx context.Context) ([]uint, error) {
+	var ids []uint
+	err := repo.DB.WithContext(ctx).Unscoped().Model(&model.Container{}).Where("deleted_at IS NOT NULL").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// NameInUse reports whether a container outside of the trash is named name.
+func (repo *ContainerRepository) NameInUse(ctx context.Context, name string) (bool, error) {
+	var count int64
+	err := repo.DB.WithContext(ctx).Model(&model.Container{}).Where("name = ?", name).Count(&count).Error
+	return count > 0, err
+}
+
+// NameInUseElsewhere reports whether a container of another project, including
+// the trash, is named name. Docker containers are looked up by name alone.
+func (repo *ContainerRepository) NameInUseElsewhere(ctx context.Context, projectID uint, name string) (bool, error) {
+	var count int64
+	err := repo.DB.WithContext(ctx).Unscoped().Model(&model.Container{}).Where("project_id <> ? AND name = ?", projectID, name).Count(&count).Error
+	return count > 0, err
+}
+
+// FindAllByProjectIDWithDeleted returns the containers of the project,
+// including the ones in the trash.
+func (repo *ContainerRepository) FindAllByProjectIDWithDeleted(ctx context.Context, projectID uint) ([]model.Container, error) {
+	var containers []model.Container
+	err := repo.DB.WithContext(ctx).Unscoped().Where("project_id = ?", projectID).Find(&containers).Error
+	return containers, err
+}
+
+// Purge permanently deletes the container and its history.
+func (repo *ContainerRepository) Purge(ctx context.Context, id uint) error {
+	return repo.DB.WithContext(ctx).Unscoped().Delete(&model.Container{}, id).Error
+}
+
 func (repo *ContainerRepository) GetAllContainers(ctx context.Context) ([]model.Container, error) {
 	var containers []model.Container
 	err := repo.DB.WithContext(ctx).Find(&containers).Error
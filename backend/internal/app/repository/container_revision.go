@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type ContainerRevisionRepository struct {
+	DB *gorm.DB
+}
+
+func (repo *ContainerRevisionRepository) FindAllByContainerID(ctx context.Context, containerID uint) ([]model.ContainerRevision, error) {
+	var revisions []model.ContainerRevision
+	err := repo.DB.WithContext(ctx).Where("container_id = ?", containerID).Order("number desc").Find(&revisions).Error
+	return revisions, err
+}
+
+func (repo *ContainerRevisionRepository) FindByNumber(ctx context.Context, containerID uint, number uint) (*model.ContainerRevision, error) {
+	var revision model.ContainerRevision
+	err := repo.DB.WithContext(ctx).Where("container_id = ? AND number = ?", containerID, number).First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// recordRevision snapshots the stored configuration of the container, unless
+// it matches the latest revision. It runs in the transaction of the change.
+func recordRevision(tx *gorm.DB, containerID uint) error {
+	var container model.Container
+	if err := tx.First(&container, containerID).Error; err != nil {
+		return err
+	}
+	snapshot := model.SnapshotOf(&container)
+
+	var latest model.ContainerRevision
+	err := tx.Where("container_id = ?", containerID).Order("number desc").Limit(1).Find(&latest).Error
+	if err != nil {
+		return err
+	}
+	if latest.ID != 0 && len(latest.Snapshot.Diff(snapshot)) == 0 {
+		return nil
+	}
+
+	return tx.Create(&model.ContainerRevision{
+		ContainerID: containerID,
+		Number:      latest.Number + 1,
+		Snapshot:    snapshot,
+	}).Error
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LoginChallengeRepository struct {
+	DB *gorm.DB
+}
+
+func (repo *LoginChallengeRepository) Create(ctx context.Context, challenge *model.LoginChallenge) error {
+	return repo.DB.WithContext(ctx).Create(challenge).Error
+}
+
+// FindByTokenHash returns the unexpired challenge with this token hash, along
+// with its user.
+func (repo *LoginChallengeRepository) FindByTokenHash(ctx context.Context, hash string) (*model.LoginChallenge, error) {
+	var challenge model.LoginChallenge
+	err := repo.DB.WithContext(ctx).Preload("User").
+		Where("token_hash = ? AND expires_at > ?", hash, time.Now()).
+		First(&challenge).Error
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// AddAttempt counts a wrong code given for the challenge.
+func (repo *LoginChallengeRepository) AddAttempt(ctx context.Context, id uint) error {
+	return repo.DB.WithContext(ctx).Model(&model.LoginChallenge{}).Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+func (repo *LoginChallengeRepository) Delete(ctx context.Context, id uint) error {
+	return repo.DB.WithContext(ctx).Delete(&model.LoginChallenge{}, id).Error
+}
+
+func (repo *LoginChallengeRepository) DeleteExpired(ctx context.Context) error {
+	return repo.DB.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&model.LoginChallenge{}).Error
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type OIDCGroupMappingRepository struct {
+	DB *gorm.DB
+}
+
+func (repo *OIDCGroupMappingRepository) FindAll(ctx context.Context) ([]model.OIDCGroupMapping, error) {
+	var mappings []model.OIDCGroupMapping
+	err := repo.DB.WithContext(ctx).Order("\"group\", id").Find(&mappings).Error
+	return mappings, err
+}
+
+// FindAllByGroups returns the mappings of the given groups.
+func (repo *OIDCGroupMappingRepository) FindAllByGroups(ctx context.Context, groups []string) ([]model.OIDCGroupMapping, error) {
+	var mappings []model.OIDCGroupMapping
+	if len(groups) == 0 {
+		return mappings, nil
+	}
+	err := repo.DB.WithContext(ctx).Where("\"group\" IN ?", groups).Find(&mappings).Error
+	return mappings, err
+}
+
+// CountAdminMappings counts the mappings granting global admin.
+func (repo *OIDCGroupMappingRepository) CountAdminMappings(ctx context.Context) (int64, error) {
+	var count int64
+	err := repo.DB.WithContext(ctx).Model(&model.OIDCGroupMapping{}).Where("project_id IS NULL").Count(&count).Error
+	return count, err
+}
+
+func (repo *OIDCGroupMappingRepository) Create(ctx context.Context, mapping *model.OIDCGroupMapping) error {
+	return repo.DB.WithContext(ctx).Create(mapping).Error
+}
+
+func (repo *OIDCGroupMappingRepository) Delete(ctx context.Context, id uint) error {
+	result := repo.DB.WithContext(ctx).Delete(&model.OIDCGroupMapping{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"axolotl-cloud/internal/app/model"
 	"context"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -21,6 +22,19 @@ func (repo *ProjectRepository) FindAll(ctx context.Context) ([]model.Project, er
 	return projects, err
 }
 
+func (repo *ProjectRepository) FindAllByIDs(ctx context.Context, ids []uint) ([]model.Project, error) {
+	var projects []model.Project
+	err := repo.DB.WithContext(ctx).Where("id IN ?", ids).Find(&projects).Error
+	return projects, err
+}
+
+// FindAllWithDeleted returns the projects, including the ones in the trash.
+func (repo *ProjectRepository) FindAllWithDeleted(ctx context.Context) ([]model.Project, error) {
+	var projects []model.Project
+	err := repo.DB.WithContext(ctx).Unscoped().Find(&projects).Error
+	return projects, err
+}
+
 func (repo *ProjectRepository) FindByID(ctx context.Context, id uint) (*model.Project, error) {
 	var project model.Project
 	err := repo.DB.WithContext(ctx).First(&project, id).Error
@@ -30,10 +44,77 @@ func (repo *ProjectRepository) FindByID(ctx context.Context, id uint) (*model.Pr
 	return &project, nil
 }
 
+// Save stores the project, the build counter is only changed by
+// BuildRepository.NextNumber.
 func (repo *ProjectRepository) Save(ctx context.Context, p *model.Project) error {
-	return repo.DB.WithContext(ctx).Save(p).Error
+	return repo.DB.WithContext(ctx).Omit("BuildNumber").Save(p).Error
 }
 
+// Delete moves the project to the trash, along with its containers.
 func (repo *ProjectRepository) Delete(ctx context.Context, id uint) error {
-	return repo.DB.WithContext(ctx).Delete(&model.Project{}, id).Error
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&model.Project{}).Where("id = ?", id).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Container{}).Where("project_id = ?", id).Update("deleted_at", now).Error
+	})
+}
+
+// Restore takes the project out of the trash, along with the containers
+// deleted with it.
+func (repo *ProjectRepository) Restore(ctx context.Context, id uint) error {
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		project, err := findDeletedProject(tx, id)
+		if err != nil {
+			return err
+		}
+		var containers []model.Container
+		if err := tx.Unscoped().Where("project_id = ?", id).Find(&containers).Error; err != nil {
+			return err
+		}
+		// Containers deleted before the project stay in the trash
+		var ids []uint
+		for _, container := range containers {
+			if container.DeletedAt.Time.Equal(project.DeletedAt.Time) {
+				ids = append(ids, container.ID)
+			}
+		}
+		if len(ids) > 0 {
+			if err := tx.Unscoped().Model(&model.Container{}).Where("id IN ?", ids).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Model(&model.Project{}).Where("id = ?", id).Update("deleted_at", nil).Error
+	})
+}
+
+// FindAllDeleted returns the projects of the trash, deleted before the date
+// when it is not zero.
+func (repo *ProjectRepository) FindAllDeleted(ctx context.Context, before time.Time) ([]model.Project, error) {
+	var projects []model.Project
+	query := repo.DB.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL")
+	if !before.IsZero() {
+		query = query.Where("deleted_at < ?", before)
+	}
+	err := query.Order("deleted_at desc").Find(&projects).Error
+	return projects, err
+}
+
+func (repo *ProjectRepository) FindDeletedByID(ctx context.Context, id uint) (*model.Project, error) {
+	return findDeletedProject(repo.DB.WithContext(ctx), id)
+}
+
+func findDeletedProject(tx *gorm.DB, id uint) (*model.Project, error) {
+	var project model.Project
+	err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&project, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// Purge permanently deletes the project, its containers and their history.
+func (repo *ProjectRepository) Purge(ctx context.Context, id uint) error {
+	return repo.DB.WithContext(ctx).Unscoped().Delete(&model.Project{}, id).Error
 }
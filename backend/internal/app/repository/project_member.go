@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProjectMemberRepository struct {
+	DB *gorm.DB
+}
+
+func (repo *ProjectMemberRepository) FindAllByProjectID(ctx context.Context, projectID uint) ([]model.ProjectMember, error) {
+	var members []model.ProjectMember
+	err := repo.DB.WithContext(ctx).Preload("User").Where("project_id = ?", projectID).Order("id").Find(&members).Error
+	return members, err
+}
+
+// FindRole returns the role of the user on the project, "" when the user is
+// not a member.
+func (repo *ProjectMemberRepository) FindRole(ctx context.Context, projectID uint, userID uint) (model.ProjectRole, error) {
+	var members []model.ProjectMember
+	err := repo.DB.WithContext(ctx).Where("project_id = ? AND user_id = ?", projectID, userID).Limit(1).Find(&members).Error
+	if err != nil || len(members) == 0 {
+		return "", err
+	}
+	return members[0].Role, nil
+}
+
+// FindProjectIDs returns the projects the user is a member of.
+func (repo *ProjectMemberRepository) FindProjectIDs(ctx context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	err := repo.DB.WithContext(ctx).Model(&model.ProjectMember{}).Where("user_id = ?", userID).Pluck("project_id", &ids).Error
+	return ids, err
+}
+
+// Save sets the role of a user on a project.
+func (repo *ProjectMemberRepository) Save(ctx context.Context, member *model.ProjectMember) error {
+	return repo.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "managed"}),
+	}).Create(member).Error
+}
+
+func (repo *ProjectMemberRepository) CountOwners(ctx context.Context, projectID uint) (int64, error) {
+	var count int64
+	err := repo.DB.WithContext(ctx).Model(&model.ProjectMember{}).
+		Where("project_id = ? AND role = ?", projectID, model.RoleOwner).
+		Count(&count).Error
+	return count, err
+}
+
+func (repo *ProjectMemberRepository) Delete(ctx context.Context, projectID uint, userID uint) error {
+	return repo.DB.WithContext(ctx).Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&model.ProjectMember{}).Error
+}
+
+// ReplaceManaged replaces the memberships of the user coming from OIDC group
+// mappings. Memberships set by hand are kept and take precedence.
+func (repo *ProjectMemberRepository) ReplaceManaged(ctx context.Context, userID uint, members []model.ProjectMember) error {
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND managed = ?", userID, true).Delete(&model.ProjectMember{}).Error; err != nil {
+			return err
+		}
+		for _, member := range members {
+			member.UserID = userID
+			member.Managed = true
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
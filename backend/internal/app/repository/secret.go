@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"axolotl-cloud/infra/secrets"
+	"axolotl-cloud/internal/app/model"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// SecretRepository stores the secrets encrypted with the master key. Values
+// only come out decrypted through Reveal.
+type SecretRepository struct {
+	DB     *gorm.DB
+	Cipher *secrets.Cipher
+}
+
+func (repo *SecretRepository) FindAll(ctx context.Context) ([]model.Secret, error) {
+	var all []model.Secret
+	err := repo.DB.WithContext(ctx).Order("name").Find(&all).Error
+	return all, err
+}
+
+func (repo *SecretRepository) FindByName(ctx context.Context, name string) (*model.Secret, error) {
+	var secret model.Secret
+	err := repo.DB.WithContext(ctx).Where("name = ?", name).First(&secret).Error
+	if err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// Put creates the secret, or rotates it to a new value.
+func (repo *SecretRepository) Put(ctx context.Context, name string, value string) (*model.Secret, error) {
+	encrypted, err := repo.Cipher.Encrypt([]byte(value))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret %s: %w", name, err)
+	}
+
+	secret, err := repo.FindByName(ctx, name)
+	if err != nil {
+		secret = &model.Secret{Name: name, ProjectIDs: []uint{}}
+	}
+	secret.Value = encrypted
+	secret.Version++
+	if err := repo.DB.WithContext(ctx).Save(secret).Error; err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// SetProjects replaces the projects allowed to use a secret.
+func (repo *SecretRepository) SetProjects(ctx context.Context, name string, projectIDs []uint) (*model.Secret, error) {
+	secret, err := repo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	secret.ProjectIDs = projectIDs
+	if err := repo.DB.WithContext(ctx).Save(secret).Error; err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// Reveal returns the decrypted value of a secret, for a container or a build
+// of a project allowed to use it.
+func (repo *SecretRepository) Reveal(ctx context.Context, name string, projectID uint) (string, error) {
+	secret, err := repo.FindByName(ctx, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("secret %s is not defined", name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+	if !slices.Contains(secret.ProjectIDs, projectID) {
+		return "", fmt.Errorf("secret %s is not allowed for project %d", name, projectID)
+	}
+	value, err := repo.Cipher.Decrypt(secret.Value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret %s: %w", name, err)
+	}
+	return string(value), nil
+}
+
+// IndexByValue returns the secrets by decrypted value, to recognize their
+// values in the env of a container adopted from Docker.
+func (repo *SecretRepository) IndexByValue(ctx context.Context) (map[string]model.Secret, error) {
+	all, err := repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]model.Secret, len(all))
+	for _, secret := range all {
+		value, err := repo.Cipher.Decrypt(secret.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret %s: %w", secret.Name, err)
+		}
+		index[string(value)] = secret
+	}
+	return index, nil
+}
+
+func (repo *SecretRepository) Delete(ctx context.Context, name string) error {
+	return repo.DB.WithContext(ctx).Where("name = ?", name).Delete(&model.Secret{}).Error
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	DB *gorm.DB
+}
+
+func (repo *SessionRepository) Create(ctx context.Context, session *model.Session) error {
+	return repo.DB.WithContext(ctx).Create(session).Error
+}
+
+// FindByTokenHash returns the unexpired session with this token hash, along
+// with its user.
+func (repo *SessionRepository) FindByTokenHash(ctx context.Context, hash string) (*model.Session, error) {
+	var session model.Session
+	err := repo.DB.WithContext(ctx).Preload("User").
+		Where("token_hash = ? AND expires_at > ?", hash, time.Now()).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (repo *SessionRepository) FindAllByUserID(ctx context.Context, userID uint) ([]model.Session, error) {
+	var sessions []model.Session
+	err := repo.DB.WithContext(ctx).
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at desc").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (repo *SessionRepository) Touch(ctx context.Context, id uint, at time.Time) error {
+	return repo.DB.WithContext(ctx).Model(&model.Session{}).Where("id = ?", id).Update("last_seen_at", at).Error
+}
+
+// Delete revokes a session of the user. It returns gorm.ErrRecordNotFound
+// when the user has no such session.
+func (repo *SessionRepository) Delete(ctx context.Context, userID uint, id uint) error {
+	result := repo.DB.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.Session{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteAllByUserID revokes the sessions of the user, except the one with
+// the ID keep (0 to revoke all of them).
+func (repo *SessionRepository) DeleteAllByUserID(ctx context.Context, userID uint, keep uint) error {
+	return repo.DB.WithContext(ctx).Where("user_id = ? AND id <> ?", userID, keep).Delete(&model.Session{}).Error
+}
+
+func (repo *SessionRepository) DeleteExpired(ctx context.Context) error {
+	return repo.DB.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&model.Session{}).Error
+}
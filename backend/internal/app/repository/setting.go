@@ -13,6 +13,11 @@ import (
 var defaultSettings = []model.Setting{
 	{Key: settings.JobTimeout, Value: "1800"},
 	{Key: settings.Language, Value: "en"},
+	{Key: settings.BuildRetention, Value: "10"},
+	{Key: settings.TrashPurgeDelay, Value: "168"},
+	{Key: settings.SessionLifetime, Value: "168"},
+	{Key: settings.TwoFactorRequired, Value: "false"},
+	{Key: settings.AllowedOrigins, Value: ""},
 }
 
 type SettingRepository struct {
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"axolotl-cloud/internal/app/model"
+	"axolotl-cloud/types"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAlreadyBootstrapped is returned by CreateFirstAdmin once a user exists.
+var ErrAlreadyBootstrapped = errors.New("the first admin has already been created")
+
+type UserRepository struct {
+	DB *gorm.DB
+}
+
+func (repo *UserRepository) FindAll(ctx context.Context) ([]model.User, error) {
+	var users []model.User
+	err := repo.DB.WithContext(ctx).Order("username").Find(&users).Error
+	return users, err
+}
+
+func (repo *UserRepository) FindByID(ctx context.Context, id uint) (*model.User, error) {
+	var user model.User
+	err := repo.DB.WithContext(ctx).First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (repo *UserRepository) FindByUsername(ctx context.Context, username string) (*model.User, error) {
+	var user model.User
+	err := repo.DB.WithContext(ctx).Where("username = ?", username).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindByOIDCSubject returns the user signing in with the given
+// <issuer>|<sub> identity.
+func (repo *UserRepository) FindByOIDCSubject(ctx context.Context, subject string) (*model.User, error) {
+	var user model.User
+	err := repo.DB.WithContext(ctx).Where("oidc_subject = ?", subject).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (repo *UserRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := repo.DB.WithContext(ctx).Model(&model.User{}).Count(&count).Error
+	return count, err
+}
+
+func (repo *UserRepository) Create(ctx context.Context, user *model.User) error {
+	return repo.DB.WithContext(ctx).Create(user).Error
+}
+
+// CreateFirstAdmin creates user as an admin if there is no user yet.
+func (repo *UserRepository) CreateFirstAdmin(ctx context.Context, user *model.User) error {
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.User{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyBootstrapped
+		}
+		user.Admin = true
+		return tx.Create(user).Error
+	})
+}
+
+// CountAdmins counts the admins, to never delete or demote the last one.
+func (repo *UserRepository) CountAdmins(ctx context.Context) (int64, error) {
+	var count int64
+	err := repo.DB.WithContext(ctx).Model(&model.User{}).Where("admin = ?", true).Count(&count).Error
+	return count, err
+}
+
+func (repo *UserRepository) UpdatePassword(ctx context.Context, id uint, hash []byte) error {
+	return repo.DB.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("password_hash", hash).Error
+}
+
+func (repo *UserRepository) SetAdmin(ctx context.Context, id uint, admin bool) error {
+	return repo.DB.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("admin", admin).Error
+}
+
+func (repo *UserRepository) SetProjectCreator(ctx context.Context, id uint, creator bool) error {
+	return repo.DB.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("project_creator", creator).Error
+}
+
+func (repo *UserRepository) SetLastLogin(ctx context.Context, id uint, at time.Time) error {
+	return repo.DB.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("last_login_at", at).Error
+}
+
+// SetTOTPSecret stores the encrypted secret of an enrollment, not enabled
+// until confirmed by EnableTOTP.
+func (repo *UserRepository) SetTOTPSecret(ctx context.Context, id uint, secret []byte) error {
+	return repo.DB.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).
+		Updates(map[string]any{"totp_secret": secret, "totp_enabled": false}).Error
+}
+
+// EnableTOTP turns on the second factor with the hashes of new recovery
+// codes.
+func (repo *UserRepository) EnableTOTP(ctx context.Context, id uint, recoveryCodes types.StringList) error {
+	return repo.DB.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).
+		Updates(map[string]any{"totp_enabled": true, "recovery_codes": recoveryCodes}).Error
+}
+
+// ResetTOTP turns off the second factor and forgets its secret and recovery
+// codes.
+func (repo *UserRepository) ResetTOTP(ctx context.Context, id uint) error {
+	return repo.DB.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).
+		Updates(map[string]any{"totp_secret": nil, "totp_enabled": false, "totp_last_step": 0, "recovery_codes": types.StringList{}}).Error
+}
+
+func (repo *UserRepository) SetRecoveryCodes(ctx context.Context, id uint, recoveryCodes types.StringList) error {
+	return repo.DB.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("recovery_codes", recoveryCodes).Error
+}
+
+// UseTOTPStep records the time step of an accepted code. It returns false
+// when a code of this step or a later one has already been used.
+func (repo *UserRepository) UseTOTPStep(ctx context.Context, id uint, step int64) (bool, error) {
+	result := repo.DB.WithContext(ctx).Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+// UseRecoveryCode removes a recovery code hash of the user. It returns false
+// when the code is not one of them.
+func (repo *UserRepository) UseRecoveryCode(ctx context.Context, id uint, hash string) (bool, error) {
+	used := false
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.Select("id", "recovery_codes").First(&user, id).Error; err != nil {
+			return err
+		}
+		remaining := slices.DeleteFunc(slices.Clone(user.RecoveryCodes), func(code string) bool {
+			return code == hash
+		})
+		if len(remaining) == len(user.RecoveryCodes) {
+			return nil
+		}
+		used = true
+		return tx.Model(&model.User{}).Where("id = ?", id).Update("recovery_codes", types.StringList(remaining)).Error
+	})
+	return used, err
+}
+
+// Delete deletes the user along with its sessions.
+func (repo *UserRepository) Delete(ctx context.Context, id uint) error {
+	return repo.DB.WithContext(ctx).Delete(&model.User{}, id).Error
+}
@@ -34,3 +34,19 @@ func (l *StringList) Scan(src any) error {
 	}
 	return json.Unmarshal([]byte(src.(string)), l)
 }
+
+// JSONMap holds arbitrary JSON values, e.g. the changes of an audit event.
+type JSONMap map[string]any
+
+func (m JSONMap) Value() (driver.Value, error) {
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+func (m *JSONMap) Scan(src any) error {
+	if src == nil {
+		*m = JSONMap{}
+		return nil
+	}
+	return json.Unmarshal([]byte(src.(string)), m)
+}
@@ -3,22 +3,8 @@ package utils
 import (
 	"axolotl-cloud/internal/app/model"
 	"strings"
-
-	"gopkg.in/yaml.v3"
 )
 
-func ParseComposeFileFromBytes(bytes []byte, project *model.Project) (model.ComposeFile, []model.Container, error) {
-	var compose model.ComposeFile
-	if err := yaml.Unmarshal(bytes, &compose); err != nil {
-		return model.ComposeFile{}, nil, err
-	}
-	containers, err := ParseComposeFile(compose, project)
-	if err != nil {
-		return model.ComposeFile{}, nil, err
-	}
-	return compose, containers, nil
-}
-
 func ParseComposeFile(content model.ComposeFile, project *model.Project) ([]model.Container, error) {
 	containers := make([]model.Container, 0, len(content.Services))
 	for name, service := range content.Services {
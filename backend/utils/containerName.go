@@ -1,6 +1,9 @@
 package utils
 
-import "regexp"
+import (
+	"fmt"
+	"regexp"
+)
 
 func FormatContainerName(projectName string, containerName string) string {
 	reg := regexp.MustCompile(`[^a-zA-Z0-9]+`)
@@ -9,3 +12,9 @@ func FormatContainerName(projectName string, containerName string) string {
 
 	return cleanProjectName + "_" + cleanContainerName
 }
+
+// FormatNetworkName returns the name of the Docker network shared by the
+// containers of a project.
+func FormatNetworkName(projectID uint) string {
+	return fmt.Sprintf("axolotl_project_%d", projectID)
+}
@@ -1,29 +1,28 @@
 package utils
 
 import (
-	"fmt"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ParamUInt parses a route parameter. The caller answers the request when it
+// is invalid.
 func ParamUInt(c *gin.Context, name string) (uint, bool) {
 	raw := c.Param(name)
 	val, err := strconv.ParseUint(raw, 10, 32)
 	if err != nil {
-		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid param %s", name)})
-		c.Abort()
 		return 0, false
 	}
 	return uint(val), true
 }
 
+// ParamInt parses a route parameter. The caller answers the request when it
+// is invalid.
 func ParamInt(c *gin.Context, name string) (int, bool) {
 	raw := c.Param(name)
 	val, err := strconv.Atoi(raw)
 	if err != nil {
-		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid param %s", name)})
-		c.Abort()
 		return 0, false
 	}
 	return val, true
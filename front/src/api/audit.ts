@@ -0,0 +1,25 @@
+import { http } from "./http"
+
+import type { AuditEvent } from "./types"
+
+export type AuditFilters = {
+  actor?: string
+  // "container" matches every container.* action
+  action?: string
+  target_type?: string
+  target_id?: string
+  project_id?: number
+  since?: string
+  until?: string
+}
+
+export const getAuditEvents = async (filters: AuditFilters & { limit?: number, offset?: number } = {}): Promise<{ events: AuditEvent[], total: number }> => {
+  const response = await http.get("/audit", { params: filters })
+  return response.data
+}
+
+// Every matching event, to save as a file for compliance.
+export const exportAuditEvents = async (filters: AuditFilters = {}): Promise<Blob> => {
+  const response = await http.get("/audit/export", { params: filters, responseType: "blob" })
+  return response.data
+}
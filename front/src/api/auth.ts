@@ -0,0 +1,80 @@
+import { http, API_HOST } from "./http"
+
+import type { Session, TwoFactorStatus, User } from "./types"
+
+export const getAuthStatus = async (): Promise<{ setup_required: boolean, oidc_enabled: boolean }> => {
+  const response = await http.get("/auth/status")
+  return response.data
+}
+
+export const setup = async (username: string, password: string): Promise<User> => {
+  const response = await http.post("/auth/setup", { username, password })
+  return response.data
+}
+
+// Users with TOTP get { two_factor_required: true }, the login is finished by
+// verifyLogin with a code.
+export const login = async (username: string, password: string): Promise<User | { two_factor_required: true }> => {
+  const response = await http.post("/auth/login", { username, password })
+  return response.data
+}
+
+// code is a TOTP code or a recovery code.
+export const verifyLogin = async (code: string): Promise<User> => {
+  const response = await http.post("/auth/login/verify", { code })
+  return response.data
+}
+
+// Single sign-on is a browser redirection to the identity provider, which
+// comes back to the callback of the API, then to the web UI.
+export const loginWithOIDC = (): void => {
+  document.location.href = API_HOST + "/api/v1/auth/oidc/login"
+}
+
+export const logout = async (): Promise<void> => {
+  await http.post("/auth/logout")
+}
+
+export const getCurrentUser = async (): Promise<User> => {
+  const response = await http.get("/auth/me")
+  return response.data
+}
+
+export const changePassword = async (currentPassword: string, newPassword: string): Promise<void> => {
+  await http.put("/auth/password", { current_password: currentPassword, new_password: newPassword })
+}
+
+export const getSessions = async (): Promise<Session[]> => {
+  const response = await http.get("/auth/sessions")
+  return response.data
+}
+
+export const revokeSession = async (id: string): Promise<void> => {
+  await http.delete(`/auth/sessions/${id}`)
+}
+
+export const getTwoFactorStatus = async (): Promise<TwoFactorStatus> => {
+  const response = await http.get("/auth/2fa")
+  return response.data
+}
+
+// uri is the otpauth:// URI to show as a QR code to the authenticator app.
+export const setupTwoFactor = async (): Promise<{ secret: string, uri: string }> => {
+  const response = await http.post("/auth/2fa/setup")
+  return response.data
+}
+
+// The recovery codes are only returned here and by regenerateRecoveryCodes.
+export const enableTwoFactor = async (code: string): Promise<{ recovery_codes: string[] }> => {
+  const response = await http.post("/auth/2fa/enable", { code })
+  return response.data
+}
+
+export const disableTwoFactor = async (code: string): Promise<void> => {
+  await http.post("/auth/2fa/disable", { code })
+}
+
+export const regenerateRecoveryCodes = async (code: string): Promise<{ recovery_codes: string[] }> => {
+  const response = await http.post("/auth/2fa/recovery_codes", { code })
+  return response.data
+}
@@ -1,11 +1,16 @@
 import { http } from "./http";
-import type { Container, ContainerStatus } from "./types";
+import type { ConfigChange, Container, ContainerEnv, ContainerRevision, ContainerStatus } from "./types";
 
 export const getContainers = async (projectId: string): Promise<Container[]> => {
     const res = await http.get<Container[]>(`/projects/${projectId}/containers`);
     return res.data;
 }
 
+export const getEffectiveEnv = async (projectId: string): Promise<ContainerEnv[]> => {
+    const res = await http.get<ContainerEnv[]>(`/projects/${projectId}/containers/env`);
+    return res.data;
+}
+
 export const getOneContainer = async (projectId: string, containerId: string): Promise<Container> => {
     const res = await http.get<Container>(`/projects/${projectId}/containers/${containerId}`);
     return res.data;
@@ -21,8 +26,28 @@ export const importComposeFile = async (projectId: string, composeFile: string):
     return res.data;
 }
 
-export const buildFromSource = async (projectId: string, gitURL: string, accessToken?: string): Promise<{ message: string }> => {
-    const res = await http.post<{ message: string }>(`/projects/${projectId}/containers/build_from_source`, { git_url: gitURL, access_token: accessToken });
+export const reimportComposeFile = async (projectId: string): Promise<Container[]> => {
+    const res = await http.post<Container[]>(`/projects/${projectId}/containers/reimport`);
+    return res.data;
+}
+
+export const getContainerRevisions = async (projectId: string, containerId: string): Promise<ContainerRevision[]> => {
+    const res = await http.get<ContainerRevision[]>(`/projects/${projectId}/containers/${containerId}/revisions`);
+    return res.data;
+}
+
+export const diffContainerRevisions = async (projectId: string, containerId: string, from: number, to?: number): Promise<{ from: number, to: number, changes: ConfigChange[] }> => {
+    const res = await http.get(`/projects/${projectId}/containers/${containerId}/revisions/diff`, { params: { from, to } });
+    return res.data;
+}
+
+export const restoreContainerRevision = async (projectId: string, containerId: string, number: number): Promise<Container> => {
+    const res = await http.post<Container>(`/projects/${projectId}/containers/${containerId}/revisions/${number}/restore`);
+    return res.data;
+}
+
+export const buildFromSource = async (projectId: string, gitURL: string, accessToken?: string): Promise<{ job_id: string }> => {
+    const res = await http.post<{ job_id: string }>(`/projects/${projectId}/containers/build_from_source`, { git_url: gitURL, access_token: accessToken });
     return res.data;
 }
 
@@ -1,9 +1,11 @@
 import axios from "axios"
 
 const env = import.meta.env.VITE_APP_ENV;
-const API_HOST = env == "production" ? document.location.origin : "http://localhost:8888";
+export const API_HOST = env == "production" ? document.location.origin : "http://localhost:8888";
 
 export const http = axios.create({
-  baseURL: API_HOST + "/api",
-  timeout: 5000
+  baseURL: API_HOST + "/api/v1",
+  timeout: 5000,
+  // sends the HttpOnly session cookie
+  withCredentials: true
 })
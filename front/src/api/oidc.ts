@@ -0,0 +1,18 @@
+import { http } from "./http"
+
+import type { OIDCGroupMapping, ProjectRole } from "./types"
+
+export const getGroupMappings = async (): Promise<OIDCGroupMapping[]> => {
+  const response = await http.get("/oidc/mappings")
+  return response.data
+}
+
+// Without project, the members of the group become admins.
+export const createGroupMapping = async (group: string, projectId?: number, role?: ProjectRole): Promise<OIDCGroupMapping> => {
+  const response = await http.post("/oidc/mappings", { group, project_id: projectId, role })
+  return response.data
+}
+
+export const deleteGroupMapping = async (id: string): Promise<void> => {
+  await http.delete(`/oidc/mappings/${id}`)
+}
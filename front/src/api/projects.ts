@@ -1,5 +1,5 @@
 import { http } from "./http"
-import type { Project } from "./types"
+import type { Project, ProjectMember, ProjectRole } from "./types"
 
 export const getProjects = async (): Promise<Project[]> => {
   const res = await http.get<Project[]>("/projects")
@@ -11,8 +11,9 @@ export const createProject = async (data: Omit<Project, 'id' | 'created_at' | 'u
   return res.data
 }
 
-export const deleteProject = async (id: string) => {
-  await http.delete(`/projects/${id}`)
+export const deleteProject = async (id: string): Promise<{ job_id: string }> => {
+  const res = await http.delete(`/projects/${id}`)
+  return res.data
 }
 
 export const updateProject = async (id: string, data: Omit<Project, 'id' | 'created_at' | 'updated_at'>): Promise<Project> => {
@@ -40,3 +41,17 @@ export const getProjectLogs = async (id: string, n: number): Promise<string> =>
 
 
 
+
+export const getProjectMembers = async (id: string): Promise<ProjectMember[]> => {
+  const res = await http.get(`/projects/${id}/members`)
+  return res.data
+}
+
+export const setProjectMember = async (id: string, userId: string, role: ProjectRole): Promise<ProjectMember> => {
+  const res = await http.put(`/projects/${id}/members/${userId}`, { role })
+  return res.data
+}
+
+export const removeProjectMember = async (id: string, userId: string): Promise<void> => {
+  await http.delete(`/projects/${id}/members/${userId}`)
+}
@@ -0,0 +1,13 @@
+import { http } from "./http"
+
+import type { ReconcileAction, ReconcileReport } from "./types"
+
+export const getReconcileReport = async (): Promise<ReconcileReport> => {
+  const response = await http.get("/reconcile")
+  return response.data
+}
+
+export const runReconcileAction = async (action: ReconcileAction): Promise<unknown> => {
+  const response = await http.post("/reconcile", action)
+  return response.data
+}
@@ -0,0 +1,27 @@
+import { http } from "./http"
+
+import type { Secret } from "./types"
+
+export const getSecrets = async (): Promise<Secret[]> => {
+  const response = await http.get("/secrets")
+  return response.data
+}
+
+export const createSecret = async (name: string, value: string, projectIds: number[] = []): Promise<Secret> => {
+  const response = await http.post("/secrets", { name, value, project_ids: projectIds })
+  return response.data
+}
+
+export const setSecretProjects = async (name: string, projectIds: number[]): Promise<Secret> => {
+  const response = await http.put(`/secrets/${name}/projects`, { project_ids: projectIds })
+  return response.data
+}
+
+export const rotateSecret = async (name: string, value: string): Promise<Secret> => {
+  const response = await http.put(`/secrets/${name}`, { value })
+  return response.data
+}
+
+export const deleteSecret = async (name: string): Promise<void> => {
+  await http.delete(`/secrets/${name}`)
+}
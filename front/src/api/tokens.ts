@@ -0,0 +1,26 @@
+import { http } from "./http"
+
+import type { APIToken, TokenScope } from "./types"
+
+export type CreateTokenRequest = {
+  name: string
+  scopes: TokenScope[]
+  project_id?: number
+  expires_at?: string
+  service?: boolean
+}
+
+export const getTokens = async (): Promise<APIToken[]> => {
+  const response = await http.get("/tokens")
+  return response.data
+}
+
+// The token value is only returned here, it cannot be retrieved later.
+export const createToken = async (data: CreateTokenRequest): Promise<APIToken & { token: string }> => {
+  const response = await http.post("/tokens", data)
+  return response.data
+}
+
+export const deleteToken = async (id: string): Promise<void> => {
+  await http.delete(`/tokens/${id}`)
+}
@@ -0,0 +1,28 @@
+import { http } from "./http"
+
+import type { Container, Project, Trash } from "./types"
+
+export const getTrash = async (): Promise<Trash> => {
+  const response = await http.get("/trash")
+  return response.data
+}
+
+export const restoreProject = async (id: string): Promise<Project> => {
+  const response = await http.post(`/trash/projects/${id}/restore`)
+  return response.data
+}
+
+export const purgeProject = async (id: string): Promise<{ job_id: string }> => {
+  const response = await http.delete(`/trash/projects/${id}`)
+  return response.data
+}
+
+export const restoreContainer = async (containerId: string): Promise<Container> => {
+  const response = await http.post(`/trash/containers/${containerId}/restore`)
+  return response.data
+}
+
+export const purgeContainer = async (containerId: string): Promise<{ job_id: string }> => {
+  const response = await http.delete(`/trash/containers/${containerId}`)
+  return response.data
+}
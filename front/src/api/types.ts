@@ -6,6 +6,10 @@ export type Project = {
   created_at: string
   updated_at: string
   website_url: string
+  dockerfile?: string
+  env?: Record<string, string>
+  variables?: Record<string, string>
+  deleted_at?: string | null
 }
 
 export type NetworkMode = "host" | "bridge" | "none"
@@ -19,7 +23,54 @@ export type Container = {
   volumes: Record<string, string>
   network_mode: NetworkMode
   networks: string[]
+  deploy_strategy?: DeployStrategy
+  health_probe?: HealthProbe | null
+  restart_required?: boolean
+  pending_changes?: boolean
+  changes?: ConfigChange[]
   last_job?: Job
+  deleted_at?: string | null
+}
+
+export type DeployStrategy = "recreate" | "blue_green"
+
+export type HealthProbe = {
+  type: "tcp" | "http"
+  port: string
+  path?: string
+  timeout?: number
+}
+
+export type ContainerRevision = {
+  id: string
+  container_id: string
+  number: number
+  snapshot: Pick<Container, "docker_image" | "ports" | "env" | "volumes" | "networks" | "network_mode" | "deploy_strategy" | "health_probe">
+  created_at: string
+}
+
+export type ConfigChange = {
+  field: string
+  old: string | null
+  new: string | null
+}
+
+export type ContainerEnv = {
+  container_id: string
+  name: string
+  env: Record<string, string>
+  error?: string
+}
+
+export type Secret = {
+  id: string
+  name: string
+  value: string // always masked
+  version: number
+  project_ids: number[] | null // projects allowed to use the secret
+  used_by: string[]
+  created_at: string
+  updated_at: string
 }
 
 export type ContainerStatus = "created" | "running" | "paused" | "restarting" | "removing" | "exited" | "dead" | "loading"
@@ -52,6 +103,34 @@ export type JobLog = {
   line: string
 }
 
+export type BuildStatus = "running" | "succeeded" | "failed"
+
+export type Build = {
+  id: number
+  project_id: number
+  container_id?: number
+  number: number
+  service: string
+  source: "git" | "archive"
+  source_ref: string
+  commit_sha: string
+  image_id: string
+  tag: string
+  duration: number
+  status: BuildStatus
+  job_id: number
+  created_at: number
+}
+
+export type BuildStep = {
+  digest: string
+  name: string
+  cached: boolean
+  started?: string
+  completed?: string
+  error?: string
+}
+
 export type Volume = {
   size: number
   source: string
@@ -65,4 +144,128 @@ export type Setting = {
   id: number
   key: string
   value: string
-}
\ No newline at end of file
+}
+export type ReconcileEntry = {
+  container_id?: string
+  project_id?: string
+  docker_id?: string
+  name: string
+  image?: string
+  state?: string
+  reasons?: string[]
+}
+
+export type ReconcileReport = {
+  missing: ReconcileEntry[]
+  orphans: ReconcileEntry[]
+  drifted: ReconcileEntry[]
+  unmanaged: ReconcileEntry[]
+}
+
+export type ReconcileAction =
+  | { action: "adopt", docker_id: string, project_id?: string }
+  | { action: "remove", docker_id: string }
+  | { action: "recreate", container_id: string }
+
+export type Trash = {
+  projects: Project[]
+  containers: Container[]
+  purge_delay: number
+}
+
+export type User = {
+  id: string
+  username: string
+  // "<issuer>|<subject>" for single sign-on users, who have no password
+  oidc_subject: string | null
+  admin: boolean
+  // allowed to create projects without being admin
+  project_creator: boolean
+  totp_enabled: boolean
+  last_login_at: string | null
+  created_at: string
+  updated_at: string
+}
+
+export type TwoFactorStatus = {
+  enabled: boolean
+  recovery_codes_left: number
+  // set by the two_factor_required setting
+  required: boolean
+}
+
+export type Session = {
+  id: string
+  user_id: string
+  user_agent: string
+  ip: string
+  created_at: string
+  last_seen_at: string
+  expires_at: string
+  current: boolean
+}
+
+export type TokenScope = "read" | "deploy" | "admin"
+
+export type APIToken = {
+  id: string
+  name: string
+  user_id: string | null
+  prefix: string
+  scopes: TokenScope[]
+  project_id: string | null
+  expires_at: string | null
+  last_used_at: string | null
+  created_at: string
+}
+
+export type ProjectRole = "owner" | "deployer" | "viewer"
+
+export type ProjectMember = {
+  id: string
+  project_id: string
+  user_id: string
+  role: ProjectRole
+  // set by an OIDC group mapping, replaced on every single sign-on login
+  managed: boolean
+  created_at: string
+  user?: User
+}
+
+// A group mapping without project makes the members of the group admins.
+export type OIDCGroupMapping = {
+  id: string
+  group: string
+  project_id: string | null
+  role: ProjectRole | ""
+  created_at: string
+}
+
+export type AuditEvent = {
+  id: string
+  created_at: string
+  // username, or token name for service tokens, empty for anonymous requests
+  actor: string
+  user_id: string | null
+  token_id: string | null
+  ip: string
+  action: string
+  target_type: string
+  target_id: string
+  project_id: string | null
+  method: string
+  path: string
+  status: number
+  changes?: Record<string, unknown>
+  job_id: string | null
+}
+
+// Body of every error response of the API.
+export type APIError = {
+  error: string
+  code: "invalid_request" | "validation_failed" | "unauthorized" | "forbidden" | "not_found" | "conflict" | "too_large" | "rate_limited" | "internal_error" | "upstream_error"
+  // invalid fields of the request, e.g. { field: "health_probe.port", rule: "required" }
+  fields?: { field: string, rule: string, message: string }[]
+  details?: Record<string, unknown>
+  request_id: string
+}
@@ -0,0 +1,27 @@
+import { http } from "./http"
+
+import type { User } from "./types"
+
+export const getUsers = async (): Promise<User[]> => {
+  const response = await http.get("/users")
+  return response.data
+}
+
+export const createUser = async (data: { username: string, password: string, admin?: boolean, project_creator?: boolean }): Promise<User> => {
+  const response = await http.post("/users", data)
+  return response.data
+}
+
+export const updateUser = async (id: string, data: { admin?: boolean, project_creator?: boolean, password?: string }): Promise<User> => {
+  const response = await http.put(`/users/${id}`, data)
+  return response.data
+}
+
+export const deleteUser = async (id: string): Promise<void> => {
+  await http.delete(`/users/${id}`)
+}
+
+// Turns off the second factor of a user who lost it.
+export const resetTwoFactor = async (id: string): Promise<void> => {
+  await http.delete(`/users/${id}/2fa`)
+}
@@ -60,7 +60,7 @@ const ProjectDetails = () => {
 
     const handleBuildFromSource = (props: { git_url: string, access_token?: string }) => {
         buildFromSource(projectId || "", props.git_url, props.access_token).then((response) => {
-            toast.success(response.message);
+            toast.success(`Build job #${response.job_id} started`);
         }).catch((error) => {
             console.error("Failed to build from source:", error);
             toast.error("Failed to build from source. Please try again.");
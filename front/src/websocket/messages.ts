@@ -1,7 +1,8 @@
-import type { JobLog } from "../api/types";
+import type { BuildStep, JobLog } from "../api/types";
 
 
 export type WSMessage = JobLogUpdateMessage
+  | JobProgressMessage
   | SubscribeMessage
   | UnsubscribeMessage;
 
@@ -21,4 +22,12 @@ export type JobLogUpdateMessage = {
         jobId: string;
         log: JobLog;
     };
+}
+
+export type JobProgressMessage = {
+    type: 'job_progress';
+    data: {
+        job_id: number;
+        steps: BuildStep[];
+    };
 }
\ No newline at end of file